@@ -47,6 +47,12 @@ go build \
     -o ${OUTPUT_FILE} \
     ./cmd/dailyflow
 
+# 任务加密命令行工具（控制台程序）
+go build \
+    -ldflags="-s -w" \
+    -o ${OUTPUT_DIR}/dailyflow-crypt.exe \
+    ./cmd/dailyflow-crypt
+
 # 检查构建结果
 if [ -f ${OUTPUT_FILE} ]; then
     FILE_SIZE=$(du -h ${OUTPUT_FILE} | cut -f1)
@@ -0,0 +1,196 @@
+// dailyflow-crypt 加密、解密任务库中已有的任务文件
+//
+// 用法:
+//
+//	dailyflow-crypt [--portable | --user | --data-dir 目录] encrypt|decrypt|status [--all | 任务名...]
+//
+// 选项可以写在任务名前后的任意位置，以 "-" 开头的任务名写在 "--" 之后
+// 口令从环境变量 DAILYFLOW_PASSPHRASE 读取，未设置时在终端中输入（不回显）
+package main
+
+import (
+	"bufio"
+	"dailyflow/internal/storage"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// passphraseEnv 提供口令的环境变量，用于脚本中批量处理
+const passphraseEnv = "DAILYFLOW_PASSPHRASE"
+
+// stdin 标准输入不是终端时从中逐行读取口令（确认口令需要读取第二行）
+var stdin = bufio.NewReader(os.Stdin)
+
+func main() {
+	if err := run(os.Args[1:]); err != nil {
+		fmt.Fprintf(os.Stderr, "dailyflow-crypt: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// run 解析命令行并执行子命令
+func run(args []string) error {
+	flags := flag.NewFlagSet("dailyflow-crypt", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: dailyflow-crypt [--portable | --user | --data-dir DIR] encrypt|decrypt|status [--all | TASK...]")
+		flags.PrintDefaults()
+	}
+	applyDataDir := storage.AddDataDirFlags(flags)
+	all := flags.Bool("all", false, "process every task in the library")
+	command, names, err := parseArgs(flags, args)
+	if err != nil {
+		return err
+	}
+	if command == "" {
+		flags.Usage()
+		return fmt.Errorf("missing command")
+	}
+	if command != "status" && command != "encrypt" && command != "decrypt" {
+		return fmt.Errorf("unknown command %q", command)
+	}
+
+	if _, err := applyDataDir(); err != nil {
+		return err
+	}
+	if *all || (command == "status" && len(names) == 0) {
+		if names, err = storage.ListTasks(); err != nil {
+			return err
+		}
+	}
+	if len(names) == 0 {
+		return fmt.Errorf("no tasks given, use --all to process every task")
+	}
+
+	switch command {
+	case "status":
+		return printStatus(names)
+	case "encrypt":
+		if err := readPassphrase(true); err != nil {
+			return err
+		}
+		return forEachTask(names, "encrypted", storage.EncryptTask)
+	case "decrypt":
+		if err := readPassphrase(false); err != nil {
+			return err
+		}
+		return forEachTask(names, "decrypted", storage.DecryptTask)
+	}
+	return nil
+}
+
+// parseArgs 解析选项，返回子命令和任务名
+// 选项可以写在子命令和任务名之间的任意位置；"--" 之后的参数都是任务名，用于以 "-" 开头的任务
+func parseArgs(flags *flag.FlagSet, args []string) (string, []string, error) {
+	var rest []string
+	for i, arg := range args {
+		if arg == "--" {
+			args, rest = args[:i], args[i+1:]
+			break
+		}
+	}
+
+	var positional []string
+	for {
+		// flag 在第一个非选项参数处停止，记下它后继续解析之后的选项
+		if err := flags.Parse(args); err != nil {
+			return "", nil, err
+		}
+		if flags.NArg() == 0 {
+			break
+		}
+		positional = append(positional, flags.Arg(0))
+		args = flags.Args()[1:]
+	}
+	positional = append(positional, rest...)
+
+	if len(positional) == 0 {
+		return "", nil, nil
+	}
+	return positional[0], positional[1:], nil
+}
+
+// printStatus 列出任务是否已加密
+func printStatus(names []string) error {
+	for _, name := range names {
+		encrypted, err := storage.IsTaskEncrypted(name)
+		if err != nil {
+			return err
+		}
+		state := "plain"
+		if encrypted {
+			state = "encrypted"
+		}
+		fmt.Printf("%-10s %s\n", state, name)
+	}
+	return nil
+}
+
+// forEachTask 依次处理任务，某个任务失败时继续处理其余任务，最后汇总错误
+func forEachTask(names []string, done string, fn func(name string) error) error {
+	failed := 0
+	for _, name := range names {
+		if err := fn(name); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", name, err)
+			failed++
+			continue
+		}
+		fmt.Printf("%s: %s\n", name, done)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d tasks failed", failed, len(names))
+	}
+	return nil
+}
+
+// readPassphrase 从环境变量或终端读取口令并设置到 storage，confirm 时在终端中要求输入两次
+func readPassphrase(confirm bool) error {
+	if value := os.Getenv(passphraseEnv); value != "" {
+		storage.SetPassphrase(value)
+		return nil
+	}
+
+	value, err := promptPassphrase("Passphrase: ")
+	if err != nil {
+		return err
+	}
+	if value == "" {
+		return errors.New("empty passphrase")
+	}
+	if confirm && term.IsTerminal(int(os.Stdin.Fd())) {
+		again, err := promptPassphrase("Repeat passphrase: ")
+		if err != nil {
+			return err
+		}
+		if again != value {
+			return errors.New("passphrases do not match")
+		}
+	}
+	storage.SetPassphrase(value)
+	return nil
+}
+
+// promptPassphrase 在终端中不回显地读取一行，标准输入不是终端时直接读取一行
+func promptPassphrase(prompt string) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		line, err := stdin.ReadString('\n')
+		if err != nil && !(errors.Is(err, io.EOF) && line != "") {
+			return "", fmt.Errorf("failed to read passphrase: %w", err)
+		}
+		return strings.TrimRight(line, "\r\n"), nil
+	}
+
+	fmt.Fprint(os.Stderr, prompt)
+	value, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", fmt.Errorf("failed to read passphrase: %w", err)
+	}
+	return string(value), nil
+}
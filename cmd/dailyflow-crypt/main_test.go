@@ -0,0 +1,40 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestParseArgs(t *testing.T) {
+	tests := []struct {
+		args        []string
+		wantCommand string
+		wantNames   []string
+		wantAll     bool
+	}{
+		{[]string{"--all", "decrypt"}, "decrypt", nil, true},
+		{[]string{"decrypt", "--all"}, "decrypt", nil, true},
+		{[]string{"encrypt", "report", "--all"}, "encrypt", []string{"report"}, true},
+		{[]string{"encrypt", "日报", "周报"}, "encrypt", []string{"日报", "周报"}, false},
+		{[]string{"encrypt", "日报", "--", "-草稿", "--all"}, "encrypt", []string{"日报", "-草稿", "--all"}, false},
+		{nil, "", nil, false},
+	}
+	for _, tt := range tests {
+		flags := flag.NewFlagSet("dailyflow-crypt", flag.ContinueOnError)
+		all := flags.Bool("all", false, "")
+		command, names, err := parseArgs(flags, tt.args)
+		sameNames := reflect.DeepEqual(names, tt.wantNames) || len(names)+len(tt.wantNames) == 0
+		if err != nil || command != tt.wantCommand || !sameNames || *all != tt.wantAll {
+			t.Errorf("parseArgs(%q) = %q, %q, all=%v, %v; want %q, %q, all=%v",
+				tt.args, command, names, *all, err, tt.wantCommand, tt.wantNames, tt.wantAll)
+		}
+	}
+
+	flags := flag.NewFlagSet("dailyflow-crypt", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	if _, _, err := parseArgs(flags, []string{"encrypt", "日报", "--bogus"}); err == nil {
+		t.Error("parseArgs() with an unknown flag error = nil")
+	}
+}
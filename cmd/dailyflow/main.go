@@ -1,9 +1,13 @@
 package main
 
 import (
+	"dailyflow/internal/storage"
 	"dailyflow/internal/ui"
+	"flag"
+	"io"
 	"log"
 	"os"
+	"path/filepath"
 	"unsafe"
 
 	"github.com/lxn/walk"
@@ -35,6 +39,9 @@ const (
 	// Mutex 名称
 	mutexName = "Global\\DailyFlow_Mutex"
 
+	// 日志文件名（位于数据目录）
+	logFileName = "dailyflow_error.log"
+
 	// 热键 ID
 	HOTKEY_F8  = 1
 	HOTKEY_F12 = 2
@@ -48,8 +55,15 @@ func main() {
 	// 设置 DPI Awareness（防止高分屏下坐标偏移）
 	setDPIAware()
 	
+	// 选择数据目录（便携 / 用户 / --data-dir），程序目录不可写时自动使用用户目录
+	dataDir, err := setupDataDir(os.Args[1:])
+	if err != nil {
+		walk.MsgBox(nil, "错误", "无法使用数据目录: "+err.Error(), walk.MsgBoxIconError)
+		os.Exit(1)
+	}
+
 	// 设置日志文件
-	logFile, err := os.OpenFile("dailyflow_error.log", os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
+	logFile, err := os.OpenFile(filepath.Join(dataDir, logFileName), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
 	if err == nil {
 		defer logFile.Close()
 		log.SetOutput(logFile)
@@ -58,6 +72,7 @@ func main() {
 	log.Println("========== DailyFlow 启动 ==========")
 	log.Println("Common Controls 初始化完成")
 	log.Println("DPI Awareness 设置完成")
+	log.Printf("数据目录: %s (%s)", dataDir, storage.GetDataMode())
 	
 	// 捕获 panic
 	defer func() {
@@ -121,6 +136,18 @@ func main() {
 	log.Println("程序正常退出")
 }
 
+// setupDataDir 按命令行参数选择数据目录
+// --portable 使用程序目录，--user 使用 %APPDATA%\DailyFlow，--data-dir 指定目录，都不指定时自动选择
+func setupDataDir(args []string) (string, error) {
+	flags := flag.NewFlagSet("DailyFlow", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	apply := storage.AddDataDirFlags(flags)
+	if err := flags.Parse(args); err != nil {
+		return "", err
+	}
+	return apply()
+}
+
 // ensureSingleInstance 确保单实例运行
 func ensureSingleInstance() bool {
 	mutexNamePtr, _ := windows.UTF16PtrFromString(mutexName)
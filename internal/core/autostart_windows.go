@@ -0,0 +1,125 @@
+package core
+
+import (
+	"dailyflow/internal/storage"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	ole32            = windows.NewLazySystemDLL("ole32.dll")
+	procCoInitialize = ole32.NewProc("CoInitialize")
+)
+
+// EnableAutoStart 启用开机自启动
+func EnableAutoStart() error {
+	return createStartupShortcut(true)
+}
+
+// DisableAutoStart 禁用开机自启动
+func DisableAutoStart() error {
+	return createStartupShortcut(false)
+}
+
+// IsAutoStartEnabled 检查是否启用了开机自启动
+func IsAutoStartEnabled() bool {
+	shortcutPath := getStartupShortcutPath()
+	_, err := os.Stat(shortcutPath)
+	return err == nil
+}
+
+// getStartupShortcutPath 获取启动文件夹中的快捷方式路径
+func getStartupShortcutPath() string {
+	appData := os.Getenv("APPDATA")
+	if appData == "" {
+		appData = filepath.Join(os.Getenv("USERPROFILE"), "AppData", "Roaming")
+	}
+	startupDir := filepath.Join(appData, "Microsoft", "Windows", "Start Menu", "Programs", "Startup")
+	return filepath.Join(startupDir, "DailyFlow.lnk")
+}
+
+// createStartupShortcut 创建或删除启动快捷方式
+func createStartupShortcut(enable bool) error {
+	shortcutPath := getStartupShortcutPath()
+
+	if !enable {
+		// 删除快捷方式
+		if err := os.Remove(shortcutPath); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove startup shortcut: %w", err)
+		}
+		return nil
+	}
+
+	// 获取当前可执行文件路径
+	exePath, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to get executable path: %w", err)
+	}
+
+	// 确保启动目录存在
+	startupDir := filepath.Dir(shortcutPath)
+	if err := os.MkdirAll(startupDir, 0755); err != nil {
+		return fmt.Errorf("failed to create startup directory: %w", err)
+	}
+
+	// 开机启动时沿用本次的数据目录选择（--portable / --user / --data-dir）
+	var arguments []string
+	for _, arg := range storage.DataDirArgs() {
+		arguments = append(arguments, windows.EscapeArg(arg))
+	}
+
+	// 创建快捷方式
+	// 注意：这里使用 Windows Shell API 创建 .lnk 文件
+	// 简化实现：直接调用 PowerShell 创建快捷方式
+	return createShortcutViaPowerShell(shortcutPath, exePath, strings.Join(arguments, " "))
+}
+
+// createShortcutViaPowerShell 通过 PowerShell 创建快捷方式
+func createShortcutViaPowerShell(shortcutPath, targetPath, arguments string) error {
+	// 使用 PowerShell 创建快捷方式（简化版本）
+	// 构建 PowerShell 命令（单引号字符串中的单引号需要写两次，双引号转义后才能通过命令行传给 PowerShell）
+	psCmd := fmt.Sprintf(
+		`$WshShell = New-Object -ComObject WScript.Shell; $Shortcut = $WshShell.CreateShortcut('%s'); $Shortcut.TargetPath = '%s'; $Shortcut.Arguments = '%s'; $Shortcut.Save()`,
+		shortcutPath,
+		targetPath,
+		strings.NewReplacer("'", "''", `"`, `\"`).Replace(arguments),
+	)
+
+	// 执行 PowerShell 命令
+	cmd := windows.StringToUTF16Ptr("powershell.exe")
+	args := windows.StringToUTF16Ptr("-NoProfile -NonInteractive -Command " + psCmd)
+	
+	var si windows.StartupInfo
+	var pi windows.ProcessInformation
+	si.Cb = uint32(unsafe.Sizeof(si))
+
+	err := windows.CreateProcess(
+		cmd,
+		args,
+		nil,
+		nil,
+		false,
+		windows.CREATE_NO_WINDOW,
+		nil,
+		nil,
+		&si,
+		&pi,
+	)
+
+	if err != nil {
+		return fmt.Errorf("failed to create shortcut: %w", err)
+	}
+
+	// 等待进程完成
+	windows.WaitForSingleObject(pi.Process, windows.INFINITE)
+	windows.CloseHandle(pi.Process)
+	windows.CloseHandle(pi.Thread)
+
+	return nil
+}
+
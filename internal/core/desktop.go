@@ -0,0 +1,28 @@
+package core
+
+import (
+	"dailyflow/internal/screen"
+)
+
+// Desktop 回放依赖的桌面操作：注入鼠标键盘输入、读取光标位置、枚举显示器和窗口
+// Windows 下由 NewDesktop 通过 SendInput 等 API 实现，测试中可以换成记录调用的假桌面
+type Desktop interface {
+	screen.WindowFinder
+
+	// MoveMouse 把光标移动到虚拟桌面坐标 (x, y)，副屏坐标可能为负数
+	MoveMouse(x, y int) error
+	// MouseButton 在当前光标位置按下或释放鼠标按键（"left"、"right"、"middle"）
+	MouseButton(button string, up bool) error
+	// Wheel 在当前光标位置滚动滚轮，orientation 为 model.WheelVertical 或 model.WheelHorizontal
+	Wheel(delta int, orientation string) error
+	// Key 按下或释放虚拟键码对应的按键
+	Key(keyCode int, up bool) error
+	// TypeText 以 Unicode 方式一次性输入文本，不受键盘布局和输入法影响
+	TypeText(text string) error
+	// CursorPos 返回当前光标位置
+	CursorPos() (x, y int)
+	// Monitors 返回当前所有显示器在虚拟桌面中的位置
+	Monitors() ([]screen.Monitor, error)
+	// PrimaryScreen 返回主显示器的分辨率
+	PrimaryScreen() screen.Rect
+}
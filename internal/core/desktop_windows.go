@@ -0,0 +1,249 @@
+package core
+
+import (
+	"dailyflow/internal/input"
+	"dailyflow/internal/model"
+	"dailyflow/internal/screen"
+	"fmt"
+	"unsafe"
+)
+
+const (
+	INPUT_MOUSE    = 0
+	INPUT_KEYBOARD = 1
+
+	MOUSEEVENTF_MOVE        = 0x0001
+	MOUSEEVENTF_LEFTDOWN    = 0x0002
+	MOUSEEVENTF_LEFTUP      = 0x0004
+	MOUSEEVENTF_RIGHTDOWN   = 0x0008
+	MOUSEEVENTF_RIGHTUP     = 0x0010
+	MOUSEEVENTF_MIDDLEDOWN  = 0x0020
+	MOUSEEVENTF_MIDDLEUP    = 0x0040
+	MOUSEEVENTF_WHEEL       = 0x0800
+	MOUSEEVENTF_HWHEEL      = 0x1000
+	MOUSEEVENTF_VIRTUALDESK = 0x4000
+	MOUSEEVENTF_ABSOLUTE    = 0x8000
+
+	KEYEVENTF_KEYUP   = 0x0002
+	KEYEVENTF_UNICODE = 0x0004
+)
+
+var (
+	procSendInput        = user32.NewProc("SendInput")
+	procSetCursorPos     = user32.NewProc("SetCursorPos")
+	procGetCursorPos     = user32.NewProc("GetCursorPos")
+	procGetAsyncKeyState = user32.NewProc("GetAsyncKeyState")
+)
+
+// MOUSE_INPUT 鼠标形态的 Windows INPUT 结构
+// INPUT 在 C 中是联合体，SendInput 要求 cbSize 与之完全一致，
+// 因此按鼠标/键盘分别定义，两者大小相同（amd64 下 40 字节）
+type MOUSE_INPUT struct {
+	Type uint32
+	Mi   MOUSEINPUT
+}
+
+// KEYBOARD_INPUT 键盘形态的 Windows INPUT 结构
+type KEYBOARD_INPUT struct {
+	Type uint32
+	Ki   KEYBDINPUT
+}
+
+// MOUSEINPUT 鼠标输入结构
+type MOUSEINPUT struct {
+	Dx          int32
+	Dy          int32
+	MouseData   uint32
+	DwFlags     uint32
+	Time        uint32
+	DwExtraInfo uintptr
+}
+
+// KEYBDINPUT 键盘输入结构
+type KEYBDINPUT struct {
+	WVk         uint16
+	WScan       uint16
+	DwFlags     uint32
+	Time        uint32
+	DwExtraInfo uintptr
+	Padding     [8]byte
+}
+
+// HARDWAREINPUT 硬件输入结构
+type HARDWAREINPUT struct {
+	UMsg    uint32
+	WParamL uint16
+	WParamH uint16
+}
+
+// systemDesktop 通过 SendInput 等 Win32 API 操作当前桌面
+type systemDesktop struct {
+	desktopWindowFinder
+}
+
+// NewDesktop 返回操作当前 Windows 桌面的 Desktop
+func NewDesktop() Desktop {
+	return systemDesktop{}
+}
+
+// MoveMouse 实现 Desktop
+// 使用虚拟桌面归一化的绝对坐标，副屏（包括负坐标）上的位置也能准确到达
+func (systemDesktop) MoveMouse(x, y int) error {
+	dx, dy := screen.Normalize(x, y, virtualScreen())
+	input := MOUSE_INPUT{
+		Type: INPUT_MOUSE,
+		Mi: MOUSEINPUT{
+			Dx:      dx,
+			Dy:      dy,
+			DwFlags: MOUSEEVENTF_MOVE | MOUSEEVENTF_ABSOLUTE | MOUSEEVENTF_VIRTUALDESK,
+		},
+	}
+	ret, _, err := procSendInput.Call(1, uintptr(unsafe.Pointer(&input)), unsafe.Sizeof(input))
+	if ret == 0 {
+		return fmt.Errorf("SendInput (mouse move) failed: %v", err)
+	}
+	return nil
+}
+
+// MouseButton 实现 Desktop
+func (systemDesktop) MouseButton(button string, up bool) error {
+	downFlag, upFlag, err := mouseButtonFlags(button)
+	if err != nil {
+		return err
+	}
+	flag, action := downFlag, "mouse down"
+	if up {
+		flag, action = upFlag, "mouse up"
+	}
+	if err := sendMouseInput(flag, 0); err != nil {
+		return fmt.Errorf("SendInput (%s) failed: %w", action, err)
+	}
+	return nil
+}
+
+// Wheel 实现 Desktop
+func (systemDesktop) Wheel(delta int, orientation string) error {
+	var flag uint32
+	switch orientation {
+	case model.WheelVertical, "":
+		flag = MOUSEEVENTF_WHEEL
+	case model.WheelHorizontal:
+		flag = MOUSEEVENTF_HWHEEL
+	default:
+		return fmt.Errorf("unknown wheel orientation: %s", orientation)
+	}
+
+	if err := sendMouseInput(flag, uint32(int32(delta))); err != nil {
+		return fmt.Errorf("SendInput (mouse wheel) failed: %w", err)
+	}
+	return nil
+}
+
+// Key 实现 Desktop
+func (systemDesktop) Key(keyCode int, up bool) error {
+	return sendKeyInput(uint16(keyCode), up)
+}
+
+// TypeText 实现 Desktop
+func (systemDesktop) TypeText(text string) error {
+	strokes := input.EncodeUnicode(text)
+	if len(strokes) == 0 {
+		return nil
+	}
+
+	inputs := make([]KEYBOARD_INPUT, len(strokes))
+	for i, stroke := range strokes {
+		inputs[i] = KEYBOARD_INPUT{
+			Type: INPUT_KEYBOARD,
+			Ki: KEYBDINPUT{
+				WScan:   stroke.Unit,
+				DwFlags: KEYEVENTF_UNICODE,
+			},
+		}
+		if stroke.Up {
+			inputs[i].Ki.DwFlags |= KEYEVENTF_KEYUP
+		}
+	}
+
+	// 一次性提交，避免与用户的物理输入交错
+	ret, _, err := procSendInput.Call(
+		uintptr(len(inputs)),
+		uintptr(unsafe.Pointer(&inputs[0])),
+		unsafe.Sizeof(inputs[0]),
+	)
+	if int(ret) != len(inputs) {
+		return fmt.Errorf("SendInput (type text) inserted %d of %d inputs: %v", ret, len(inputs), err)
+	}
+
+	return nil
+}
+
+// CursorPos 实现 Desktop
+func (systemDesktop) CursorPos() (int, int) {
+	var pt POINT
+	procGetCursorPos.Call(uintptr(unsafe.Pointer(&pt)))
+	return int(pt.X), int(pt.Y)
+}
+
+// Monitors 实现 Desktop
+func (systemDesktop) Monitors() ([]screen.Monitor, error) {
+	return enumMonitors()
+}
+
+// PrimaryScreen 实现 Desktop
+func (systemDesktop) PrimaryScreen() screen.Rect {
+	width, _, _ := procGetSystemMetrics.Call(0)  // SM_CXSCREEN
+	height, _, _ := procGetSystemMetrics.Call(1) // SM_CYSCREEN
+	return screen.Rect{Width: int(width), Height: int(height)}
+}
+
+// mouseButtonFlags 返回鼠标按键对应的按下和释放标志
+func mouseButtonFlags(button string) (uint32, uint32, error) {
+	switch button {
+	case "left":
+		return MOUSEEVENTF_LEFTDOWN, MOUSEEVENTF_LEFTUP, nil
+	case "right":
+		return MOUSEEVENTF_RIGHTDOWN, MOUSEEVENTF_RIGHTUP, nil
+	case "middle":
+		return MOUSEEVENTF_MIDDLEDOWN, MOUSEEVENTF_MIDDLEUP, nil
+	default:
+		return 0, 0, fmt.Errorf("unknown button: %s", button)
+	}
+}
+
+// sendMouseInput 通过 SendInput 发送一次鼠标输入
+func sendMouseInput(flags uint32, mouseData uint32) error {
+	input := MOUSE_INPUT{
+		Type: INPUT_MOUSE,
+		Mi: MOUSEINPUT{
+			MouseData: mouseData,
+			DwFlags:   flags,
+		},
+	}
+	ret, _, err := procSendInput.Call(1, uintptr(unsafe.Pointer(&input)), unsafe.Sizeof(input))
+	if ret == 0 {
+		return err
+	}
+	return nil
+}
+
+// sendKeyInput 通过 SendInput 发送单次按键按下或释放
+func sendKeyInput(vk uint16, up bool) error {
+	input := KEYBOARD_INPUT{
+		Type: INPUT_KEYBOARD,
+		Ki: KEYBDINPUT{
+			WVk: vk,
+		},
+	}
+	action := "key down"
+	if up {
+		input.Ki.DwFlags = KEYEVENTF_KEYUP
+		action = "key up"
+	}
+
+	ret, _, err := procSendInput.Call(1, uintptr(unsafe.Pointer(&input)), unsafe.Sizeof(input))
+	if ret == 0 {
+		return fmt.Errorf("SendInput (%s) failed: %v", action, err)
+	}
+	return nil
+}
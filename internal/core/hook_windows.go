@@ -0,0 +1,244 @@
+package core
+
+import (
+	"dailyflow/internal/model"
+	"dailyflow/internal/screen"
+	"fmt"
+	"runtime"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+const (
+	WH_MOUSE_LL    = 14
+	WH_KEYBOARD_LL = 13
+
+	WM_MOUSEMOVE   = 0x0200
+	WM_LBUTTONDOWN = 0x0201
+	WM_LBUTTONUP   = 0x0202
+	WM_RBUTTONDOWN = 0x0204
+	WM_RBUTTONUP   = 0x0205
+	WM_MBUTTONDOWN = 0x0207
+	WM_MBUTTONUP   = 0x0208
+	WM_MOUSEWHEEL  = 0x020A
+	WM_MOUSEHWHEEL = 0x020E
+	WM_KEYDOWN     = 0x0100
+	WM_KEYUP       = 0x0101
+	WM_SYSKEYDOWN  = 0x0104
+	WM_SYSKEYUP    = 0x0105
+	WM_QUIT        = 0x0012
+)
+
+var (
+	user32                  = windows.NewLazySystemDLL("user32.dll")
+	procSetWindowsHookEx    = user32.NewProc("SetWindowsHookExW")
+	procUnhookWindowsHookEx = user32.NewProc("UnhookWindowsHookEx")
+	procCallNextHookEx      = user32.NewProc("CallNextHookEx")
+	procGetMessage          = user32.NewProc("GetMessageW")
+	procPostThreadMessage   = user32.NewProc("PostThreadMessageW")
+	procGetSystemMetrics    = user32.NewProc("GetSystemMetrics")
+	procMessageBeep         = user32.NewProc("MessageBeep")
+)
+
+// MSLLHOOKSTRUCT 鼠标钩子结构
+type MSLLHOOKSTRUCT struct {
+	Pt          POINT
+	MouseData   uint32
+	Flags       uint32
+	Time        uint32
+	DwExtraInfo uintptr
+}
+
+// KBDLLHOOKSTRUCT 键盘钩子结构
+type KBDLLHOOKSTRUCT struct {
+	VkCode      uint32
+	ScanCode    uint32
+	Flags       uint32
+	Time        uint32
+	DwExtraInfo uintptr
+}
+
+// POINT 屏幕坐标点
+type POINT struct {
+	X, Y int32
+}
+
+// MSG Windows 消息结构
+type MSG struct {
+	Hwnd    uintptr
+	Message uint32
+	WParam  uintptr
+	LParam  uintptr
+	Time    uint32
+	Pt      POINT
+}
+
+// hookSource 通过低级鼠标、键盘钩子采集输入
+// 钩子在专用的线程中安装并由该线程的消息循环驱动，回调只把输入交给 emit，不做任何耗时操作
+type hookSource struct {
+	mouseCallback    uintptr // syscall.NewCallback 的数量有上限且不会释放，每个来源只创建一次
+	keyboardCallback uintptr
+	emit             func(RawInput)
+	threadID         uint32        // 钩子线程的 ID，Stop 时向它发送 WM_QUIT
+	done             chan struct{} // 钩子线程卸载钩子并退出后关闭
+}
+
+// NewInputSource 返回通过低级钩子采集当前桌面鼠标键盘输入的 InputSource
+func NewInputSource() InputSource {
+	s := &hookSource{}
+	s.mouseCallback = syscall.NewCallback(s.mouseProc)
+	s.keyboardCallback = syscall.NewCallback(s.keyboardProc)
+	return s
+}
+
+// Start 实现 InputSource：启动钩子线程，钩子安装完成（或失败）后返回
+func (s *hookSource) Start(emit func(RawInput)) error {
+	s.emit = emit
+	s.done = make(chan struct{})
+	started := make(chan error, 1)
+	go s.run(started)
+	return <-started
+}
+
+// Stop 实现 InputSource：结束钩子线程的消息循环，等待它卸载钩子
+func (s *hookSource) Stop() {
+	procPostThreadMessage.Call(uintptr(s.threadID), WM_QUIT, 0, 0)
+	<-s.done
+}
+
+// Window 实现 InputSource
+func (s *hookSource) Window(handle uintptr) (screen.Window, bool) {
+	return describeWindow(handle)
+}
+
+// WindowTitle 实现 InputSource
+func (s *hookSource) WindowTitle(handle uintptr) string {
+	return windowTitle(handle)
+}
+
+// Beep 实现 InputSource
+func (s *hookSource) Beep(sensitive bool) {
+	if sensitive {
+		procMessageBeep.Call(0x40) // MB_ICONASTERISK
+	} else {
+		procMessageBeep.Call(0) // MB_OK
+	}
+}
+
+// run 钩子线程：安装钩子后运行消息循环，收到 WM_QUIT 时卸载钩子并退出
+// 低级钩子的回调在安装它的线程的消息循环中执行，因此安装、消息循环和卸载必须在同一个系统线程上
+func (s *hookSource) run(started chan<- error) {
+	defer close(s.done)
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	s.threadID = windows.GetCurrentThreadId()
+
+	mouseHook, _, err := procSetWindowsHookEx.Call(uintptr(WH_MOUSE_LL), s.mouseCallback, 0, 0)
+	if mouseHook == 0 {
+		started <- fmt.Errorf("failed to install mouse hook: %v", err)
+		return
+	}
+	defer procUnhookWindowsHookEx.Call(mouseHook)
+
+	keyboardHook, _, err := procSetWindowsHookEx.Call(uintptr(WH_KEYBOARD_LL), s.keyboardCallback, 0, 0)
+	if keyboardHook == 0 {
+		started <- fmt.Errorf("failed to install keyboard hook: %v", err)
+		return
+	}
+	defer procUnhookWindowsHookEx.Call(keyboardHook)
+
+	started <- nil
+
+	// GetMessage 返回 0（WM_QUIT）或 -1（出错）时结束
+	var msg MSG
+	for {
+		ret, _, _ := procGetMessage.Call(uintptr(unsafe.Pointer(&msg)), 0, 0, 0)
+		if int32(ret) <= 0 {
+			return
+		}
+	}
+}
+
+// mouseProc 鼠标钩子回调
+// 按下时记下光标下的顶层窗口句柄，窗口的标题和位置由消费协程读取
+func (s *hookSource) mouseProc(nCode int, wParam uintptr, lParam uintptr) uintptr {
+	if nCode >= 0 {
+		mouseInfo := (*MSLLHOOKSTRUCT)(unsafe.Pointer(lParam))
+		event := model.Event{
+			X:      int(mouseInfo.Pt.X),
+			Y:      int(mouseInfo.Pt.Y),
+			Button: "none",
+		}
+
+		switch wParam {
+		case WM_MOUSEMOVE:
+			event.Type = model.EventMouseMove
+		case WM_LBUTTONDOWN, WM_LBUTTONUP, WM_RBUTTONDOWN, WM_RBUTTONUP, WM_MBUTTONDOWN, WM_MBUTTONUP:
+			// 按下与释放分别记录，停止录制时再把原地的按下+释放合并为点击
+			event.Type, event.Button = mouseButtonEvent(wParam)
+		case WM_MOUSEWHEEL, WM_MOUSEHWHEEL:
+			// MouseData 高位字为有符号的滚轮增量
+			event.Type = model.EventMouseWheel
+			event.WheelDelta = int(int16(mouseInfo.MouseData >> 16))
+			event.Orientation = model.WheelVertical
+			if wParam == WM_MOUSEHWHEEL {
+				event.Orientation = model.WheelHorizontal
+			}
+		}
+		if event.Type != "" {
+			input := RawInput{Event: event, Time: time.Now()}
+			if event.Type == model.EventMouseDown {
+				input.Window = rootWindowAt(mouseInfo.Pt)
+			}
+			s.emit(input)
+		}
+	}
+
+	ret, _, _ := procCallNextHookEx.Call(0, uintptr(nCode), wParam, lParam)
+	return ret
+}
+
+// mouseButtonEvent 把鼠标按键消息转换为事件类型和按键名称
+func mouseButtonEvent(wParam uintptr) (string, string) {
+	switch wParam {
+	case WM_LBUTTONDOWN:
+		return model.EventMouseDown, "left"
+	case WM_LBUTTONUP:
+		return model.EventMouseUp, "left"
+	case WM_RBUTTONDOWN:
+		return model.EventMouseDown, "right"
+	case WM_RBUTTONUP:
+		return model.EventMouseUp, "right"
+	case WM_MBUTTONDOWN:
+		return model.EventMouseDown, "middle"
+	default:
+		return model.EventMouseUp, "middle"
+	}
+}
+
+// keyboardProc 键盘钩子回调
+// 记下按键时的前台窗口句柄，消费协程据此判断按键是否输入到敏感窗口
+func (s *hookSource) keyboardProc(nCode int, wParam uintptr, lParam uintptr) uintptr {
+	if nCode >= 0 {
+		var eventType string
+		switch wParam {
+		case WM_KEYDOWN, WM_SYSKEYDOWN:
+			eventType = model.EventKeyDown
+		case WM_KEYUP, WM_SYSKEYUP:
+			eventType = model.EventKeyUp
+		}
+
+		if eventType != "" {
+			kbInfo := (*KBDLLHOOKSTRUCT)(unsafe.Pointer(lParam))
+			event := model.Event{Type: eventType, Button: "none", KeyCode: int(kbInfo.VkCode)}
+			s.emit(RawInput{Event: event, Time: time.Now(), Window: foregroundWindow()})
+		}
+	}
+
+	ret, _, _ := procCallNextHookEx.Call(0, uintptr(nCode), wParam, lParam)
+	return ret
+}
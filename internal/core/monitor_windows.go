@@ -0,0 +1,92 @@
+package core
+
+import (
+	"dailyflow/internal/screen"
+	"fmt"
+	"sync"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	SM_XVIRTUALSCREEN  = 76
+	SM_YVIRTUALSCREEN  = 77
+	SM_CXVIRTUALSCREEN = 78
+	SM_CYVIRTUALSCREEN = 79
+
+	MONITORINFOF_PRIMARY = 0x00000001
+)
+
+var (
+	procEnumDisplayMonitors = user32.NewProc("EnumDisplayMonitors")
+	procGetMonitorInfo      = user32.NewProc("GetMonitorInfoW")
+
+	// syscall.NewCallback 创建的回调数量有限，只创建一次
+	monitorEnumCallback = syscall.NewCallback(monitorEnumProc)
+	monitorEnumMutex    sync.Mutex
+	monitorEnumResult   []screen.Monitor
+)
+
+// RECT Windows 矩形结构
+type RECT struct {
+	Left, Top, Right, Bottom int32
+}
+
+// MONITORINFO 显示器信息结构
+type MONITORINFO struct {
+	CbSize    uint32
+	RcMonitor RECT
+	RcWork    RECT
+	DwFlags   uint32
+}
+
+// enumMonitors 获取当前所有显示器在虚拟桌面中的位置
+func enumMonitors() ([]screen.Monitor, error) {
+	monitorEnumMutex.Lock()
+	defer monitorEnumMutex.Unlock()
+
+	monitorEnumResult = nil
+	ret, _, err := procEnumDisplayMonitors.Call(0, 0, monitorEnumCallback, 0)
+	if ret == 0 {
+		return nil, fmt.Errorf("EnumDisplayMonitors failed: %v", err)
+	}
+
+	monitors := monitorEnumResult
+	monitorEnumResult = nil
+	return monitors, nil
+}
+
+// monitorEnumProc EnumDisplayMonitors 回调，逐个收集显示器信息
+func monitorEnumProc(hMonitor, hdc, rect, lParam uintptr) uintptr {
+	info := MONITORINFO{}
+	info.CbSize = uint32(unsafe.Sizeof(info))
+	ret, _, _ := procGetMonitorInfo.Call(hMonitor, uintptr(unsafe.Pointer(&info)))
+	if ret != 0 {
+		monitorEnumResult = append(monitorEnumResult, screen.Monitor{
+			Rect: screen.Rect{
+				Left:   int(info.RcMonitor.Left),
+				Top:    int(info.RcMonitor.Top),
+				Width:  int(info.RcMonitor.Right - info.RcMonitor.Left),
+				Height: int(info.RcMonitor.Bottom - info.RcMonitor.Top),
+			},
+			Primary: info.DwFlags&MONITORINFOF_PRIMARY != 0,
+		})
+	}
+	return 1 // 继续枚举
+}
+
+// virtualScreen 获取当前虚拟桌面区域（包含所有显示器）
+func virtualScreen() screen.Rect {
+	left, _, _ := procGetSystemMetrics.Call(SM_XVIRTUALSCREEN)
+	top, _, _ := procGetSystemMetrics.Call(SM_YVIRTUALSCREEN)
+	width, _, _ := procGetSystemMetrics.Call(SM_CXVIRTUALSCREEN)
+	height, _, _ := procGetSystemMetrics.Call(SM_CYVIRTUALSCREEN)
+
+	// 左侧或上方有副屏时原点为负数，GetSystemMetrics 返回的是 int
+	return screen.Rect{
+		Left:   int(int32(left)),
+		Top:    int(int32(top)),
+		Width:  int(int32(width)),
+		Height: int(int32(height)),
+	}
+}
@@ -0,0 +1,289 @@
+package core
+
+import (
+	"dailyflow/internal/model"
+	"dailyflow/internal/screen"
+	"dailyflow/internal/storage"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// RecordHotKey 开始和停止录制的热键（F8），本身不会被录制
+const RecordHotKey = 0x77
+
+// recordQueueSize 原始输入队列的容量，消费协程来不及处理时多出的输入被丢弃并计数
+const recordQueueSize = 4096
+
+// mouseMoveInterval 鼠标移动的最小采样间隔
+const mouseMoveInterval = 50 * time.Millisecond
+
+// RawInput 输入来源采集的一次原始输入
+// 钩子回调只填写这些字段并放入队列，读取窗口信息、敏感输入处理和延迟计算都在录制的消费协程中进行，
+// 避免回调耗时过长被 Windows 移除钩子。目标窗口的句柄必须在输入发生时记下，队列积压时窗口可能已经变化
+type RawInput struct {
+	Event  model.Event // 事件类型、坐标、按键或滚轮增量；Delay 和 Window 由消费协程填写
+	Time   time.Time   // 输入发生的时刻
+	Window uintptr     // 输入发生时的窗口句柄：鼠标按下为光标下的顶层窗口，按键为前台窗口；0 表示没有
+}
+
+// InputSource 录制的输入来源
+// Windows 下由 NewInputSource 通过低级鼠标、键盘钩子实现，测试中可以换成发送合成输入的来源
+type InputSource interface {
+	// Start 开始采集输入，每个输入调用一次 emit（emit 不会阻塞）
+	Start(emit func(RawInput)) error
+	// Stop 停止采集，返回后不再调用 emit
+	Stop()
+	// Window 返回 RawInput.Window 句柄对应窗口的标题、类名和位置，用于按窗口定位点击
+	Window(handle uintptr) (screen.Window, bool)
+	// WindowTitle 返回 RawInput.Window 句柄对应窗口的标题，用于判断敏感窗口
+	WindowTitle(handle uintptr) string
+	// Beep 切换敏感输入后发出提示音，sensitive 为切换后的状态
+	Beep(sensitive bool)
+}
+
+// RecordStats 一次录制采集和丢弃的输入数
+type RecordStats struct {
+	Received       int // 采集到的输入数，含被丢弃的
+	Dropped        int // 队列已满而丢弃的输入数
+	DroppedActions int // 其中鼠标按键、滚轮和键盘输入的个数（其余为鼠标移动），丢失会使回放不完整
+}
+
+// Recorder 录制引擎
+type Recorder struct {
+	store            storage.Store // 停止录制时保存任务
+	desktop          Desktop       // 读取录制时的分辨率和显示器布局
+	source           InputSource   // 采集鼠标键盘输入
+	taskName         string
+	isRecording      bool
+	session          *recording // 当前或最近一次录制
+	optimizeOptions  model.OptimizeOptions
+	sensitiveWindows []string         // 按键自动录制为敏感输入的窗口标题关键字
+	now              func() time.Time // 录制开始的时刻，测试中可替换
+	mutex            sync.Mutex
+}
+
+// recording 一次录制的状态
+// 输入来源只通过 emit 把输入放入 queue；task 等其余字段只由消费协程访问，done 关闭后由 StopRecording 读取
+type recording struct {
+	source         InputSource
+	queue          chan RawInput
+	done           chan struct{} // 消费协程处理完队列中的全部输入后关闭
+	received       atomic.Int64
+	dropped        atomic.Int64
+	droppedActions atomic.Int64
+
+	task          *model.TaskData
+	redactor      *redactor // 敏感输入处理
+	lastEventTime time.Time // 上一个录制的事件的时刻，用于计算延迟
+	lastMoveTime  time.Time // 上一个录制的鼠标移动的时刻，用于限频采样
+	lastMoveX     int
+	lastMoveY     int
+}
+
+// NewRecorder 创建新的录制器，从 source 采集输入，停止录制时把任务保存到 store
+func NewRecorder(store storage.Store, desktop Desktop, source InputSource) *Recorder {
+	return &Recorder{
+		store:           store,
+		desktop:         desktop,
+		source:          source,
+		optimizeOptions: model.OptimizeOptions{PathTolerance: model.DefaultPathTolerance},
+		now:             time.Now,
+	}
+}
+
+// SetOptimizeOptions 设置停止录制时使用的优化参数
+func (r *Recorder) SetOptimizeOptions(opts model.OptimizeOptions) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.optimizeOptions = opts
+}
+
+// SetSensitiveWindows 设置自动视为敏感输入的窗口标题关键字（不区分大小写），下次开始录制时生效
+func (r *Recorder) SetSensitiveWindows(keywords []string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.sensitiveWindows = append([]string(nil), keywords...)
+}
+
+// StartRecording 开始录制，停止时保存为任务库中的 taskName（同名任务会被覆盖）
+func (r *Recorder) StartRecording(taskName string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.isRecording {
+		return fmt.Errorf("recording is already in progress")
+	}
+
+	if err := storage.ValidateTaskName(taskName); err != nil {
+		return err
+	}
+
+	// 初始化任务数据
+	now := r.now()
+	task := model.NewTaskData(r.desktop.PrimaryScreen().Resolution())
+	task.Meta.CreatedAt = now.Unix()
+
+	// 记录显示器布局，回放时用于检测布局变化
+	if monitors, err := r.desktop.Monitors(); err == nil {
+		task.Meta.Monitors = monitors
+	} else {
+		fmt.Printf("Error enumerating monitors: %v\n", err)
+	}
+
+	session := &recording{
+		source:        r.source,
+		queue:         make(chan RawInput, recordQueueSize),
+		done:          make(chan struct{}),
+		task:          task,
+		redactor:      newRedactor(taskName, r.sensitiveWindows),
+		lastEventTime: now,
+		lastMoveTime:  now,
+	}
+	go session.consume()
+
+	if err := r.source.Start(session.emit); err != nil {
+		close(session.queue)
+		<-session.done
+		return fmt.Errorf("failed to start recording: %w", err)
+	}
+
+	r.taskName = taskName
+	r.session = session
+	r.isRecording = true
+	return nil
+}
+
+// StopRecording 停止录制，等待已采集的输入处理完后优化并保存任务
+func (r *Recorder) StopRecording() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if !r.isRecording {
+		return fmt.Errorf("no recording in progress")
+	}
+	r.isRecording = false
+
+	// 输入来源停止后不会再发送输入，此时才能关闭队列
+	r.source.Stop()
+	session := r.session
+	close(session.queue)
+	<-session.done
+
+	if stats := session.stats(); stats.Dropped > 0 {
+		fmt.Printf("Recording dropped %d of %d inputs (%d clicks, wheels or keys)\n",
+			stats.Dropped, stats.Received, stats.DroppedActions)
+	}
+
+	// 合并点击、丢弃无用的鼠标移动并简化路径
+	session.task.Optimize(r.optimizeOptions)
+
+	// 保存任务数据
+	if err := r.store.SaveTask(r.taskName, session.task); err != nil {
+		return fmt.Errorf("failed to save task: %w", err)
+	}
+	return nil
+}
+
+// IsRecording 检查是否正在录制
+func (r *Recorder) IsRecording() bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.isRecording
+}
+
+// Stats 返回当前或最近一次录制的输入统计，录制中也可以调用
+func (r *Recorder) Stats() RecordStats {
+	r.mutex.Lock()
+	session := r.session
+	r.mutex.Unlock()
+
+	if session == nil {
+		return RecordStats{}
+	}
+	return session.stats()
+}
+
+// stats 返回本次录制的输入统计
+func (s *recording) stats() RecordStats {
+	return RecordStats{
+		Received:       int(s.received.Load()),
+		Dropped:        int(s.dropped.Load()),
+		DroppedActions: int(s.droppedActions.Load()),
+	}
+}
+
+// emit 把输入放入队列，队列已满时丢弃并计数，从不阻塞输入来源
+func (s *recording) emit(input RawInput) {
+	s.received.Add(1)
+	select {
+	case s.queue <- input:
+	default:
+		s.dropped.Add(1)
+		if input.Event.Type != model.EventMouseMove {
+			s.droppedActions.Add(1)
+		}
+	}
+}
+
+// consume 唯一的消费协程：按采集顺序把输入转换为任务事件，队列关闭且处理完后结束
+func (s *recording) consume() {
+	defer close(s.done)
+	for input := range s.queue {
+		s.record(input)
+	}
+}
+
+// record 处理一个输入，需要录制时追加到任务
+func (s *recording) record(input RawInput) {
+	event := input.Event
+	event.Delay = int(input.Time.Sub(s.lastEventTime).Milliseconds())
+
+	switch event.Type {
+	case model.EventMouseMove:
+		// 限频采样，并忽略没有真正移动的微小抖动
+		if input.Time.Sub(s.lastMoveTime) < mouseMoveInterval || (event.X == s.lastMoveX && event.Y == s.lastMoveY) {
+			return
+		}
+		s.lastMoveTime, s.lastMoveX, s.lastMoveY = input.Time, event.X, event.Y
+
+	case model.EventMouseDown:
+		// 点击通常意味着换了输入框，之后的敏感输入另起一段
+		s.redactor.breakRange()
+		// 按下时记录目标窗口，合并为点击后回放可按窗口相对位置定位
+		if input.Window != 0 {
+			if window, ok := s.source.Window(input.Window); ok {
+				event.Window = &window
+				event.RelX = event.X - window.Left
+				event.RelY = event.Y - window.Top
+			}
+		}
+
+	case model.EventKeyDown, model.EventKeyUp:
+		switch event.KeyCode {
+		case RecordHotKey:
+			// 录制控制键，按下和释放都不记录
+			return
+		case SensitiveToggleKey:
+			// 切换敏感输入，按下时提示音表示切换成功，释放不记录
+			if event.Type == model.EventKeyDown {
+				s.source.Beep(s.redactor.toggle())
+			}
+			return
+		}
+
+		// 敏感输入替换为占位事件；被丢弃的按键不更新时间基准，下一个事件的延迟包含输入所用的时间
+		title := ""
+		if s.redactor.watchesWindows() && input.Window != 0 {
+			title = s.source.WindowTitle(input.Window)
+		}
+		var ok bool
+		if event, ok = s.redactor.key(event, title); !ok {
+			return
+		}
+	}
+
+	s.task.AddEvent(event)
+	s.lastEventTime = input.Time
+}
@@ -0,0 +1,352 @@
+package core
+
+import (
+	"dailyflow/internal/model"
+	"dailyflow/internal/screen"
+	"dailyflow/internal/storage"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeSource 发送合成输入的输入来源，send 可以在任意协程中调用，模拟钩子线程
+type fakeSource struct {
+	mutex      sync.Mutex
+	emit       func(RawInput)
+	windows    map[uintptr]screen.Window // 桌面上的窗口，按句柄从小到大视为从上到下叠放
+	foreground uintptr                   // 前台窗口句柄
+	beeps      []bool
+	startErr   error
+	entered    chan struct{} // 不为 nil 时，Window 先通知 entered 再等待 release，用于阻塞消费协程
+	release    chan struct{}
+}
+
+func (s *fakeSource) Start(emit func(RawInput)) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.startErr != nil {
+		return s.startErr
+	}
+	s.emit = emit
+	return nil
+}
+
+func (s *fakeSource) Stop() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.emit = nil
+}
+
+// send 依次发送输入，停止后发送的输入被忽略
+// 和钩子一样在发送时记下窗口句柄：按下鼠标时为该位置最上层的窗口，按键时为前台窗口
+func (s *fakeSource) send(inputs ...RawInput) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for _, input := range inputs {
+		switch input.Event.Type {
+		case model.EventMouseDown:
+			input.Window = s.windowAt(input.Event.X, input.Event.Y)
+		case model.EventKeyDown, model.EventKeyUp:
+			input.Window = s.foreground
+		}
+		if s.emit != nil {
+			s.emit(input)
+		}
+	}
+}
+
+// windowAt 返回 (x, y) 处最上层窗口的句柄，没有时返回 0
+func (s *fakeSource) windowAt(x, y int) uintptr {
+	var found uintptr
+	for handle, window := range s.windows {
+		if window.Contains(x, y) && (found == 0 || handle < found) {
+			found = handle
+		}
+	}
+	return found
+}
+
+// setWindow 添加或替换窗口
+func (s *fakeSource) setWindow(handle uintptr, window screen.Window) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.windows == nil {
+		s.windows = make(map[uintptr]screen.Window)
+	}
+	s.windows[handle] = window
+}
+
+// setForeground 切换前台窗口
+func (s *fakeSource) setForeground(handle uintptr) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.foreground = handle
+}
+
+func (s *fakeSource) Window(handle uintptr) (screen.Window, bool) {
+	if s.entered != nil {
+		s.entered <- struct{}{}
+		<-s.release
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	window, ok := s.windows[handle]
+	return window, ok
+}
+
+func (s *fakeSource) WindowTitle(handle uintptr) string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.windows[handle].Title
+}
+
+func (s *fakeSource) Beep(sensitive bool) {
+	s.beeps = append(s.beeps, sensitive)
+}
+
+// newTestRecorder 创建使用内存存储、假桌面和合成输入来源的录制器，录制开始于固定时刻
+func newTestRecorder(t *testing.T) (*Recorder, *fakeSource, *storage.MemoryStore, time.Time) {
+	t.Helper()
+	store := storage.NewMemoryStore()
+	source := &fakeSource{}
+	recorder := NewRecorder(store, newFakeDesktop(), source)
+	start := time.Date(2024, 12, 2, 8, 30, 0, 0, time.Local)
+	recorder.now = func() time.Time { return start }
+	// 不简化路径，便于逐个比较事件
+	recorder.SetOptimizeOptions(model.OptimizeOptions{})
+	return recorder, source, store, start
+}
+
+// rawAt 创建 start 之后 ms 毫秒发生的原始输入
+func rawAt(start time.Time, ms int, event model.Event) RawInput {
+	return RawInput{Event: event, Time: start.Add(time.Duration(ms) * time.Millisecond)}
+}
+
+func TestRecording(t *testing.T) {
+	recorder, source, store, start := newTestRecorder(t)
+	notepad := screen.Window{Title: "记事本", Class: "Notepad", Rect: screen.Rect{Left: 250, Top: 250, Width: 400, Height: 300}}
+	source.setWindow(1, notepad)
+	move := func(x, y int) model.Event { return model.Event{Type: model.EventMouseMove, X: x, Y: y, Button: "none"} }
+	key := func(eventType string, code int) model.Event {
+		return model.Event{Type: eventType, Button: "none", KeyCode: code}
+	}
+
+	if err := recorder.StartRecording("录制"); err != nil {
+		t.Fatalf("StartRecording() error = %v", err)
+	}
+	if err := recorder.StartRecording("录制"); err == nil {
+		t.Error("second StartRecording() error = nil, want already in progress")
+	}
+	source.send(
+		rawAt(start, 10, move(100, 100)), // 距开始不足 50ms，不采样
+		rawAt(start, 100, move(200, 200)),
+		rawAt(start, 200, move(200, 200)), // 没有移动
+		rawAt(start, 300, model.Event{Type: model.EventMouseDown, X: 300, Y: 300, Button: "left"}),
+		rawAt(start, 350, model.Event{Type: model.EventMouseUp, X: 300, Y: 300, Button: "left"}),
+		rawAt(start, 450, model.Event{Type: model.EventMouseWheel, X: 300, Y: 300, Button: "none", WheelDelta: -120, Orientation: model.WheelVertical}),
+		rawAt(start, 500, key(model.EventKeyDown, RecordHotKey)),
+		rawAt(start, 510, key(model.EventKeyUp, RecordHotKey)),
+		rawAt(start, 600, key(model.EventKeyDown, 'A')),
+		rawAt(start, 650, key(model.EventKeyUp, 'A')),
+		rawAt(start, 700, key(model.EventKeyDown, SensitiveToggleKey)),
+		rawAt(start, 710, key(model.EventKeyUp, SensitiveToggleKey)),
+		rawAt(start, 800, key(model.EventKeyDown, 'P')),
+		rawAt(start, 820, key(model.EventKeyUp, 'P')),
+		rawAt(start, 900, key(model.EventKeyDown, SensitiveToggleKey)),
+		rawAt(start, 1000, key(model.EventKeyDown, 0x0D)),
+	)
+	if err := recorder.StopRecording(); err != nil {
+		t.Fatalf("StopRecording() error = %v", err)
+	}
+	if recorder.IsRecording() {
+		t.Error("IsRecording() = true after StopRecording()")
+	}
+	// 停止后的输入不再录制
+	source.send(rawAt(start, 1100, key(model.EventKeyUp, 0x0D)))
+
+	task, err := store.LoadTask("录制")
+	if err != nil {
+		t.Fatalf("LoadTask() error = %v", err)
+	}
+	want := []model.Event{
+		{Type: model.EventMouseMove, X: 200, Y: 200, Button: "none", Delay: 100},
+		{Type: model.EventMouseClick, X: 300, Y: 300, Button: "left", Delay: 200,
+			Window: &notepad, RelX: 50, RelY: 50},
+		// 被合并的释放的延迟累加到下一个事件
+		{Type: model.EventMouseWheel, X: 300, Y: 300, Button: "none", WheelDelta: -120, Orientation: model.WheelVertical, Delay: 150},
+		{Type: model.EventKeyDown, Button: "none", KeyCode: 'A', Delay: 150},
+		{Type: model.EventKeyUp, Button: "none", KeyCode: 'A', Delay: 50},
+		{Type: model.EventSecret, Button: "none", Secret: "录制#1", Delay: 150},
+		{Type: model.EventKeyDown, Button: "none", KeyCode: 0x0D, Delay: 200},
+	}
+	if !reflect.DeepEqual(task.Events, want) {
+		t.Errorf("recorded events =\n%+v\nwant\n%+v", task.Events, want)
+	}
+	if task.Meta.Resolution != "1920x1080" || task.Meta.CreatedAt != start.Unix() || len(task.Meta.Monitors) != 1 {
+		t.Errorf("recorded meta = %+v", task.Meta)
+	}
+	if want := []bool{true, false}; !reflect.DeepEqual(source.beeps, want) {
+		t.Errorf("beeps = %v, want %v", source.beeps, want)
+	}
+	if stats := recorder.Stats(); stats != (RecordStats{Received: 16}) {
+		t.Errorf("Stats() = %+v, want 16 received and none dropped", stats)
+	}
+}
+
+func TestRecordingDropsWhenQueueFull(t *testing.T) {
+	recorder, source, store, start := newTestRecorder(t)
+	source.setWindow(1, screen.Window{Title: "记事本", Rect: screen.Rect{Width: 100, Height: 100}})
+	source.entered = make(chan struct{})
+	source.release = make(chan struct{})
+
+	if err := recorder.StartRecording("录制"); err != nil {
+		t.Fatalf("StartRecording() error = %v", err)
+	}
+
+	// 消费协程处理按下时阻塞在查询窗口上，之后的输入只能进入队列
+	go source.send(rawAt(start, 100, model.Event{Type: model.EventMouseDown, X: 1, Y: 1, Button: "left"}))
+	<-source.entered
+
+	var inputs []RawInput
+	for i := 0; i < recordQueueSize+5; i++ {
+		inputs = append(inputs, rawAt(start, 200+i, model.Event{Type: model.EventMouseMove, X: i, Y: 1, Button: "none"}))
+	}
+	for i := 0; i < 3; i++ {
+		inputs = append(inputs, rawAt(start, 10000, model.Event{Type: model.EventKeyDown, Button: "none", KeyCode: 'A'}))
+	}
+	source.send(inputs...)
+
+	// 录制中也可以读取统计
+	want := RecordStats{Received: 1 + recordQueueSize + 8, Dropped: 8, DroppedActions: 3}
+	if stats := recorder.Stats(); stats != want {
+		t.Errorf("Stats() during recording = %+v, want %+v", stats, want)
+	}
+
+	close(source.release)
+	if err := recorder.StopRecording(); err != nil {
+		t.Fatalf("StopRecording() error = %v", err)
+	}
+	if stats := recorder.Stats(); stats != want {
+		t.Errorf("Stats() = %+v, want %+v", stats, want)
+	}
+
+	// 队列中的输入全部处理完才保存：按下和采样后的移动，丢弃的按键没有录下
+	task, err := store.LoadTask("录制")
+	if err != nil {
+		t.Fatalf("LoadTask() error = %v", err)
+	}
+	if task.Events[0].Type != model.EventMouseDown {
+		t.Errorf("first event = %+v, want mouse_down", task.Events[0])
+	}
+	for _, event := range task.Events {
+		if event.Type == model.EventKeyDown {
+			t.Fatalf("dropped key was recorded: %+v", event)
+		}
+	}
+}
+
+func TestRecordingUsesWindowAtInputTime(t *testing.T) {
+	recorder, source, store, start := newTestRecorder(t)
+	recorder.SetSensitiveWindows([]string{"登录"})
+	login := screen.Window{Title: "登录 - 网银", Class: "Login", Rect: screen.Rect{Left: 600, Top: 100, Width: 400, Height: 300}}
+	source.setWindow(2, login)
+	source.setForeground(2)
+	source.entered = make(chan struct{})
+	source.release = make(chan struct{})
+
+	if err := recorder.StartRecording("录制"); err != nil {
+		t.Fatalf("StartRecording() error = %v", err)
+	}
+
+	// 消费协程处理按下时阻塞，之后的按键在队列中积压
+	go source.send(rawAt(start, 100, model.Event{Type: model.EventMouseDown, X: 700, Y: 200, Button: "left"}))
+	<-source.entered
+	source.send(
+		rawAt(start, 150, model.Event{Type: model.EventMouseUp, X: 700, Y: 200, Button: "left"}),
+		rawAt(start, 200, model.Event{Type: model.EventKeyDown, Button: "none", KeyCode: 'P'}),
+		rawAt(start, 250, model.Event{Type: model.EventKeyUp, Button: "none", KeyCode: 'P'}),
+	)
+
+	// 处理积压的输入之前，记事本打开在同一位置并成为前台窗口
+	source.setWindow(1, screen.Window{Title: "记事本", Class: "Notepad", Rect: screen.Rect{Left: 500, Top: 0, Width: 800, Height: 600}})
+	source.setForeground(1)
+	close(source.release)
+
+	if err := recorder.StopRecording(); err != nil {
+		t.Fatalf("StopRecording() error = %v", err)
+	}
+	task, err := store.LoadTask("录制")
+	if err != nil {
+		t.Fatalf("LoadTask() error = %v", err)
+	}
+
+	// 点击记录按下时光标下的窗口，按键按输入时的前台窗口处理为敏感输入
+	want := []model.Event{
+		{Type: model.EventMouseClick, X: 700, Y: 200, Button: "left", Delay: 100,
+			Window: &login, RelX: 100, RelY: 100},
+		// 被合并的释放的延迟累加到下一个事件
+		{Type: model.EventSecret, Button: "none", Secret: "录制#1", Delay: 100},
+	}
+	if !reflect.DeepEqual(task.Events, want) {
+		t.Errorf("recorded events =\n%+v\nwant\n%+v", task.Events, want)
+	}
+}
+
+func TestRecordingConcurrentInput(t *testing.T) {
+	recorder, source, store, start := newTestRecorder(t)
+	if err := recorder.StartRecording("录制"); err != nil {
+		t.Fatalf("StartRecording() error = %v", err)
+	}
+
+	// 多个协程同时发送输入，同时读取录制状态；在竞态检测下运行
+	const senders, perSender = 4, 1000
+	var wg sync.WaitGroup
+	for n := 0; n < senders; n++ {
+		wg.Add(1)
+		go func(n int) {
+			defer wg.Done()
+			for i := 0; i < perSender; i++ {
+				source.send(rawAt(start, n*perSender+i, model.Event{Type: model.EventKeyDown, Button: "none", KeyCode: 'A' + n}))
+			}
+		}(n)
+	}
+	for i := 0; i < 100; i++ {
+		recorder.Stats()
+		recorder.IsRecording()
+	}
+	wg.Wait()
+
+	if err := recorder.StopRecording(); err != nil {
+		t.Fatalf("StopRecording() error = %v", err)
+	}
+	task, err := store.LoadTask("录制")
+	if err != nil {
+		t.Fatalf("LoadTask() error = %v", err)
+	}
+	stats := recorder.Stats()
+	if stats.Received != senders*perSender || len(task.Events)+stats.Dropped != stats.Received {
+		t.Errorf("recorded %d events with %+v, want every input recorded or counted as dropped", len(task.Events), stats)
+	}
+}
+
+func TestStartRecordingFailure(t *testing.T) {
+	recorder, source, _, _ := newTestRecorder(t)
+	source.startErr = errors.New("hook refused")
+
+	err := recorder.StartRecording("录制")
+	if err == nil || !errors.Is(err, source.startErr) {
+		t.Fatalf("StartRecording() error = %v, want hook refused", err)
+	}
+	if recorder.IsRecording() {
+		t.Error("IsRecording() = true after failed start")
+	}
+	if err := recorder.StopRecording(); err == nil {
+		t.Error("StopRecording() error = nil, want no recording in progress")
+	}
+
+	if err := recorder.StartRecording("bad/name"); !errors.Is(err, storage.ErrInvalidTaskName) {
+		t.Errorf("StartRecording() with invalid name error = %v, want ErrInvalidTaskName", err)
+	}
+}
@@ -0,0 +1,92 @@
+package core
+
+import (
+	"dailyflow/internal/model"
+	"fmt"
+	"strings"
+)
+
+// SensitiveToggleKey 录制时切换敏感输入的热键（F9），本身不会被录制
+const SensitiveToggleKey = 0x78
+
+// sensitiveBreakKeys 敏感输入中也照常录制的按键：按下时结束当前的敏感输入，
+// 否则输入密码后的回车、切换输入框的 Tab 也会被当作密码的一部分丢弃
+var sensitiveBreakKeys = map[int]bool{
+	0x09: true, // VK_TAB
+	0x0D: true, // VK_RETURN
+	0x1B: true, // VK_ESCAPE
+}
+
+// redactor 录制时把敏感输入替换为 secret 占位事件
+// 每段连续的敏感按键只生成一个占位事件，按键本身不写入任务
+type redactor struct {
+	taskName string
+	windows  []string // 自动视为敏感的窗口标题关键字（小写）
+	manual   bool     // 热键切换的敏感状态
+	active   bool     // 当前这段敏感输入已生成占位事件
+	count    int      // 已生成的占位事件数，用于命名
+}
+
+// newRedactor 创建录制 taskName 时使用的 redactor，标题包含 windows 中任一关键字的窗口自动视为敏感
+func newRedactor(taskName string, windows []string) *redactor {
+	r := &redactor{taskName: taskName}
+	for _, keyword := range windows {
+		if keyword = strings.TrimSpace(keyword); keyword != "" {
+			r.windows = append(r.windows, strings.ToLower(keyword))
+		}
+	}
+	return r
+}
+
+// toggle 切换热键控制的敏感状态，返回切换后的状态
+func (r *redactor) toggle() bool {
+	r.manual = !r.manual
+	r.active = false
+	return r.manual
+}
+
+// breakRange 结束当前这段敏感输入（如点击了别的输入框），之后的敏感按键生成新的占位事件
+func (r *redactor) breakRange() {
+	r.active = false
+}
+
+// key 处理一个按键事件，title 为当前前台窗口的标题
+// 返回要录制的事件；ok 为 false 时丢弃该按键
+func (r *redactor) key(event model.Event, title string) (model.Event, bool) {
+	if sensitiveBreakKeys[event.KeyCode] || !(r.manual || r.sensitiveWindow(title)) {
+		r.active = false
+		return event, true
+	}
+	// 只有按下才开始新的一段，敏感输入结束后才松开的按键直接丢弃
+	if r.active || event.Type == model.EventKeyUp {
+		return model.Event{}, false
+	}
+
+	r.active = true
+	r.count++
+	return model.Event{
+		Type:   model.EventSecret,
+		Button: "none",
+		Delay:  event.Delay,
+		Secret: fmt.Sprintf("%s#%d", r.taskName, r.count),
+	}, true
+}
+
+// watchesWindows 是否配置了敏感窗口（没有配置时不必查询前台窗口标题）
+func (r *redactor) watchesWindows() bool {
+	return len(r.windows) > 0
+}
+
+// sensitiveWindow 判断窗口标题是否包含敏感关键字
+func (r *redactor) sensitiveWindow(title string) bool {
+	if len(r.windows) == 0 || title == "" {
+		return false
+	}
+	title = strings.ToLower(title)
+	for _, keyword := range r.windows {
+		if strings.Contains(title, keyword) {
+			return true
+		}
+	}
+	return false
+}
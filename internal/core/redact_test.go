@@ -0,0 +1,107 @@
+package core
+
+import (
+	"dailyflow/internal/model"
+	"reflect"
+	"testing"
+)
+
+func TestRedactor(t *testing.T) {
+	down := func(code, delay int) model.Event {
+		return model.Event{Type: model.EventKeyDown, Button: "none", KeyCode: code, Delay: delay}
+	}
+	up := func(code int) model.Event {
+		return model.Event{Type: model.EventKeyUp, Button: "none", KeyCode: code}
+	}
+	secret := func(name string, delay int) model.Event {
+		return model.Event{Type: model.EventSecret, Button: "none", Secret: name, Delay: delay}
+	}
+
+	type step struct {
+		event  model.Event
+		title  string
+		toggle bool // 处理 event 之前按下 F9
+		click  bool // 处理 event 之前点击了鼠标
+	}
+	tests := []struct {
+		name    string
+		windows []string
+		steps   []step
+		want    []model.Event
+	}{
+		{
+			name: "hotkey toggle",
+			steps: []step{
+				{event: down('U', 10)},
+				{event: up('U')},
+				{event: down('P', 200), toggle: true},
+				{event: up('P')},
+				{event: down('W', 50)},
+				{event: up('W')},
+				{event: down(0x0D, 30)}, // 回车结束这段敏感输入并照常录制
+				{event: up(0x0D)},
+				{event: down('X', 40)},
+				{event: down('Y', 40), toggle: true},
+			},
+			want: []model.Event{
+				down('U', 10), up('U'),
+				secret("登录#1", 200),
+				down(0x0D, 30), up(0x0D),
+				secret("登录#2", 40),
+				down('Y', 40),
+			},
+		},
+		{
+			name:    "sensitive window",
+			windows: []string{" 密码 ", "Sign In"},
+			steps: []step{
+				{event: down('A', 10), title: "记事本"},
+				{event: down('B', 20), title: "门户 - SIGN IN"},
+				{event: up('B'), title: "门户 - SIGN IN"},
+				{event: down('C', 20), title: "门户 - SIGN IN"},
+				// 点击另一个输入框，之后的输入另起一段
+				{event: down('D', 30), title: "门户 - SIGN IN", click: true},
+				{event: up('D'), title: "记事本"},
+				{event: down('E', 40), title: "修改密码"},
+			},
+			want: []model.Event{
+				down('A', 10),
+				secret("登录#1", 20),
+				secret("登录#2", 30),
+				up('D'),
+				secret("登录#3", 40),
+			},
+		},
+		{
+			name:    "key released after the range",
+			windows: []string{"sign in"},
+			steps: []step{
+				{event: down('P', 10), title: "Sign in"},
+				{event: down(0x09, 10), title: "Sign in"},
+				{event: up('P'), title: "Sign in"},
+				{event: up(0x09), title: "Sign in"},
+			},
+			want: []model.Event{secret("登录#1", 10), down(0x09, 10), up(0x09)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newRedactor("登录", tt.windows)
+			var got []model.Event
+			for _, s := range tt.steps {
+				if s.toggle {
+					r.toggle()
+				}
+				if s.click {
+					r.breakRange()
+				}
+				if event, ok := r.key(s.event, s.title); ok {
+					got = append(got, event)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("recorded events =\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -3,43 +3,42 @@ package core
 import (
 	"dailyflow/internal/model"
 	"dailyflow/internal/storage"
+	"errors"
 	"fmt"
-	"os"
-	"path/filepath"
 	"sync"
 	"time"
-	"unsafe"
-
-	"golang.org/x/sys/windows"
-)
-
-var (
-	ole32            = windows.NewLazySystemDLL("ole32.dll")
-	procCoInitialize = ole32.NewProc("CoInitialize")
 )
 
 // Scheduler 调度器
 type Scheduler struct {
+	store        storage.Store // 读取任务列表、读写配置
 	config       *model.Config
 	player       *Player
 	isRunning    bool
 	mutex        sync.Mutex
 	stopChan     chan bool
 	ticker       *time.Ticker
-	onTaskRun    func() // UI 回调函数
-	onTaskFailed func(error)
+	onTaskRun    func(taskName string) // UI 回调函数
+	onTaskFailed func(taskName string, err error)
+	refused      map[string]string                         // 因内容错误被拒绝执行的任务及日期，当天不再重试
+	now          func() time.Time                          // 当前时间，测试中可替换
+	loadData     func(path string) (*model.DataSet, error) // 读取任务的数据文件，测试中可替换
 }
 
-// NewScheduler 创建新的调度器
-func NewScheduler(player *Player) *Scheduler {
+// NewScheduler 创建新的调度器，通过 player 回放到期任务
+func NewScheduler(player *Player, store storage.Store) *Scheduler {
 	return &Scheduler{
+		store:    store,
 		player:   player,
+		now:      time.Now,
+		loadData: storage.LoadDataFile,
 		stopChan: make(chan bool),
+		refused:  make(map[string]string),
 	}
 }
 
 // SetCallbacks 设置回调函数
-func (s *Scheduler) SetCallbacks(onTaskRun func(), onTaskFailed func(error)) {
+func (s *Scheduler) SetCallbacks(onTaskRun func(taskName string), onTaskFailed func(taskName string, err error)) {
 	s.onTaskRun = onTaskRun
 	s.onTaskFailed = onTaskFailed
 }
@@ -54,7 +53,7 @@ func (s *Scheduler) Start() error {
 	}
 
 	// 加载配置
-	config, err := storage.LoadConfig()
+	config, err := s.store.LoadConfig()
 	if err != nil {
 		return fmt.Errorf("failed to load config: %w", err)
 	}
@@ -100,7 +99,7 @@ func (s *Scheduler) UpdateConfig(config *model.Config) error {
 	defer s.mutex.Unlock()
 
 	s.config = config
-	return storage.SaveConfig(config)
+	return s.store.SaveConfig(config)
 }
 
 // heartbeatLoop 心跳循环
@@ -118,175 +117,108 @@ func (s *Scheduler) heartbeatLoop() {
 	}
 }
 
-// checkAndExecute 检查并执行任务
+// checkAndExecute 逐个检查任务库中的任务，依次执行到期的任务
 func (s *Scheduler) checkAndExecute() {
 	s.mutex.Lock()
 	config := s.config
 	s.mutex.Unlock()
 
-	if config == nil || !config.IsEnabled {
+	if config == nil {
 		return
 	}
 
-	now := time.Now()
-	today := now.Format("2006-01-02")
-
-	// 检查今天是否已经运行过
-	if config.HasTaskToday(today) {
+	taskNames, err := s.store.ListTasks()
+	if err != nil {
+		s.notifyFailed("", fmt.Errorf("failed to list tasks: %w", err))
 		return
 	}
 
-	// 解析设定的时间
-	scheduleTime, err := time.Parse("15:04", config.ScheduleTime)
-	if err != nil {
-		if s.onTaskFailed != nil {
-			s.onTaskFailed(fmt.Errorf("invalid schedule time: %w", err))
+	for _, taskName := range taskNames {
+		s.mutex.Lock()
+		schedule, ok := config.Tasks[taskName]
+		s.mutex.Unlock()
+		if !ok {
+			continue
 		}
-		return
-	}
 
-	// 构造今天的执行时间
-	targetTime := time.Date(
-		now.Year(), now.Month(), now.Day(),
-		scheduleTime.Hour(), scheduleTime.Minute(), 0, 0,
-		now.Location(),
-	)
+		now := s.now()
+		due, err := schedule.IsDue(now)
+		if err != nil {
+			s.notifyFailed(taskName, err)
+			continue
+		}
+		if !due {
+			continue
+		}
 
-	// 检查是否到达执行时间
-	if now.Before(targetTime) {
-		return
-	}
+		today := now.Format("2006-01-02")
+		if s.refused[taskName] == today {
+			continue
+		}
 
-	// 执行任务
-	if err := s.executeTask(); err != nil {
-		if s.onTaskFailed != nil {
-			s.onTaskFailed(err)
+		// 执行任务，等待结束后再处理下一个，保证同一时间只有一个任务在回放
+		// 数据驱动回放中部分行失败时任务已执行过，照常记录运行日期，否则下一分钟会整批重跑
+		runErr := s.executeTask(taskName, schedule)
+		var batchErr *BatchError
+		if runErr != nil && !errors.As(runErr, &batchErr) {
+			if errors.Is(runErr, model.ErrInvalidTask) {
+				// 任务内容有错误，重试也不会成功，当天只提示一次
+				s.refused[taskName] = today
+			}
+			s.notifyFailed(taskName, runErr)
+			continue
 		}
-		return
-	}
 
-	// 更新最后运行日期
-	config.LastRunDate = today
-	if err := storage.SaveConfig(config); err != nil {
-		if s.onTaskFailed != nil {
-			s.onTaskFailed(fmt.Errorf("failed to save config: %w", err))
+		// 更新最后运行日期
+		s.mutex.Lock()
+		schedule.LastRunDate = today
+		err = s.store.SaveConfig(config)
+		s.mutex.Unlock()
+		if err != nil {
+			s.notifyFailed(taskName, fmt.Errorf("failed to save config: %w", err))
 		}
-	}
 
-	// 回调通知 UI
-	if s.onTaskRun != nil {
-		s.onTaskRun()
+		// 回调通知 UI
+		if batchErr != nil {
+			s.notifyFailed(taskName, runErr)
+		} else if s.onTaskRun != nil {
+			s.onTaskRun(taskName)
+		}
 	}
 }
 
-// executeTask 执行任务
-func (s *Scheduler) executeTask() error {
-	// 使用配置的速度因子
-	speedFactor := s.config.SpeedFactor
+// executeTask 执行任务并等待回放结束
+func (s *Scheduler) executeTask(taskName string, schedule *model.TaskSchedule) error {
+	// 使用任务自己的速度因子
+	speedFactor := schedule.SpeedFactor
 	if speedFactor <= 0 {
 		speedFactor = 1.0
 	}
 
-	return s.player.StartPlayback(speedFactor)
-}
-
-// EnableAutoStart 启用开机自启动
-func EnableAutoStart() error {
-	return createStartupShortcut(true)
-}
-
-// DisableAutoStart 禁用开机自启动
-func DisableAutoStart() error {
-	return createStartupShortcut(false)
-}
-
-// IsAutoStartEnabled 检查是否启用了开机自启动
-func IsAutoStartEnabled() bool {
-	shortcutPath := getStartupShortcutPath()
-	_, err := os.Stat(shortcutPath)
-	return err == nil
-}
-
-// getStartupShortcutPath 获取启动文件夹中的快捷方式路径
-func getStartupShortcutPath() string {
-	appData := os.Getenv("APPDATA")
-	if appData == "" {
-		appData = filepath.Join(os.Getenv("USERPROFILE"), "AppData", "Roaming")
-	}
-	startupDir := filepath.Join(appData, "Microsoft", "Windows", "Start Menu", "Programs", "Startup")
-	return filepath.Join(startupDir, "DailyFlow.lnk")
-}
-
-// createStartupShortcut 创建或删除启动快捷方式
-func createStartupShortcut(enable bool) error {
-	shortcutPath := getStartupShortcutPath()
-
-	if !enable {
-		// 删除快捷方式
-		if err := os.Remove(shortcutPath); err != nil && !os.IsNotExist(err) {
-			return fmt.Errorf("failed to remove startup shortcut: %w", err)
+	// 设置了数据文件时逐行回放，失败的行跳过，结束后汇总
+	if schedule.DataFile != "" {
+		data, err := s.loadData(schedule.DataFile)
+		if err != nil {
+			return err
 		}
-		return nil
-	}
-
-	// 获取当前可执行文件路径
-	exePath, err := os.Executable()
-	if err != nil {
-		return fmt.Errorf("failed to get executable path: %w", err)
+		if err := s.player.StartBatch(taskName, speedFactor, BatchOptions{Data: data}); err != nil {
+			return err
+		}
+		s.player.Wait()
+		return BatchFailure(s.player.Results())
 	}
 
-	// 确保启动目录存在
-	startupDir := filepath.Dir(shortcutPath)
-	if err := os.MkdirAll(startupDir, 0755); err != nil {
-		return fmt.Errorf("failed to create startup directory: %w", err)
+	if err := s.player.StartPlayback(taskName, speedFactor); err != nil {
+		return err
 	}
+	s.player.Wait()
 
-	// 创建快捷方式
-	// 注意：这里使用 Windows Shell API 创建 .lnk 文件
-	// 简化实现：直接调用 PowerShell 创建快捷方式
-	return createShortcutViaPowerShell(shortcutPath, exePath)
+	return nil
 }
 
-// createShortcutViaPowerShell 通过 PowerShell 创建快捷方式
-func createShortcutViaPowerShell(shortcutPath, targetPath string) error {
-	// 使用 PowerShell 创建快捷方式（简化版本）
-	// 构建 PowerShell 命令
-	psCmd := fmt.Sprintf(
-		`$WshShell = New-Object -ComObject WScript.Shell; $Shortcut = $WshShell.CreateShortcut('%s'); $Shortcut.TargetPath = '%s'; $Shortcut.Save()`,
-		shortcutPath,
-		targetPath,
-	)
-
-	// 执行 PowerShell 命令
-	cmd := windows.StringToUTF16Ptr("powershell.exe")
-	args := windows.StringToUTF16Ptr("-NoProfile -NonInteractive -Command " + psCmd)
-	
-	var si windows.StartupInfo
-	var pi windows.ProcessInformation
-	si.Cb = uint32(unsafe.Sizeof(si))
-
-	err := windows.CreateProcess(
-		cmd,
-		args,
-		nil,
-		nil,
-		false,
-		windows.CREATE_NO_WINDOW,
-		nil,
-		nil,
-		&si,
-		&pi,
-	)
-
-	if err != nil {
-		return fmt.Errorf("failed to create shortcut: %w", err)
+// notifyFailed 通知 UI 任务执行失败
+func (s *Scheduler) notifyFailed(taskName string, err error) {
+	if s.onTaskFailed != nil {
+		s.onTaskFailed(taskName, err)
 	}
-
-	// 等待进程完成
-	windows.WaitForSingleObject(pi.Process, windows.INFINITE)
-	windows.CloseHandle(pi.Process)
-	windows.CloseHandle(pi.Thread)
-
-	return nil
 }
-
@@ -0,0 +1,135 @@
+package core
+
+import (
+	"dailyflow/internal/model"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSchedulerRunsDueTasks(t *testing.T) {
+	click := testTask(model.Event{Type: model.EventMouseClick, X: 10, Y: 10, Button: "left"})
+	player, desktop, store := newTestPlayer(t, map[string]*model.TaskData{
+		"晨报": click,
+		"午报": click,
+		"巡检": click,
+		"导出": click,
+	})
+
+	config := model.NewConfig()
+	config.Tasks["晨报"] = &model.TaskSchedule{ScheduleTime: "08:30", IsEnabled: true, SpeedFactor: 1.0}
+	config.Tasks["午报"] = &model.TaskSchedule{ScheduleTime: "12:00", IsEnabled: true, SpeedFactor: 1.0}
+	config.Tasks["巡检"] = &model.TaskSchedule{ScheduleTime: "08:00", IsEnabled: false, SpeedFactor: 1.0}
+	config.Tasks["导出"] = &model.TaskSchedule{ScheduleTime: "07:00", IsEnabled: true, SpeedFactor: 1.0, LastRunDate: "2024-12-02"}
+
+	scheduler := NewScheduler(player, store)
+	scheduler.now = func() time.Time { return time.Date(2024, 12, 2, 9, 0, 0, 0, time.Local) }
+	var ran []string
+	scheduler.SetCallbacks(func(taskName string) { ran = append(ran, taskName) }, func(taskName string, err error) {
+		t.Errorf("task %s failed: %v", taskName, err)
+	})
+	if err := scheduler.UpdateConfig(config); err != nil {
+		t.Fatal(err)
+	}
+
+	scheduler.checkAndExecute()
+
+	if want := []string{"晨报"}; !reflect.DeepEqual(ran, want) {
+		t.Errorf("ran tasks %v, want %v", ran, want)
+	}
+	if calls := desktop.Calls(); len(calls) != 3 {
+		t.Errorf("playback calls = %q, want one click", calls)
+	}
+	saved, err := store.LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := saved.Tasks["晨报"].LastRunDate; got != "2024-12-02" {
+		t.Errorf("saved LastRunDate = %q, want 2024-12-02", got)
+	}
+
+	// 同一天再次检查不会重复执行
+	scheduler.checkAndExecute()
+	if len(ran) != 1 {
+		t.Errorf("ran tasks %v after second check, want one run", ran)
+	}
+}
+
+func TestSchedulerRefusesInvalidTask(t *testing.T) {
+	invalid := testTask(model.Event{Type: model.EventKeyDown, Button: "none", KeyCode: 0x1FF})
+	player, _, store := newTestPlayer(t, map[string]*model.TaskData{"日报": invalid})
+
+	config := model.NewConfig()
+	config.Tasks["日报"] = &model.TaskSchedule{ScheduleTime: "08:30", IsEnabled: true, SpeedFactor: 1.0}
+
+	now := time.Date(2024, 12, 2, 9, 0, 0, 0, time.Local)
+	scheduler := NewScheduler(player, store)
+	scheduler.now = func() time.Time { return now }
+	var failures []error
+	scheduler.SetCallbacks(func(taskName string) {
+		t.Errorf("invalid task %s ran", taskName)
+	}, func(taskName string, err error) {
+		failures = append(failures, err)
+	})
+	if err := scheduler.UpdateConfig(config); err != nil {
+		t.Fatal(err)
+	}
+
+	// 当天只提示一次，第二天再次尝试
+	scheduler.checkAndExecute()
+	scheduler.checkAndExecute()
+	now = now.Add(24 * time.Hour)
+	scheduler.checkAndExecute()
+
+	if len(failures) != 2 {
+		t.Fatalf("failures = %v, want 2", failures)
+	}
+	for _, err := range failures {
+		if !errors.Is(err, model.ErrInvalidTask) {
+			t.Errorf("failure %v is not ErrInvalidTask", err)
+		}
+	}
+}
+
+func TestSchedulerRunsDataFile(t *testing.T) {
+	task := testTask(model.Event{Type: model.EventTypeText, Button: "none", Text: "{{单号}}"})
+	player, desktop, store := newTestPlayer(t, map[string]*model.TaskData{"录单": task})
+	desktop.failText = "A2"
+
+	config := model.NewConfig()
+	config.Tasks["录单"] = &model.TaskSchedule{ScheduleTime: "08:30", IsEnabled: true, SpeedFactor: 1.0, DataFile: "单据.csv"}
+
+	scheduler := NewScheduler(player, store)
+	scheduler.now = func() time.Time { return time.Date(2024, 12, 2, 9, 0, 0, 0, time.Local) }
+	scheduler.loadData = func(path string) (*model.DataSet, error) {
+		if path != "单据.csv" {
+			t.Errorf("loadData(%q), want 单据.csv", path)
+		}
+		return model.NewDataSet([][]string{{"单号"}, {"A1"}, {"A2"}, {"A3"}})
+	}
+	var failures []error
+	scheduler.SetCallbacks(func(taskName string) {
+		t.Errorf("task %s reported success with a failed row", taskName)
+	}, func(taskName string, err error) {
+		failures = append(failures, err)
+	})
+	if err := scheduler.UpdateConfig(config); err != nil {
+		t.Fatal(err)
+	}
+
+	// 失败的行不影响其余行，任务当天不再重复执行
+	scheduler.checkAndExecute()
+	scheduler.checkAndExecute()
+
+	if want := []string{`type "A1"`, `type "A3"`}; !reflect.DeepEqual(desktop.Calls(), want) {
+		t.Errorf("playback calls = %q, want %q", desktop.Calls(), want)
+	}
+	var batchErr *BatchError
+	if len(failures) != 1 || !errors.As(failures[0], &batchErr) || batchErr.Total != 3 {
+		t.Fatalf("failures = %v, want one BatchError for 3 rows", failures)
+	}
+	if got := config.Tasks["录单"].LastRunDate; got != "2024-12-02" {
+		t.Errorf("LastRunDate = %q, want 2024-12-02", got)
+	}
+}
@@ -2,94 +2,141 @@ package core
 
 import (
 	"dailyflow/internal/model"
+	"dailyflow/internal/screen"
 	"dailyflow/internal/storage"
+	"errors"
 	"fmt"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
-	"unsafe"
 )
 
-const (
-	INPUT_MOUSE    = 0
-	INPUT_KEYBOARD = 1
+// ErrPlaybackStopped 回放被用户停止；数据驱动回放中，停止时正在执行的那一行以此作为结果
+var ErrPlaybackStopped = errors.New("playback stopped")
 
-	MOUSEEVENTF_MOVE       = 0x0001
-	MOUSEEVENTF_LEFTDOWN   = 0x0002
-	MOUSEEVENTF_LEFTUP     = 0x0004
-	MOUSEEVENTF_RIGHTDOWN  = 0x0008
-	MOUSEEVENTF_RIGHTUP    = 0x0010
-	MOUSEEVENTF_MIDDLEDOWN = 0x0020
-	MOUSEEVENTF_MIDDLEUP   = 0x0040
-	MOUSEEVENTF_ABSOLUTE   = 0x8000
+// windowPollInterval wait_window 查找窗口的间隔
+const windowPollInterval = 200 * time.Millisecond
 
-	KEYEVENTF_KEYUP = 0x0002
-)
-
-var (
-	procSendInput        = user32.NewProc("SendInput")
-	procSetCursorPos     = user32.NewProc("SetCursorPos")
-	procGetCursorPos     = user32.NewProc("GetCursorPos")
-	procGetAsyncKeyState = user32.NewProc("GetAsyncKeyState")
-)
+// RowResult 数据驱动回放中一行数据的执行结果
+type RowResult struct {
+	Row    int               // 数据行的序号，从 1 开始，不含表头和空行
+	Line   int               // 这一行在数据文件中的行号，报告失败时使用
+	Values map[string]string // 这一行的变量
+	Err    error             // 失败的原因，成功时为 nil
+}
 
-// INPUT Windows 输入结构
-type INPUT struct {
-	Type uint32
-	Mi   MOUSEINPUT
-	Ki   KEYBDINPUT
-	Hi   HARDWAREINPUT
+// BatchOptions 数据驱动回放的设置
+type BatchOptions struct {
+	Data        *model.DataSet         // 数据文件的内容，任务按数据行逐行回放
+	StopOnError bool                   // 某一行失败时不再执行后面的行，默认跳过失败的行继续
+	OnRow       func(result RowResult) // 每一行结束时在回放 goroutine 中调用，可为 nil
 }
 
-// MOUSEINPUT 鼠标输入结构
-type MOUSEINPUT struct {
-	Dx          int32
-	Dy          int32
-	MouseData   uint32
-	DwFlags     uint32
-	Time        uint32
-	DwExtraInfo uintptr
+// BatchError 数据驱动回放中有数据行失败
+type BatchError struct {
+	Total  int         // 执行过的行数
+	Failed []RowResult // 失败的行
 }
 
-// KEYBDINPUT 键盘输入结构
-type KEYBDINPUT struct {
-	WVk         uint16
-	WScan       uint16
-	DwFlags     uint32
-	Time        uint32
-	DwExtraInfo uintptr
-	Padding     [8]byte
+// Error 实现 error
+func (e *BatchError) Error() string {
+	parts := make([]string, len(e.Failed))
+	for i, result := range e.Failed {
+		parts[i] = fmt.Sprintf("line %d: %v", result.Line, result.Err)
+	}
+	return fmt.Sprintf("%d of %d rows failed: %s", len(e.Failed), e.Total, strings.Join(parts, "; "))
 }
 
-// HARDWAREINPUT 硬件输入结构
-type HARDWAREINPUT struct {
-	UMsg    uint32
-	WParamL uint16
-	WParamH uint16
+// BatchFailure 汇总逐行的执行结果，全部成功时返回 nil，否则返回 *BatchError
+func BatchFailure(results []RowResult) error {
+	batchErr := &BatchError{Total: len(results)}
+	for _, result := range results {
+		if result.Err != nil {
+			batchErr.Failed = append(batchErr.Failed, result)
+		}
+	}
+	if len(batchErr.Failed) == 0 {
+		return nil
+	}
+	return batchErr
 }
 
 // Player 回放引擎
 type Player struct {
-	taskData      *model.TaskData
-	isPlaying     bool
-	isPaused      bool
-	speedFactor   float64
-	mutex         sync.Mutex
-	stopChan      chan bool
-	pauseChan     chan bool
-	initialCursor POINT
-}
-
-// NewPlayer 创建新的回放器
-func NewPlayer() *Player {
+	store            storage.Store       // 加载任务数据
+	secrets          storage.SecretStore // secret 事件的内容
+	desktop          Desktop             // 注入输入、查询显示器和窗口
+	taskData         *model.TaskData
+	secretValues     map[string]string // 本次回放用到的敏感输入，开始回放前全部取出
+	isPlaying        bool
+	isPaused         bool
+	speedFactor      float64
+	resolutionPolicy string                 // 分辨率不一致时的处理策略
+	variables        map[string]string      // type_text 模板中的自定义变量
+	now              func() time.Time       // 模板中日期时间变量的基准，测试中可替换
+	startedAt        time.Time              // 本次回放开始的时刻，整个任务的日期时间变量都以它为准
+	executed         int                    // 本次回放已执行的动作数
+	results          []RowResult            // 数据驱动回放已结束的各行结果
+	rowScope         map[string]string      // 当前这一行的变量（不含 repeat 计数变量），被调用的任务使用
+	outputs          map[string]string      // 当前这一行 wait_window 保存的结果，之后的条件和文本都可以使用
+	called           map[string]*calledTask // call 步骤调用的任务，开始回放前全部加载
+	scaler           screen.Scaler          // 录制坐标到当前屏幕坐标的换算
+	mutex            sync.Mutex
+	stopChan         chan bool
+	pauseChan        chan bool
+	done             chan struct{}   // 本次回放结束时关闭
+	cursorX, cursorY int             // 上一个事件执行后的光标位置，用于检测用户移动鼠标
+	heldKeys         map[int]bool    // 已按下尚未释放的键，回放结束时统一释放
+	heldButtons      map[string]bool // 已按下尚未释放的鼠标按键（拖拽中）
+}
+
+// calledTask call 步骤调用的任务及回放它所用的设置
+type calledTask struct {
+	task   *model.TaskData
+	scaler screen.Scaler // 按被调用任务自己录制时的屏幕换算坐标
+	speed  float64       // 被调用任务自己的速度因子
+}
+
+// NewPlayer 创建新的回放器，从 store 加载任务，从 secrets 取出敏感输入，通过 desktop 注入输入
+func NewPlayer(store storage.Store, secrets storage.SecretStore, desktop Desktop) *Player {
 	return &Player{
-		speedFactor: 1.0,
-		stopChan:    make(chan bool, 1),
-		pauseChan:   make(chan bool, 1),
+		store:            store,
+		secrets:          secrets,
+		desktop:          desktop,
+		speedFactor:      1.0,
+		resolutionPolicy: model.ResolutionScale,
+		now:              time.Now,
+		stopChan:         make(chan bool, 1),
+		pauseChan:        make(chan bool, 1),
 	}
 }
 
-// StartPlayback 开始回放
-func (p *Player) StartPlayback(speedFactor float64) error {
+// StartPlayback 开始回放任务库中的 taskName
+func (p *Player) StartPlayback(taskName string, speedFactor float64) error {
+	return p.start(taskName, speedFactor, nil)
+}
+
+// StartBatch 开始数据驱动回放：按 opts.Data 的数据行逐行回放 taskName，
+// 每一行的值替换输入文本中对应的 {{列名}}；某一行的步骤出错时结束这一行，
+// 默认继续执行下一行。回放结束后由 Results 取得各行结果
+func (p *Player) StartBatch(taskName string, speedFactor float64, opts BatchOptions) error {
+	if opts.Data == nil || len(opts.Data.Rows) == 0 {
+		return fmt.Errorf("no data rows to play")
+	}
+	return p.start(taskName, speedFactor, &opts)
+}
+
+// Results 返回最近一次数据驱动回放已结束的各行结果，回放中调用时只包含已结束的行
+func (p *Player) Results() []RowResult {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return append([]RowResult(nil), p.results...)
+}
+
+// start 加载并检查任务后在独立 goroutine 中开始回放，batch 为 nil 时只回放一次
+func (p *Player) start(taskName string, speedFactor float64, batch *BatchOptions) error {
 	p.mutex.Lock()
 	defer p.mutex.Unlock()
 
@@ -98,7 +145,7 @@ func (p *Player) StartPlayback(speedFactor float64) error {
 	}
 
 	// 加载任务数据
-	taskData, err := storage.LoadTask()
+	taskData, err := p.store.LoadTask(taskName)
 	if err != nil {
 		return fmt.Errorf("failed to load task: %w", err)
 	}
@@ -107,20 +154,184 @@ func (p *Player) StartPlayback(speedFactor float64) error {
 		return fmt.Errorf("no task data to play")
 	}
 
+	// 检查任务数据，有错误（含未定义的模板变量）的任务拒绝回放，警告只打印
+	// 数据驱动回放时数据文件的列也是已定义的变量
+	variables := p.variables
+	if batch != nil {
+		variables = model.MergeValues(p.variables, batch.Data.Placeholders())
+	}
+	findings := append(taskData.Validate(), taskData.ValidateVariables(variables)...)
+
+	// 被调用的任务在开始前全部加载和检查，缺失的任务和循环调用同样拒绝回放
+	tasks, callFindings, err := model.ResolveCalls(taskName, taskData, storage.TaskLoader(p.store))
+	if err != nil {
+		return err
+	}
+	findings = append(findings, callFindings...)
+	names := make([]string, 0, len(tasks))
+	for name := range tasks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, finding := range append(tasks[name].Validate(), tasks[name].ValidateVariables(variables)...) {
+			finding.Path = name + ":" + finding.Path
+			findings = append(findings, finding)
+		}
+	}
+
+	if model.HasErrors(findings) {
+		return &model.ValidationError{Findings: findings}
+	}
+	for _, finding := range findings {
+		fmt.Printf("Task %s: %s\n", taskName, finding)
+	}
+
+	// 根据录制时的显示器布局（旧任务只有分辨率）决定坐标换算或拒绝回放
+	scaler, err := p.fitScreen(&taskData.Meta)
+	if err != nil {
+		return err
+	}
+	called, err := p.prepareCalls(tasks)
+	if err != nil {
+		return err
+	}
+
+	// 敏感输入在开始前全部取出，缺少任何一个都不回放，避免输入到一半才失败
+	secretNames := taskData.Secrets()
+	for _, name := range names {
+		secretNames = append(secretNames, tasks[name].Secrets()...)
+	}
+	secretValues, err := p.resolveSecrets(secretNames)
+	if err != nil {
+		return err
+	}
+
+	p.scaler = scaler
+	p.called = called
+	p.secretValues = secretValues
 	p.taskData = taskData
 	p.speedFactor = speedFactor
+	p.startedAt = p.now()
+	p.executed = 0
+	p.results = nil
 	p.isPlaying = true
 	p.isPaused = false
+	p.heldKeys = make(map[int]bool)
+	p.heldButtons = make(map[string]bool)
+	p.done = make(chan struct{})
+
+	// 丢弃上次回放结束时才送达的停止信号，否则本次回放会立即停止
+	select {
+	case <-p.stopChan:
+	default:
+	}
 
 	// 记录初始鼠标位置
-	procGetCursorPos.Call(uintptr(unsafe.Pointer(&p.initialCursor)))
+	p.cursorX, p.cursorY = p.desktop.CursorPos()
 
 	// 在独立 goroutine 中执行回放
-	go p.playbackLoop()
+	if batch != nil {
+		go p.batchLoop(p.variables, *batch)
+	} else {
+		go p.playbackLoop(p.variables)
+	}
 
 	return nil
 }
 
+// prepareCalls 为被调用的任务生成坐标换算，并读取它们各自的速度设置
+func (p *Player) prepareCalls(tasks map[string]*model.TaskData) (map[string]*calledTask, error) {
+	if len(tasks) == 0 {
+		return nil, nil
+	}
+	config, err := p.store.LoadConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+
+	called := make(map[string]*calledTask, len(tasks))
+	for name, taskData := range tasks {
+		scaler, err := p.fitScreen(&taskData.Meta)
+		if err != nil {
+			return nil, fmt.Errorf("called task %s: %w", name, err)
+		}
+		speed := 1.0
+		if schedule, ok := config.Tasks[name]; ok && schedule.SpeedFactor > 0 {
+			speed = schedule.SpeedFactor
+		}
+		called[name] = &calledTask{task: taskData, scaler: scaler, speed: speed}
+	}
+	return called, nil
+}
+
+// resolveSecrets 从凭据存储取出 names 中的全部敏感输入
+func (p *Player) resolveSecrets(names []string) (map[string]string, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+	if p.secrets == nil {
+		return nil, fmt.Errorf("%w: no secret store for %s", storage.ErrSecretNotFound, strings.Join(names, ", "))
+	}
+
+	values := make(map[string]string, len(names))
+	for _, name := range names {
+		value, err := p.secrets.Secret(name)
+		if err != nil {
+			return nil, err
+		}
+		values[name] = value
+	}
+	return values, nil
+}
+
+// SetVariables 设置 type_text 模板中可用的自定义变量（config.json 的 variables）
+func (p *Player) SetVariables(values map[string]string) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.variables = values
+}
+
+// SetResolutionPolicy 设置回放时分辨率不一致的处理策略（model.ResolutionScale / model.ResolutionStrict）
+func (p *Player) SetResolutionPolicy(policy string) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.resolutionPolicy = policy
+}
+
+// fitScreen 比较录制时与当前的屏幕，生成坐标换算
+func (p *Player) fitScreen(meta *model.TaskMeta) (screen.Scaler, error) {
+	if len(meta.Monitors) == 0 {
+		return p.fitResolution(meta.Resolution)
+	}
+
+	current, err := p.desktop.Monitors()
+	if err != nil {
+		return screen.Scaler{}, err
+	}
+	strict := p.resolutionPolicy == model.ResolutionStrict
+	scaler, err := screen.FitLayout(meta.Monitors, current, strict)
+	if err == nil && !scaler.Identity() {
+		fmt.Printf("Monitor layout changed since recording, coordinates will be rescaled\n")
+	}
+	return scaler, err
+}
+
+// fitResolution 比较录制分辨率与当前主屏分辨率，生成坐标换算
+func (p *Player) fitResolution(recordedResolution string) (screen.Scaler, error) {
+	// 手工编辑或早期的任务可能没有记录分辨率，按原坐标回放
+	if recordedResolution == "" {
+		return screen.Scaler{}, nil
+	}
+	recorded, err := screen.ParseResolution(recordedResolution)
+	if err != nil {
+		return screen.Scaler{}, err
+	}
+
+	current := p.desktop.PrimaryScreen()
+	return screen.Fit(recorded, current, p.resolutionPolicy == model.ResolutionStrict)
+}
+
 // StopPlayback 停止回放
 func (p *Player) StopPlayback() error {
 	p.mutex.Lock()
@@ -178,89 +389,332 @@ func (p *Player) IsPlaying() bool {
 	return p.isPlaying
 }
 
-// playbackLoop 回放循环
-func (p *Player) playbackLoop() {
-	defer func() {
+// IsPaused 检查回放是否处于暂停状态（手动暂停或检测到用户移动鼠标）
+func (p *Player) IsPaused() bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.isPaused
+}
+
+// Wait 阻塞直到当前回放结束，没有回放时立即返回
+func (p *Player) Wait() {
+	p.mutex.Lock()
+	done := p.done
+	p.mutex.Unlock()
+
+	if done != nil {
+		<-done
+	}
+}
+
+// playbackLoop 回放循环，variables 为开始回放时的自定义变量
+func (p *Player) playbackLoop(variables map[string]string) {
+	defer p.finish()
+
+	p.rowScope = variables
+	p.outputs = make(map[string]string)
+	p.runSteps("events", p.taskData.Events, variables, false)
+}
+
+// batchLoop 数据驱动回放循环，逐行回放任务并记录每一行的结果
+func (p *Player) batchLoop(variables map[string]string, opts BatchOptions) {
+	defer p.finish()
+
+	for i := range opts.Data.Rows {
+		values := opts.Data.Values(i)
+		p.rowScope = model.MergeValues(variables, values)
+		p.outputs = make(map[string]string)
+		err := p.runSteps("events", p.taskData.Events, p.rowScope, true)
+		// 出错的行可能停在拖拽或组合键的中途，下一行开始前先释放
+		p.releaseHeldKeys()
+		p.releaseHeldButtons()
+
+		result := RowResult{Row: i + 1, Line: opts.Data.Lines[i], Values: values, Err: err}
+		if err != nil && !errors.Is(err, ErrPlaybackStopped) {
+			fmt.Printf("Line %d failed: %v\n", result.Line, err)
+		}
 		p.mutex.Lock()
-		p.isPlaying = false
+		p.results = append(p.results, result)
 		p.mutex.Unlock()
-	}()
+		if opts.OnRow != nil {
+			opts.OnRow(result)
+		}
+
+		if errors.Is(err, ErrPlaybackStopped) || (err != nil && opts.StopOnError) {
+			return
+		}
+	}
+}
+
+// finish 回放结束时释放仍按下的按键并更新状态
+func (p *Player) finish() {
+	// 中途停止时可能还有修饰键或鼠标按键处于按下状态，必须释放，否则会"粘住"
+	p.releaseHeldKeys()
+	p.releaseHeldButtons()
+
+	p.mutex.Lock()
+	p.isPlaying = false
+	p.secretValues = nil
+	close(p.done)
+	p.mutex.Unlock()
+}
 
-	for i, event := range p.taskData.Events {
-		// 检查是否需要停止
+// runSteps 依次执行同一层级的步骤，path 为这一层在任务中的位置（如 "events[3].steps"），
+// scope 为这一层可用的模板变量；被停止时返回 ErrPlaybackStopped，
+// abortOnError 时某一步出错即返回带步骤位置的错误，否则打印错误后继续
+func (p *Player) runSteps(path string, steps []model.Event, scope map[string]string, abortOnError bool) error {
+	for i := range steps {
+		if err := p.runStep(fmt.Sprintf("%s[%d]", path, i), &steps[i], scope, abortOnError); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runStep 等待步骤的延迟后执行它，块中的步骤递归执行
+func (p *Player) runStep(path string, step *model.Event, scope map[string]string, abortOnError bool) error {
+	if err := p.checkpoint(); err != nil {
+		return err
+	}
+
+	// 计算延迟（考虑速度因子），等待期间也能停止
+	if step.Delay > 0 {
+		actualDelay := time.Duration(float64(step.Delay)/p.speedFactor) * time.Millisecond
 		select {
 		case <-p.stopChan:
-			return
+			return ErrPlaybackStopped
+		case <-time.After(actualDelay):
+		}
+	}
+
+	if step.Type == model.EventRepeat {
+		for n := 1; n <= step.Count; n++ {
+			inner := scope
+			if step.Var != "" {
+				inner = model.MergeValues(scope, map[string]string{step.Var: strconv.Itoa(n)})
+			}
+			if err := p.runSteps(path+".steps", step.Steps, inner, abortOnError); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if step.Type == model.EventCall {
+		return p.runCall(path, step, abortOnError)
+	}
+	if step.Type == model.EventIf {
+		return p.runIf(path, step, scope, abortOnError)
+	}
+
+	// 执行事件
+	if err := p.executeEvent(step, scope); err != nil {
+		if errors.Is(err, ErrPlaybackStopped) {
+			return err
+		}
+		if abortOnError {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		// 记录错误，但继续执行
+		fmt.Printf("Error executing %s: %v\n", path, err)
+	}
+	p.executed++
+
+	// 以事件执行后的光标位置作为下一次检测的基准，
+	// 否则回放自身的移动（如拖拽）会被误判为用户操作
+	p.cursorX, p.cursorY = p.desktop.CursorPos()
+	return nil
+}
+
+// runCall 以被调用任务自己的坐标换算和速度执行它的全部步骤，结束后恢复调用方的设置
+// 被调用的任务只能使用这一行的变量，看不到调用方 repeat 的计数变量
+func (p *Player) runCall(path string, step *model.Event, abortOnError bool) error {
+	called := p.called[step.Task]
+	speed := called.speed
+	if step.Speed > 0 {
+		speed = step.Speed
+	}
+
+	scaler, speedFactor := p.scaler, p.speedFactor
+	p.scaler, p.speedFactor = called.scaler, speed
+	defer func() { p.scaler, p.speedFactor = scaler, speedFactor }()
+
+	return p.runSteps(fmt.Sprintf("%s(%s).events", path, step.Task), called.task.Events, p.rowScope, abortOnError)
+}
+
+// runIf 求 if 步骤的条件并执行对应的分支
+// 条件无法求值（如数字和文本比较大小）时，abortOnError 返回带步骤位置的错误，否则打印错误后跳过整个块
+func (p *Player) runIf(path string, step *model.Event, scope map[string]string, abortOnError bool) error {
+	matched, err := (model.Variables{Now: p.startedAt, Values: p.values(scope)}).Evaluate(step.Cond)
+	if err != nil {
+		if abortOnError {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		fmt.Printf("Error evaluating %s: %v\n", path, err)
+		return nil
+	}
+	if matched {
+		return p.runSteps(path+".steps", step.Steps, scope, abortOnError)
+	}
+	return p.runSteps(path+".else", step.Else, scope, abortOnError)
+}
+
+// values 返回 scope 与 wait_window 结果合并后的变量表，同名时 scope 优先
+func (p *Player) values(scope map[string]string) map[string]string {
+	if len(p.outputs) == 0 {
+		return scope
+	}
+	return model.MergeValues(p.outputs, scope)
+}
+
+// checkpoint 每一步之前检查停止和暂停：被停止时返回 ErrPlaybackStopped，暂停时等待恢复
+// 检测到用户移动鼠标时自动暂停，恢复后从当前这一步继续
+func (p *Player) checkpoint() error {
+	for {
+		select {
+		case <-p.stopChan:
+			return ErrPlaybackStopped
 		default:
 		}
 
-		// 检查是否暂停
-		for p.isPaused {
+		if p.IsPaused() {
 			select {
 			case <-p.pauseChan:
-				// 继续执行
 			case <-p.stopChan:
-				return
+				return ErrPlaybackStopped
 			case <-time.After(100 * time.Millisecond):
 				// 继续检查暂停状态
 			}
+			if !p.IsPaused() {
+				// 暂停期间用户可能移动过鼠标，以恢复时的位置为新的检测基准
+				p.cursorX, p.cursorY = p.desktop.CursorPos()
+			}
+			continue
 		}
 
-		// 检测用户物理鼠标移动
-		var currentCursor POINT
-		procGetCursorPos.Call(uintptr(unsafe.Pointer(&currentCursor)))
-		
-		// 如果鼠标移动超过 50px，暂停并警告
-		dx := currentCursor.X - p.initialCursor.X
-		dy := currentCursor.Y - p.initialCursor.Y
-		distance := dx*dx + dy*dy
-		if i > 0 && distance > 50*50 { // 50px 阈值
+		// 检测用户物理鼠标移动，超过 50px 暂停并等待恢复
+		cursorX, cursorY := p.desktop.CursorPos()
+		dx := cursorX - p.cursorX
+		dy := cursorY - p.cursorY
+		if p.executed > 0 && dx*dx+dy*dy > 50*50 {
 			p.mutex.Lock()
 			p.isPaused = true
 			p.mutex.Unlock()
-			// 这里应该触发 UI 警告，但现在只是暂停
-			// UI 层需要轮询检查 isPaused 状态
+			// UI 层轮询 IsPaused 显示提示
 			continue
 		}
+		return nil
+	}
+}
+
+// executeEvent 执行单个事件，scope 为展开文本模板时可用的变量
+func (p *Player) executeEvent(event *model.Event, scope map[string]string) error {
+	// 录制坐标换算到当前屏幕
+	x, y := p.scaler.Map(event.X, event.Y)
+
+	switch event.Type {
+	case model.EventMouseMove:
+		return p.simulateMouseMove(x, y)
+	case model.EventMouseClick:
+		if event.Window != nil {
+			x, y = p.locateClick(event, x, y)
+		}
+		return p.simulateMouseClick(x, y, event.Button)
+	case model.EventMouseDown:
+		return p.simulateMouseDown(x, y, event.Button)
+	case model.EventMouseUp:
+		return p.simulateMouseUp(x, y, event.Button)
+	case model.EventMouseWheel:
+		return p.simulateMouseWheel(x, y, event.WheelDelta, event.Orientation)
+	case model.EventKeyDown:
+		return p.simulateKeyDown(event.KeyCode)
+	case model.EventKeyUp:
+		return p.simulateKeyUp(event.KeyCode)
+	case model.EventKeyPress:
+		return p.simulateKeyPress(event.KeyCode)
+	case model.EventTypeText:
+		text := event.Text
+		if model.HasTemplate(text) {
+			var err error
+			if text, err = (model.Variables{Now: p.startedAt, Values: p.values(scope)}).Expand(text); err != nil {
+				return err
+			}
+		}
+		return p.simulateTypeText(text)
+	case model.EventSecret:
+		return p.simulateTypeText(p.secretValues[event.Secret])
+	case model.EventWaitWindow:
+		return p.waitWindow(event)
+	default:
+		return fmt.Errorf("unknown event type: %s", event.Type)
+	}
+}
+
+// locateClick 在当前桌面上查找录制时的目标窗口，按窗口相对坐标定位点击
+// 找不到窗口时退回绝对坐标 (x, y)
+func (p *Player) locateClick(event *model.Event, x, y int) (int, int) {
+	wx, wy, ok, err := screen.LocateClick(p.desktop, *event.Window, event.RelX, event.RelY)
+	if err != nil {
+		fmt.Printf("Error locating window %q: %v\n", event.Window.Title, err)
+	}
+	if !ok {
+		return x, y
+	}
+	return wx, wy
+}
 
-		// 计算延迟（考虑速度因子）
-		if event.Delay > 0 {
-			actualDelay := time.Duration(float64(event.Delay)/p.speedFactor) * time.Millisecond
-			time.Sleep(actualDelay)
+// waitWindow 定时查找与 event.Window 匹配的窗口，直到出现或超时
+// 每次查找前经过检查点，等待期间可以停止或暂停，暂停的时间不计入超时
+// 指定了 Var 时把是否找到（"true" / "false"）保存到这个变量，否则超时返回错误
+func (p *Player) waitWindow(event *model.Event) error {
+	timeout := time.Duration(event.Timeout) * time.Millisecond
+	var waited time.Duration
+
+	found := false
+	for {
+		if err := p.checkpoint(); err != nil {
+			return err
+		}
+		candidates, err := p.desktop.Windows()
+		if err != nil {
+			return fmt.Errorf("failed to list windows: %w", err)
+		}
+		if _, found = screen.MatchWindow(*event.Window, candidates); found || waited >= timeout {
+			break
 		}
 
-		// 执行事件
-		if err := p.executeEvent(&event); err != nil {
-			// 记录错误，但继续执行
-			fmt.Printf("Error executing event: %v\n", err)
+		interval := windowPollInterval
+		if remaining := timeout - waited; remaining < interval {
+			interval = remaining
 		}
+		select {
+		case <-p.stopChan:
+			return ErrPlaybackStopped
+		case <-time.After(interval):
+		}
+		waited += interval
+	}
 
-		// 更新初始位置
-		p.initialCursor = currentCursor
+	if event.Var != "" {
+		p.outputs[event.Var] = strconv.FormatBool(found)
+		return nil
 	}
+	if !found {
+		return fmt.Errorf("window %q did not appear within %dms", windowName(event.Window), event.Timeout)
+	}
+	return nil
 }
 
-// executeEvent 执行单个事件
-func (p *Player) executeEvent(event *model.Event) error {
-	switch event.Type {
-	case "mouse_move":
-		return p.simulateMouseMove(event.X, event.Y)
-	case "mouse_click":
-		return p.simulateMouseClick(event.X, event.Y, event.Button)
-	case "key_press":
-		return p.simulateKeyPress(event.KeyCode)
-	default:
-		return fmt.Errorf("unknown event type: %s", event.Type)
+// windowName 返回用于提示的窗口名称，没有标题时用类名
+func windowName(window *screen.Window) string {
+	if window.Title != "" {
+		return window.Title
 	}
+	return window.Class
 }
 
 // simulateMouseMove 模拟鼠标移动
 func (p *Player) simulateMouseMove(x, y int) error {
-	ret, _, err := procSetCursorPos.Call(uintptr(x), uintptr(y))
-	if ret == 0 {
-		return fmt.Errorf("SetCursorPos failed: %v", err)
-	}
-	return nil
+	return p.desktop.MoveMouse(x, y)
 }
 
 // simulateMouseClick 模拟鼠标点击
@@ -273,77 +727,105 @@ func (p *Player) simulateMouseClick(x, y int, button string) error {
 	// 小延迟，确保移动完成
 	time.Sleep(10 * time.Millisecond)
 
-	var downFlag, upFlag uint32
-	switch button {
-	case "left":
-		downFlag = MOUSEEVENTF_LEFTDOWN
-		upFlag = MOUSEEVENTF_LEFTUP
-	case "right":
-		downFlag = MOUSEEVENTF_RIGHTDOWN
-		upFlag = MOUSEEVENTF_RIGHTUP
-	case "middle":
-		downFlag = MOUSEEVENTF_MIDDLEDOWN
-		upFlag = MOUSEEVENTF_MIDDLEUP
-	case "double":
+	if button == "double" {
 		// 双击：两次左键点击
 		if err := p.simulateMouseClick(x, y, "left"); err != nil {
 			return err
 		}
 		time.Sleep(50 * time.Millisecond)
 		return p.simulateMouseClick(x, y, "left")
-	default:
-		return fmt.Errorf("unknown button: %s", button)
 	}
 
 	// 按下
-	input := INPUT{
-		Type: INPUT_MOUSE,
-		Mi: MOUSEINPUT{
-			DwFlags: downFlag,
-		},
-	}
-	ret, _, err := procSendInput.Call(1, uintptr(unsafe.Pointer(&input)), unsafe.Sizeof(input))
-	if ret == 0 {
-		return fmt.Errorf("SendInput (mouse down) failed: %v", err)
+	if err := p.desktop.MouseButton(button, false); err != nil {
+		return err
 	}
 
 	// 小延迟
 	time.Sleep(10 * time.Millisecond)
 
 	// 释放
-	input.Mi.DwFlags = upFlag
-	ret, _, err = procSendInput.Call(1, uintptr(unsafe.Pointer(&input)), unsafe.Sizeof(input))
-	if ret == 0 {
-		return fmt.Errorf("SendInput (mouse up) failed: %v", err)
-	}
+	return p.desktop.MouseButton(button, true)
+}
 
+// simulateMouseDown 移动到指定位置并按下鼠标按键（拖拽开始）
+func (p *Player) simulateMouseDown(x, y int, button string) error {
+	if err := p.simulateMouseMove(x, y); err != nil {
+		return err
+	}
+	if err := p.desktop.MouseButton(button, false); err != nil {
+		return err
+	}
+	p.heldButtons[button] = true
 	return nil
 }
 
-// simulateKeyPress 模拟按键
-func (p *Player) simulateKeyPress(keyCode int) error {
-	// 按下
-	input := INPUT{
-		Type: INPUT_KEYBOARD,
-		Ki: KEYBDINPUT{
-			WVk: uint16(keyCode),
-		},
+// simulateMouseUp 移动到指定位置并释放鼠标按键（拖拽结束）
+func (p *Player) simulateMouseUp(x, y int, button string) error {
+	if err := p.simulateMouseMove(x, y); err != nil {
+		return err
+	}
+	delete(p.heldButtons, button)
+	return p.desktop.MouseButton(button, true)
+}
+
+// releaseHeldButtons 释放回放过程中仍处于按下状态的鼠标按键
+func (p *Player) releaseHeldButtons() {
+	for button := range p.heldButtons {
+		if err := p.desktop.MouseButton(button, true); err != nil {
+			fmt.Printf("Error releasing mouse button %s: %v\n", button, err)
+		}
 	}
-	ret, _, err := procSendInput.Call(1, uintptr(unsafe.Pointer(&input)), unsafe.Sizeof(input))
-	if ret == 0 {
-		return fmt.Errorf("SendInput (key down) failed: %v", err)
+	p.heldButtons = make(map[string]bool)
+}
+
+// simulateMouseWheel 模拟滚轮滚动
+func (p *Player) simulateMouseWheel(x, y, delta int, orientation string) error {
+	// 滚轮消息发给光标下的窗口，先移动到录制时的位置
+	if err := p.simulateMouseMove(x, y); err != nil {
+		return err
+	}
+	return p.desktop.Wheel(delta, orientation)
+}
+
+// simulateKeyPress 模拟按键（旧版 key_press 事件：按下后立即释放）
+func (p *Player) simulateKeyPress(keyCode int) error {
+	if err := p.simulateKeyDown(keyCode); err != nil {
+		return err
 	}
 
 	// 小延迟
 	time.Sleep(10 * time.Millisecond)
 
-	// 释放
-	input.Ki.DwFlags = KEYEVENTF_KEYUP
-	ret, _, err = procSendInput.Call(1, uintptr(unsafe.Pointer(&input)), unsafe.Sizeof(input))
-	if ret == 0 {
-		return fmt.Errorf("SendInput (key up) failed: %v", err)
-	}
+	return p.simulateKeyUp(keyCode)
+}
 
+// simulateKeyDown 模拟按下按键，并记录为按住状态
+func (p *Player) simulateKeyDown(keyCode int) error {
+	if err := p.desktop.Key(keyCode, false); err != nil {
+		return err
+	}
+	p.heldKeys[keyCode] = true
 	return nil
 }
 
+// simulateKeyUp 模拟释放按键
+func (p *Player) simulateKeyUp(keyCode int) error {
+	delete(p.heldKeys, keyCode)
+	return p.desktop.Key(keyCode, true)
+}
+
+// releaseHeldKeys 释放回放过程中仍处于按下状态的按键
+func (p *Player) releaseHeldKeys() {
+	for keyCode := range p.heldKeys {
+		if err := p.desktop.Key(keyCode, true); err != nil {
+			fmt.Printf("Error releasing key %d: %v\n", keyCode, err)
+		}
+	}
+	p.heldKeys = make(map[int]bool)
+}
+
+// simulateTypeText 以 Unicode 方式输入文本，不依赖键盘布局和输入法
+func (p *Player) simulateTypeText(text string) error {
+	return p.desktop.TypeText(text)
+}
@@ -0,0 +1,790 @@
+package core
+
+import (
+	"dailyflow/internal/model"
+	"dailyflow/internal/screen"
+	"dailyflow/internal/storage"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeDesktop 记录回放注入的输入，光标位置跟随 MoveMouse
+type fakeDesktop struct {
+	mutex    sync.Mutex
+	calls    []string
+	x, y     int
+	primary  screen.Rect
+	monitors []screen.Monitor
+	windows  []screen.Window
+	polls    int    // Windows 被调用的次数
+	failText string // 输入这段文本时返回错误，用于模拟步骤失败
+}
+
+func newFakeDesktop() *fakeDesktop {
+	primary := screen.Rect{Width: 1920, Height: 1080}
+	return &fakeDesktop{
+		primary:  primary,
+		monitors: []screen.Monitor{{Rect: primary, Primary: true}},
+	}
+}
+
+func (d *fakeDesktop) record(format string, args ...interface{}) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.calls = append(d.calls, fmt.Sprintf(format, args...))
+}
+
+func (d *fakeDesktop) Calls() []string {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	return append([]string(nil), d.calls...)
+}
+
+func (d *fakeDesktop) MoveMouse(x, y int) error {
+	d.record("move %d,%d", x, y)
+	d.mutex.Lock()
+	d.x, d.y = x, y
+	d.mutex.Unlock()
+	return nil
+}
+
+func (d *fakeDesktop) MouseButton(button string, up bool) error {
+	if up {
+		d.record("%s up", button)
+	} else {
+		d.record("%s down", button)
+	}
+	return nil
+}
+
+func (d *fakeDesktop) Wheel(delta int, orientation string) error {
+	d.record("wheel %d %s", delta, orientation)
+	return nil
+}
+
+func (d *fakeDesktop) Key(keyCode int, up bool) error {
+	if up {
+		d.record("key 0x%02X up", keyCode)
+	} else {
+		d.record("key 0x%02X down", keyCode)
+	}
+	return nil
+}
+
+func (d *fakeDesktop) TypeText(text string) error {
+	if d.failText != "" && text == d.failText {
+		return fmt.Errorf("cannot type %q", text)
+	}
+	d.record("type %q", text)
+	return nil
+}
+
+func (d *fakeDesktop) CursorPos() (int, int) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	return d.x, d.y
+}
+
+func (d *fakeDesktop) Monitors() ([]screen.Monitor, error) {
+	return d.monitors, nil
+}
+
+func (d *fakeDesktop) PrimaryScreen() screen.Rect {
+	return d.primary
+}
+
+func (d *fakeDesktop) Windows() ([]screen.Window, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.polls++
+	return d.windows, nil
+}
+
+// Polls 返回查找窗口的次数
+func (d *fakeDesktop) Polls() int {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	return d.polls
+}
+
+// setWindows 替换桌面上的窗口，回放进行中也可以调用
+func (d *fakeDesktop) setWindows(windows ...screen.Window) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.windows = windows
+}
+
+// newTestPlayer 创建使用内存存储、临时凭据文件和假桌面的回放器，tasks 预先保存到存储中
+func newTestPlayer(t *testing.T, tasks map[string]*model.TaskData) (*Player, *fakeDesktop, *storage.MemoryStore) {
+	t.Helper()
+	store := storage.NewMemoryStore()
+	for name, task := range tasks {
+		if err := store.SaveTask(name, task); err != nil {
+			t.Fatal(err)
+		}
+	}
+	secrets := storage.NewFileSecretStore(filepath.Join(t.TempDir(), "secrets.json"))
+	desktop := newFakeDesktop()
+	return NewPlayer(store, secrets, desktop), desktop, store
+}
+
+// testTask 创建录制分辨率为 1920x1080 的任务
+func testTask(events ...model.Event) *model.TaskData {
+	task := model.NewTaskData("1920x1080")
+	for _, event := range events {
+		task.AddEvent(event)
+	}
+	return task
+}
+
+func TestPlayback(t *testing.T) {
+	task := testTask(
+		model.Event{Type: model.EventMouseMove, X: 100, Y: 100, Button: "none"},
+		model.Event{Type: model.EventMouseClick, X: 812, Y: 440, Button: "left",
+			Window: &screen.Window{Title: "日报", Class: "Notepad", Rect: screen.Rect{Left: 100, Top: 50, Width: 800, Height: 600}},
+			RelX:   712, RelY: 390},
+		model.Event{Type: model.EventMouseWheel, X: 812, Y: 440, Button: "none", WheelDelta: -120, Orientation: model.WheelVertical},
+		model.Event{Type: model.EventKeyDown, Button: "none", KeyCode: 0xA2},
+		model.Event{Type: model.EventKeyDown, Button: "none", KeyCode: 'S'},
+		model.Event{Type: model.EventKeyUp, Button: "none", KeyCode: 'S'},
+		model.Event{Type: model.EventTypeText, Button: "none", Text: "完成"},
+		model.Event{Type: model.EventMouseDown, X: 10, Y: 10, Button: "right"},
+	)
+	player, desktop, _ := newTestPlayer(t, map[string]*model.TaskData{"日报": task})
+	// 窗口被移动到了别处，点击跟随窗口
+	desktop.windows = []screen.Window{{Title: "日报", Class: "Notepad", Rect: screen.Rect{Left: 300, Top: 150, Width: 800, Height: 600}}}
+
+	if err := player.StartPlayback("日报", 1.0); err != nil {
+		t.Fatalf("StartPlayback() error = %v", err)
+	}
+	player.Wait()
+
+	want := []string{
+		"move 100,100",
+		"move 1012,540", "left down", "left up",
+		"move 812,440", "wheel -120 vertical",
+		"key 0xA2 down",
+		"key 0x53 down", "key 0x53 up",
+		`type "完成"`,
+		"move 10,10", "right down",
+		// 回放结束时释放仍按住的键和鼠标按键
+		"key 0xA2 up", "right up",
+	}
+	if got := desktop.Calls(); !reflect.DeepEqual(got, want) {
+		t.Errorf("playback calls =\n%q\nwant\n%q", got, want)
+	}
+	if player.IsPlaying() {
+		t.Error("IsPlaying() = true after playback finished")
+	}
+}
+
+func TestPlaybackResolution(t *testing.T) {
+	task := testTask(model.Event{Type: model.EventMouseClick, X: 960, Y: 540, Button: "left"})
+
+	tests := []struct {
+		name    string
+		policy  string
+		wantErr bool
+		want    string
+	}{
+		{"scale", model.ResolutionScale, false, "move 640,360"},
+		{"strict", model.ResolutionStrict, true, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			player, desktop, _ := newTestPlayer(t, map[string]*model.TaskData{"日报": task})
+			desktop.primary = screen.Rect{Width: 1280, Height: 720}
+			player.SetResolutionPolicy(tt.policy)
+
+			err := player.StartPlayback("日报", 1.0)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("StartPlayback() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			player.Wait()
+			if calls := desktop.Calls(); len(calls) == 0 || calls[0] != tt.want {
+				t.Errorf("playback calls = %q, want first %q", calls, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlaybackRefused(t *testing.T) {
+	invalid := testTask(model.Event{Type: model.EventMouseClick, X: 1, Y: 1, Button: "x1"})
+	player, desktop, _ := newTestPlayer(t, map[string]*model.TaskData{
+		"无效":  invalid,
+		"空任务": model.NewTaskData("1920x1080"),
+	})
+
+	if err := player.StartPlayback("无效", 1.0); !errors.Is(err, model.ErrInvalidTask) {
+		t.Errorf("invalid task error = %v, want ErrInvalidTask", err)
+	}
+	if err := player.StartPlayback("不存在", 1.0); !errors.Is(err, storage.ErrTaskNotFound) {
+		t.Errorf("missing task error = %v, want ErrTaskNotFound", err)
+	}
+	if err := player.StartPlayback("空任务", 1.0); err == nil {
+		t.Error("empty task started playing")
+	}
+	if player.IsPlaying() || len(desktop.Calls()) != 0 {
+		t.Errorf("refused playback injected input: %q", desktop.Calls())
+	}
+}
+
+func TestPlaybackSecrets(t *testing.T) {
+	task := testTask(
+		model.Event{Type: model.EventSecret, Button: "none", Secret: "门户#1"},
+		model.Event{Type: model.EventKeyDown, Button: "none", KeyCode: 0x0D},
+		model.Event{Type: model.EventKeyUp, Button: "none", KeyCode: 0x0D},
+		model.Event{Type: model.EventSecret, Button: "none", Secret: "门户#1"},
+	)
+	player, desktop, _ := newTestPlayer(t, map[string]*model.TaskData{"登录": task})
+
+	// 缺少敏感输入时拒绝回放，不注入任何输入
+	if err := player.StartPlayback("登录", 1.0); !errors.Is(err, storage.ErrSecretNotFound) {
+		t.Fatalf("StartPlayback() error = %v, want ErrSecretNotFound", err)
+	}
+	if calls := desktop.Calls(); len(calls) != 0 {
+		t.Fatalf("refused playback injected input: %q", calls)
+	}
+
+	if err := player.secrets.SetSecret("门户#1", "P@ssw0rd"); err != nil {
+		t.Fatal(err)
+	}
+	if err := player.StartPlayback("登录", 1.0); err != nil {
+		t.Fatalf("StartPlayback() error = %v", err)
+	}
+	player.Wait()
+
+	want := []string{`type "P@ssw0rd"`, "key 0x0D down", "key 0x0D up", `type "P@ssw0rd"`}
+	if got := desktop.Calls(); !reflect.DeepEqual(got, want) {
+		t.Errorf("playback calls = %q, want %q", got, want)
+	}
+}
+
+func TestPlaybackTemplates(t *testing.T) {
+	task := testTask(
+		model.Event{Type: model.EventTypeText, Button: "none", Text: "{{branch}} {{prev_workday:MMDD}}"},
+		model.Event{Type: model.EventTypeText, Button: "none", Text: "{{date}}"},
+	)
+	player, desktop, _ := newTestPlayer(t, map[string]*model.TaskData{"日报": task})
+	player.now = func() time.Time { return time.Date(2024, 12, 2, 8, 30, 0, 0, time.Local) }
+
+	// 未定义的自定义变量在回放前报错，不注入任何输入
+	err := player.StartPlayback("日报", 1.0)
+	var validationErr *model.ValidationError
+	if !errors.As(err, &validationErr) || validationErr.Findings[0].Check != model.CheckUndefinedVar {
+		t.Fatalf("StartPlayback() error = %v, want undefined variable", err)
+	}
+	if calls := desktop.Calls(); len(calls) != 0 {
+		t.Fatalf("refused playback injected input: %q", calls)
+	}
+
+	player.SetVariables(map[string]string{"branch": "0101"})
+	if err := player.StartPlayback("日报", 1.0); err != nil {
+		t.Fatalf("StartPlayback() error = %v", err)
+	}
+	player.Wait()
+
+	want := []string{`type "0101 1129"`, `type "2024-12-02"`}
+	if got := desktop.Calls(); !reflect.DeepEqual(got, want) {
+		t.Errorf("playback calls = %q, want %q", got, want)
+	}
+}
+
+func TestPlaybackRepeat(t *testing.T) {
+	task := testTask(
+		model.Event{Type: model.EventRepeat, Button: "none", Count: 2, Var: "行", Steps: []model.Event{
+			{Type: model.EventMouseClick, X: 100, Y: 100, Button: "left"},
+			{Type: model.EventRepeat, Button: "none", Count: 2, Var: "列", Steps: []model.Event{
+				{Type: model.EventTypeText, Button: "none", Text: "{{行}}-{{列}}"},
+			}},
+		}},
+		model.Event{Type: model.EventKeyDown, Button: "none", KeyCode: 0x0D},
+		model.Event{Type: model.EventKeyUp, Button: "none", KeyCode: 0x0D},
+	)
+	player, desktop, _ := newTestPlayer(t, map[string]*model.TaskData{"批量": task})
+
+	if err := player.StartPlayback("批量", 1.0); err != nil {
+		t.Fatalf("StartPlayback() error = %v", err)
+	}
+	player.Wait()
+
+	want := []string{
+		"move 100,100", "left down", "left up", `type "1-1"`, `type "1-2"`,
+		"move 100,100", "left down", "left up", `type "2-1"`, `type "2-2"`,
+		"key 0x0D down", "key 0x0D up",
+	}
+	if got := desktop.Calls(); !reflect.DeepEqual(got, want) {
+		t.Errorf("playback calls =\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestPlaybackIf(t *testing.T) {
+	text := func(s string) model.Event { return model.Event{Type: model.EventTypeText, Button: "none", Text: s} }
+	task := testTask(
+		model.Event{Type: model.EventWaitWindow, Button: "none", Window: &screen.Window{Title: "日报"}, Timeout: 1000, Var: "found"},
+		model.Event{Type: model.EventWaitWindow, Button: "none", Window: &screen.Window{Title: "保存"}, Timeout: 300, Var: "saved"},
+		model.Event{Type: model.EventRepeat, Button: "none", Count: 3, Var: "i", Steps: []model.Event{
+			{Type: model.EventIf, Button: "none", Cond: "i % 2 == 1 && found", Steps: []model.Event{text("{{i}}")}, Else: []model.Event{text("偶{{i}}")}},
+		}},
+		model.Event{Type: model.EventIf, Button: "none", Cond: "weekday == 1 || is_month_end", Steps: []model.Event{text("周一")}},
+		model.Event{Type: model.EventIf, Button: "none", Cond: "saved", Steps: []model.Event{text("已保存")}, Else: []model.Event{text("{{saved}}")}},
+		// 条件无法求值时打印错误并跳过整个块
+		model.Event{Type: model.EventIf, Button: "none", Cond: `[客户] > 1`, Steps: []model.Event{text("大")}, Else: []model.Event{text("小")}},
+		text("完成"),
+	)
+	player, desktop, _ := newTestPlayer(t, map[string]*model.TaskData{"日报": task})
+	desktop.windows = []screen.Window{{Title: "日报 - 记事本", Class: "Notepad"}}
+	player.now = func() time.Time { return time.Date(2024, 12, 2, 8, 30, 0, 0, time.Local) }
+	player.SetVariables(map[string]string{"客户": "华东"})
+
+	if err := player.StartPlayback("日报", 1.0); err != nil {
+		t.Fatalf("StartPlayback() error = %v", err)
+	}
+	player.Wait()
+
+	want := []string{`type "1"`, `type "偶2"`, `type "3"`, `type "周一"`, `type "false"`, `type "完成"`}
+	if got := desktop.Calls(); !reflect.DeepEqual(got, want) {
+		t.Errorf("playback calls = %q, want %q", got, want)
+	}
+}
+
+func TestPlaybackBatchIfError(t *testing.T) {
+	task := testTask(
+		model.Event{Type: model.EventIf, Button: "none", Cond: "金额 > 5",
+			Steps: []model.Event{{Type: model.EventTypeText, Button: "none", Text: "大{{金额}}"}},
+			Else:  []model.Event{{Type: model.EventTypeText, Button: "none", Text: "小{{金额}}"}}},
+		model.Event{Type: model.EventWaitWindow, Button: "none", Window: &screen.Window{Title: "确认"}, Timeout: 1},
+	)
+	data, err := model.NewDataSet([][]string{{"金额"}, {"10"}, {"abc"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	player, desktop, _ := newTestPlayer(t, map[string]*model.TaskData{"录单": task})
+	desktop.windows = []screen.Window{{Title: "确认", Class: "#32770"}}
+
+	if err := player.StartBatch("录单", 1.0, BatchOptions{Data: data}); err != nil {
+		t.Fatalf("StartBatch() error = %v", err)
+	}
+	player.Wait()
+
+	want := []string{`type "大10"`}
+	if got := desktop.Calls(); !reflect.DeepEqual(got, want) {
+		t.Errorf("playback calls = %q, want %q", got, want)
+	}
+	results := player.Results()
+	if len(results) != 2 || results[0].Err != nil {
+		t.Fatalf("Results() = %+v, want row 1 to succeed", results)
+	}
+	if err := results[1].Err; err == nil || !strings.HasPrefix(err.Error(), "events[0]: ") {
+		t.Errorf("row 2 error = %v, want an error at events[0]", err)
+	}
+}
+
+func TestPlaybackWaitWindowTimeout(t *testing.T) {
+	task := testTask(
+		model.Event{Type: model.EventWaitWindow, Button: "none", Window: &screen.Window{Class: "#32770"}, Timeout: 1},
+		model.Event{Type: model.EventTypeText, Button: "none", Text: "{{金额}}"},
+	)
+	data, err := model.NewDataSet([][]string{{"金额"}, {"10"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	player, desktop, _ := newTestPlayer(t, map[string]*model.TaskData{"录单": task})
+
+	// 没有保存结果的 wait_window 超时即出错，数据驱动回放中结束这一行
+	if err := player.StartBatch("录单", 1.0, BatchOptions{Data: data}); err != nil {
+		t.Fatalf("StartBatch() error = %v", err)
+	}
+	player.Wait()
+
+	if calls := desktop.Calls(); len(calls) != 0 {
+		t.Errorf("playback calls = %q, want none", calls)
+	}
+	results := player.Results()
+	if len(results) != 1 || results[0].Err == nil || !strings.Contains(results[0].Err.Error(), `events[0]: window "#32770" did not appear within 1ms`) {
+		t.Errorf("Results() = %+v, want a timeout at events[0]", results)
+	}
+}
+
+func TestPlaybackBatch(t *testing.T) {
+	task := testTask(
+		model.Event{Type: model.EventMouseDown, X: 10, Y: 10, Button: "left"},
+		model.Event{Type: model.EventTypeText, Button: "none", Text: "{{单号}}"},
+		model.Event{Type: model.EventMouseUp, X: 20, Y: 20, Button: "left"},
+		model.Event{Type: model.EventTypeText, Button: "none", Text: "{{金额}}"},
+	)
+	// 空行不是数据行，但失败按文件中的行号报告
+	data, err := model.NewDataSet([][]string{{"单号", "金额"}, {"A1", "10"}, {"", ""}, {"A2", "20"}, {"A3", "30"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		stopOnError bool
+		want        []string
+		wantRows    []int // 各行结果的行号，负数表示该行失败
+	}{
+		{
+			name: "skip failed row",
+			want: []string{
+				"move 10,10", "left down", `type "A1"`, "move 20,20", "left up", `type "10"`,
+				// 第 2 行输入失败，按住的鼠标按键在下一行开始前释放
+				"move 10,10", "left down", "left up",
+				"move 10,10", "left down", `type "A3"`, "move 20,20", "left up", `type "30"`,
+			},
+			wantRows: []int{1, -2, 3},
+		},
+		{
+			name:        "stop on error",
+			stopOnError: true,
+			want: []string{
+				"move 10,10", "left down", `type "A1"`, "move 20,20", "left up", `type "10"`,
+				"move 10,10", "left down", "left up",
+			},
+			wantRows: []int{1, -2},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			player, desktop, _ := newTestPlayer(t, map[string]*model.TaskData{"录单": task})
+			desktop.failText = "A2"
+
+			var reported []int
+			opts := BatchOptions{Data: data, StopOnError: tt.stopOnError, OnRow: func(result RowResult) {
+				reported = append(reported, result.Row)
+			}}
+			if err := player.StartBatch("录单", 1.0, opts); err != nil {
+				t.Fatalf("StartBatch() error = %v", err)
+			}
+			player.Wait()
+
+			if got := desktop.Calls(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("playback calls =\n%q\nwant\n%q", got, tt.want)
+			}
+			var rows []int
+			for _, result := range player.Results() {
+				if result.Err != nil {
+					rows = append(rows, -result.Row)
+				} else {
+					rows = append(rows, result.Row)
+				}
+			}
+			if !reflect.DeepEqual(rows, tt.wantRows) {
+				t.Errorf("results rows = %v, want %v", rows, tt.wantRows)
+			}
+			if len(reported) != len(tt.wantRows) {
+				t.Errorf("OnRow called for rows %v, want %d calls", reported, len(tt.wantRows))
+			}
+
+			var batchErr *BatchError
+			if err := BatchFailure(player.Results()); !errors.As(err, &batchErr) || len(batchErr.Failed) != 1 {
+				t.Errorf("BatchFailure() = %v, want one failed row", err)
+			} else if got := batchErr.Failed[0].Err.Error(); got != `events[1]: cannot type "A2"` {
+				t.Errorf("failed row error = %q", got)
+			} else if batchErr.Failed[0].Line != 4 || !strings.Contains(err.Error(), "line 4:") {
+				t.Errorf("BatchFailure() = %v with failed line %d, want line 4", err, batchErr.Failed[0].Line)
+			}
+		})
+	}
+}
+
+func TestPlaybackBatchRefused(t *testing.T) {
+	task := testTask(model.Event{Type: model.EventTypeText, Button: "none", Text: "{{单号}} {{备注}}"})
+	player, desktop, _ := newTestPlayer(t, map[string]*model.TaskData{"录单": task})
+	data, err := model.NewDataSet([][]string{{"单号"}, {"A1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 数据文件中没有"备注"列，一行都不执行
+	err = player.StartBatch("录单", 1.0, BatchOptions{Data: data})
+	if !errors.Is(err, model.ErrInvalidTask) {
+		t.Errorf("StartBatch() error = %v, want ErrInvalidTask", err)
+	}
+	if calls := desktop.Calls(); len(calls) != 0 {
+		t.Errorf("refused batch made calls %q", calls)
+	}
+
+	// 直接回放时数据列没有定义
+	if err := player.StartPlayback("录单", 1.0); !errors.Is(err, model.ErrInvalidTask) {
+		t.Errorf("StartPlayback() error = %v, want ErrInvalidTask", err)
+	}
+}
+
+func TestStopPlaybackBatch(t *testing.T) {
+	task := testTask(model.Event{Type: model.EventTypeText, Button: "none", Text: "{{单号}}", Delay: 60 * 1000})
+	player, _, _ := newTestPlayer(t, map[string]*model.TaskData{"录单": task})
+	data, err := model.NewDataSet([][]string{{"单号"}, {"A1"}, {"A2"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := player.StartBatch("录单", 1.0, BatchOptions{Data: data}); err != nil {
+		t.Fatal(err)
+	}
+	if err := player.StopPlayback(); err != nil {
+		t.Fatalf("StopPlayback() error = %v", err)
+	}
+	player.Wait()
+
+	results := player.Results()
+	if len(results) != 1 || !errors.Is(results[0].Err, ErrPlaybackStopped) {
+		t.Errorf("results = %+v, want only row 1 stopped", results)
+	}
+}
+
+func TestPlaybackCall(t *testing.T) {
+	// 登录任务在 1280x720 下录制，坐标按它自己的分辨率换算
+	login := model.NewTaskData("1280x720")
+	login.AddEvent(model.Event{Type: model.EventMouseClick, X: 640, Y: 360, Button: "left"})
+	login.AddEvent(model.Event{Type: model.EventTypeText, Button: "none", Text: "{{工号}}", Delay: 1000})
+	task := testTask(
+		model.Event{Type: model.EventRepeat, Button: "none", Count: 2, Steps: []model.Event{
+			{Type: model.EventCall, Button: "none", Task: "登录"},
+		}},
+		model.Event{Type: model.EventCall, Button: "none", Task: "登录", Speed: 10},
+		model.Event{Type: model.EventMouseClick, X: 640, Y: 360, Button: "left"},
+	)
+	player, desktop, store := newTestPlayer(t, map[string]*model.TaskData{"日报": task, "登录": login})
+	player.SetVariables(map[string]string{"工号": "A01"})
+	config := model.NewConfig()
+	config.Tasks["登录"] = &model.TaskSchedule{ScheduleTime: "08:30", SpeedFactor: 10}
+	if err := store.SaveConfig(config); err != nil {
+		t.Fatal(err)
+	}
+
+	// 被调用的任务按自己的速度回放，三次调用的 1 秒等待合计远小于 3 秒
+	start := time.Now()
+	if err := player.StartPlayback("日报", 1.0); err != nil {
+		t.Fatalf("StartPlayback() error = %v", err)
+	}
+	player.Wait()
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("playback took %v, called task did not use its own speed", elapsed)
+	}
+
+	login3 := []string{"move 960,540", "left down", "left up", `type "A01"`}
+	want := append(append(append([]string{}, login3...), login3...), login3...)
+	want = append(want, "move 640,360", "left down", "left up")
+	if got := desktop.Calls(); !reflect.DeepEqual(got, want) {
+		t.Errorf("playback calls =\n%q\nwant\n%q", got, want)
+	}
+
+	// 被调用任务中的错误带有完整的步骤位置
+	desktop.failText = "A01"
+	data, err := model.NewDataSet([][]string{{"单号"}, {"1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := player.StartBatch("日报", 1.0, BatchOptions{Data: data}); err != nil {
+		t.Fatalf("StartBatch() error = %v", err)
+	}
+	player.Wait()
+	results := player.Results()
+	if len(results) != 1 || results[0].Err == nil ||
+		results[0].Err.Error() != `events[0].steps[0](登录).events[1]: cannot type "A01"` {
+		t.Errorf("results = %+v, want failure in called task", results)
+	}
+}
+
+func TestPlaybackCallRefused(t *testing.T) {
+	tests := []struct {
+		name  string
+		tasks map[string]*model.TaskData
+		want  string
+	}{
+		{
+			name: "missing task",
+			tasks: map[string]*model.TaskData{
+				"日报": testTask(model.Event{Type: model.EventCall, Button: "none", Task: "登录"}),
+			},
+			want: `events[0]: called task "登录" does not exist`,
+		},
+		{
+			name: "call cycle",
+			tasks: map[string]*model.TaskData{
+				"日报": testTask(model.Event{Type: model.EventCall, Button: "none", Task: "登录"}),
+				"登录": testTask(model.Event{Type: model.EventCall, Button: "none", Task: "日报"}),
+			},
+			want: "登录:events[0]: call cycle 日报 -> 登录 -> 日报",
+		},
+		{
+			name: "invalid called task",
+			tasks: map[string]*model.TaskData{
+				"日报": testTask(model.Event{Type: model.EventCall, Button: "none", Task: "登录"}),
+				"登录": testTask(model.Event{Type: model.EventKeyDown, Button: "none", KeyCode: 0x1FF}),
+			},
+			want: "登录:events[0]: virtual key code",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			player, desktop, _ := newTestPlayer(t, tt.tasks)
+			err := player.StartPlayback("日报", 1.0)
+			if !errors.Is(err, model.ErrInvalidTask) || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("StartPlayback() error = %v, want ErrInvalidTask containing %q", err, tt.want)
+			}
+			if calls := desktop.Calls(); len(calls) != 0 {
+				t.Errorf("refused playback made calls %q", calls)
+			}
+		})
+	}
+}
+
+func TestPausePlaybackInBlock(t *testing.T) {
+	task := testTask(model.Event{Type: model.EventRepeat, Button: "none", Count: 3, Steps: []model.Event{
+		{Type: model.EventRepeat, Button: "none", Count: 5, Var: "i", Steps: []model.Event{
+			{Type: model.EventTypeText, Button: "none", Text: "{{i}}", Delay: 5},
+		}},
+	}})
+	player, desktop, _ := newTestPlayer(t, map[string]*model.TaskData{"批量": task})
+
+	if err := player.StartPlayback("批量", 1.0); err != nil {
+		t.Fatal(err)
+	}
+	for len(desktop.Calls()) < 3 {
+		time.Sleep(time.Millisecond)
+	}
+	if err := player.PausePlayback(); err != nil {
+		t.Fatalf("PausePlayback() error = %v", err)
+	}
+	// 暂停前已越过检查点的一步仍会执行完，之后不再有输入
+	time.Sleep(50 * time.Millisecond)
+	paused := len(desktop.Calls())
+	time.Sleep(150 * time.Millisecond)
+	if got := len(desktop.Calls()); got != paused || paused == 15 {
+		t.Fatalf("calls while paused went from %d to %d", paused, got)
+	}
+
+	if err := player.ResumePlayback(); err != nil {
+		t.Fatalf("ResumePlayback() error = %v", err)
+	}
+	player.Wait()
+	if got := len(desktop.Calls()); got != 15 {
+		t.Errorf("playback made %d calls after resume, want 15", got)
+	}
+}
+
+func TestPausePlaybackWaitWindow(t *testing.T) {
+	task := testTask(
+		model.Event{Type: model.EventTypeText, Button: "none", Text: "before"},
+		model.Event{Type: model.EventWaitWindow, Button: "none", Window: &screen.Window{Title: "导出完成"}, Timeout: 300, Var: "found"},
+		model.Event{Type: model.EventTypeText, Button: "none", Text: "{{found}}"},
+	)
+	player, desktop, _ := newTestPlayer(t, map[string]*model.TaskData{"导出": task})
+
+	// 开始查找窗口后暂停
+	if err := player.StartPlayback("导出", 1.0); err != nil {
+		t.Fatal(err)
+	}
+	for desktop.Polls() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	if err := player.PausePlayback(); err != nil {
+		t.Fatalf("PausePlayback() error = %v", err)
+	}
+
+	// 暂停的时间超过超时时间，恢复后窗口出现，等待仍然成功
+	time.Sleep(500 * time.Millisecond)
+	desktop.setWindows(screen.Window{Title: "导出完成", Class: "#32770"})
+	if err := player.ResumePlayback(); err != nil {
+		t.Fatalf("ResumePlayback() error = %v", err)
+	}
+	player.Wait()
+
+	want := []string{`type "before"`, `type "true"`}
+	if got := desktop.Calls(); !reflect.DeepEqual(got, want) {
+		t.Errorf("playback calls = %q, want %q", got, want)
+	}
+}
+
+func TestStopPlayback(t *testing.T) {
+	task := testTask(
+		model.Event{Type: model.EventKeyDown, Button: "none", KeyCode: 0x11},
+		model.Event{Type: model.EventKeyUp, Button: "none", KeyCode: 0x11, Delay: 60 * 1000},
+	)
+	player, desktop, _ := newTestPlayer(t, map[string]*model.TaskData{"日报": task})
+
+	// 在第二个事件前的长延迟中停止，按住的键被释放
+	if err := player.StartPlayback("日报", 1.0); err != nil {
+		t.Fatal(err)
+	}
+	for len(desktop.Calls()) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	if err := player.StopPlayback(); err != nil {
+		t.Fatalf("StopPlayback() error = %v", err)
+	}
+	player.Wait()
+
+	want := []string{"key 0x11 down", "key 0x11 up"}
+	if got := desktop.Calls(); !reflect.DeepEqual(got, want) {
+		t.Errorf("playback calls = %q, want %q", got, want)
+	}
+}
+
+func TestStopPlaybackWaitWindow(t *testing.T) {
+	task := testTask(
+		model.Event{Type: model.EventTypeText, Button: "none", Text: "before"},
+		model.Event{Type: model.EventWaitWindow, Button: "none", Window: &screen.Window{Title: "导出完成"}, Timeout: model.MaxWaitTimeout},
+		model.Event{Type: model.EventTypeText, Button: "none", Text: "after"},
+	)
+	player, desktop, _ := newTestPlayer(t, map[string]*model.TaskData{"导出": task})
+
+	// 等待窗口期间停止，之后的步骤不再执行
+	if err := player.StartPlayback("导出", 1.0); err != nil {
+		t.Fatal(err)
+	}
+	for len(desktop.Calls()) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	if err := player.StopPlayback(); err != nil {
+		t.Fatalf("StopPlayback() error = %v", err)
+	}
+	player.Wait()
+
+	want := []string{`type "before"`}
+	if got := desktop.Calls(); !reflect.DeepEqual(got, want) {
+		t.Errorf("playback calls = %q, want %q", got, want)
+	}
+}
+
+func TestStopPlaybackInBlock(t *testing.T) {
+	task := testTask(
+		model.Event{Type: model.EventRepeat, Button: "none", Count: 100, Steps: []model.Event{
+			{Type: model.EventRepeat, Button: "none", Count: 100, Steps: []model.Event{
+				{Type: model.EventKeyDown, Button: "none", KeyCode: 0x11},
+				{Type: model.EventKeyUp, Button: "none", KeyCode: 0x11, Delay: 60 * 1000},
+			}},
+		}},
+		model.Event{Type: model.EventTypeText, Button: "none", Text: "after"},
+	)
+	player, desktop, _ := newTestPlayer(t, map[string]*model.TaskData{"批量": task})
+
+	// 在最内层块的长延迟中停止，外层的循环和之后的步骤都不再执行
+	if err := player.StartPlayback("批量", 1.0); err != nil {
+		t.Fatal(err)
+	}
+	for len(desktop.Calls()) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	if err := player.StopPlayback(); err != nil {
+		t.Fatalf("StopPlayback() error = %v", err)
+	}
+	player.Wait()
+
+	want := []string{"key 0x11 down", "key 0x11 up"}
+	if got := desktop.Calls(); !reflect.DeepEqual(got, want) {
+		t.Errorf("playback calls = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,125 @@
+package core
+
+import (
+	"dailyflow/internal/screen"
+	"fmt"
+	"sync"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+const (
+	GA_ROOT = 2
+
+	maxWindowTextLength = 256
+)
+
+var (
+	procEnumWindows         = user32.NewProc("EnumWindows")
+	procWindowFromPoint     = user32.NewProc("WindowFromPoint")
+	procGetAncestor         = user32.NewProc("GetAncestor")
+	procGetWindowText       = user32.NewProc("GetWindowTextW")
+	procGetClassName        = user32.NewProc("GetClassNameW")
+	procGetWindowRect       = user32.NewProc("GetWindowRect")
+	procIsWindowVisible     = user32.NewProc("IsWindowVisible")
+	procIsIconic            = user32.NewProc("IsIconic")
+	procGetDesktopWindow    = user32.NewProc("GetDesktopWindow")
+	procGetForegroundWindow = user32.NewProc("GetForegroundWindow")
+
+	// syscall.NewCallback 创建的回调数量有限，只创建一次
+	windowEnumCallback = syscall.NewCallback(windowEnumProc)
+	windowEnumMutex    sync.Mutex
+	windowEnumResult   []screen.Window
+)
+
+// desktopWindowFinder 通过 EnumWindows 枚举桌面上可见且未最小化的顶层窗口
+type desktopWindowFinder struct{}
+
+// Windows 实现 screen.WindowFinder
+func (desktopWindowFinder) Windows() ([]screen.Window, error) {
+	windowEnumMutex.Lock()
+	defer windowEnumMutex.Unlock()
+
+	windowEnumResult = nil
+	ret, _, err := procEnumWindows.Call(windowEnumCallback, 0)
+	if ret == 0 {
+		return nil, fmt.Errorf("EnumWindows failed: %v", err)
+	}
+
+	found := windowEnumResult
+	windowEnumResult = nil
+	return found, nil
+}
+
+// windowEnumProc EnumWindows 回调，收集可见窗口
+func windowEnumProc(hwnd, lParam uintptr) uintptr {
+	visible, _, _ := procIsWindowVisible.Call(hwnd)
+	iconic, _, _ := procIsIconic.Call(hwnd)
+	if visible != 0 && iconic == 0 {
+		if window, ok := describeWindow(hwnd); ok {
+			windowEnumResult = append(windowEnumResult, window)
+		}
+	}
+	return 1 // 继续枚举
+}
+
+// rootWindowAt 返回屏幕坐标处的顶层窗口句柄，没有时返回 0
+// 只调用不发送消息的 API，可以在钩子回调中使用
+func rootWindowAt(pt POINT) uintptr {
+	// POINT 按值传递，64 位下打包为一个参数
+	packed := uintptr(uint32(pt.X)) | uintptr(uint32(pt.Y))<<32
+	hwnd, _, _ := procWindowFromPoint.Call(packed)
+	if hwnd == 0 {
+		return 0
+	}
+
+	root, _, _ := procGetAncestor.Call(hwnd, GA_ROOT)
+	if root == 0 {
+		root = hwnd
+	}
+	desktop, _, _ := procGetDesktopWindow.Call()
+	if root == desktop {
+		return 0
+	}
+	return root
+}
+
+// foregroundWindow 返回前台窗口句柄，没有前台窗口时返回 0
+func foregroundWindow() uintptr {
+	hwnd, _, _ := procGetForegroundWindow.Call()
+	return hwnd
+}
+
+// windowTitle 返回窗口的标题
+func windowTitle(hwnd uintptr) string {
+	title := make([]uint16, maxWindowTextLength)
+	procGetWindowText.Call(hwnd, uintptr(unsafe.Pointer(&title[0])), uintptr(len(title)))
+	return windows.UTF16ToString(title)
+}
+
+// describeWindow 读取窗口标题、类名和位置
+func describeWindow(hwnd uintptr) (screen.Window, bool) {
+	var rect RECT
+	ret, _, _ := procGetWindowRect.Call(hwnd, uintptr(unsafe.Pointer(&rect)))
+	if ret == 0 {
+		return screen.Window{}, false
+	}
+
+	title := make([]uint16, maxWindowTextLength)
+	procGetWindowText.Call(hwnd, uintptr(unsafe.Pointer(&title[0])), uintptr(len(title)))
+	class := make([]uint16, maxWindowTextLength)
+	procGetClassName.Call(hwnd, uintptr(unsafe.Pointer(&class[0])), uintptr(len(class)))
+
+	return screen.Window{
+		Title: windows.UTF16ToString(title),
+		Class: windows.UTF16ToString(class),
+		Rect: screen.Rect{
+			Left:   int(rect.Left),
+			Top:    int(rect.Top),
+			Width:  int(rect.Right - rect.Left),
+			Height: int(rect.Bottom - rect.Top),
+		},
+	}, true
+}
@@ -0,0 +1,40 @@
+// Package input 提供与平台无关的输入编码逻辑，供回放引擎组装 SendInput 参数
+package input
+
+import "unicode/utf16"
+
+// UnicodeStroke 一次 KEYEVENTF_UNICODE 按键：Unit 作为 wScan 发送
+type UnicodeStroke struct {
+	Unit uint16 // UTF-16 代码单元
+	Up   bool   // true 表示释放
+}
+
+// EncodeUnicode 把 UTF-8 文本编码为 Unicode 按键序列
+// 每个 UTF-16 代码单元依次按下、释放；BMP 以外的字符拆为代理对，由目标程序组合。
+// 换行统一转换为回车（\r），与在编辑框中按 Enter 的效果一致
+func EncodeUnicode(text string) []UnicodeStroke {
+	units := utf16.Encode([]rune(normalizeNewlines(text)))
+	strokes := make([]UnicodeStroke, 0, len(units)*2)
+	for _, unit := range units {
+		strokes = append(strokes,
+			UnicodeStroke{Unit: unit},
+			UnicodeStroke{Unit: unit, Up: true},
+		)
+	}
+	return strokes
+}
+
+// normalizeNewlines 把 \r\n 和 \n 转换为 \r
+func normalizeNewlines(text string) string {
+	runes := make([]rune, 0, len(text))
+	for i, r := range text {
+		if r == '\n' {
+			if i > 0 && text[i-1] == '\r' {
+				continue
+			}
+			r = '\r'
+		}
+		runes = append(runes, r)
+	}
+	return string(runes)
+}
@@ -0,0 +1,39 @@
+package input
+
+import (
+	"reflect"
+	"testing"
+)
+
+func pressed(units ...uint16) []UnicodeStroke {
+	strokes := make([]UnicodeStroke, 0, len(units)*2)
+	for _, unit := range units {
+		strokes = append(strokes, UnicodeStroke{Unit: unit}, UnicodeStroke{Unit: unit, Up: true})
+	}
+	return strokes
+}
+
+func TestEncodeUnicode(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []UnicodeStroke
+	}{
+		{name: "empty", text: "", want: []UnicodeStroke{}},
+		{name: "ascii", text: "Ab1", want: pressed('A', 'b', '1')},
+		{name: "chinese", text: "日报", want: pressed(0x65E5, 0x62A5)},
+		{name: "surrogate pair", text: "😀", want: pressed(0xD83D, 0xDE00)},
+		{name: "mixed surrogate", text: "a𠮷b", want: pressed('a', 0xD842, 0xDFB7, 'b')},
+		{name: "newline", text: "a\nb", want: pressed('a', '\r', 'b')},
+		{name: "crlf", text: "a\r\nb", want: pressed('a', '\r', 'b')},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := EncodeUnicode(tt.text)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("EncodeUnicode(%q) = %v, want %v", tt.text, got, tt.want)
+			}
+		})
+	}
+}
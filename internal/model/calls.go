@@ -0,0 +1,83 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+)
+
+// TaskLoader 按名称加载任务库中的任务，任务不存在时返回 nil, nil
+type TaskLoader func(name string) (*TaskData, error)
+
+// ResolveCalls 从 root 出发加载它直接或间接调用的全部任务（不含 root 本身）
+// 调用了不存在的任务和循环调用作为错误级别的检查结果返回，位置为 call 步骤所在的任务和路径；
+// 加载失败（如任务已加密、文件损坏）时返回 error
+func ResolveCalls(root string, task *TaskData, load TaskLoader) (map[string]*TaskData, []Finding, error) {
+	r := &callResolver{load: load, tasks: make(map[string]*TaskData), missing: make(map[string]bool)}
+	if err := r.visit(root, task, []string{root}); err != nil {
+		return nil, nil, err
+	}
+	return r.tasks, r.findings, nil
+}
+
+// callResolver 保存一次 ResolveCalls 的上下文和结果
+type callResolver struct {
+	validator
+	load    TaskLoader
+	tasks   map[string]*TaskData // 已加载的被调用任务
+	missing map[string]bool      // 已确认不存在的任务
+}
+
+// visit 检查 task 中的 call 步骤并递归加载被调用的任务，stack 为当前的调用链（含 name）
+func (r *callResolver) visit(name string, task *TaskData, stack []string) error {
+	var err error
+	walkSteps("events", task.Events, func(path string, event *Event) {
+		if err != nil || event.Type != EventCall || event.Task == "" {
+			return
+		}
+		if len(stack) > 1 {
+			path = name + ":" + path
+		}
+		err = r.call(path, event.Task, stack)
+	})
+	return err
+}
+
+// call 处理位于 path 的一次调用
+func (r *callResolver) call(path, callee string, stack []string) error {
+	for i, caller := range stack {
+		if caller == callee {
+			cycle := append(append([]string(nil), stack[i:]...), callee)
+			r.add(SeverityError, CheckCallCycle, path, "call cycle %s", strings.Join(cycle, " -> "))
+			return nil
+		}
+	}
+	if r.missing[callee] {
+		r.add(SeverityError, CheckMissingTask, path, "called task %q does not exist", callee)
+		return nil
+	}
+	if _, loaded := r.tasks[callee]; loaded {
+		return nil
+	}
+
+	task, err := r.load(callee)
+	if err != nil {
+		return fmt.Errorf("failed to load called task %s: %w", callee, err)
+	}
+	if task == nil {
+		r.missing[callee] = true
+		r.add(SeverityError, CheckMissingTask, path, "called task %q does not exist", callee)
+		return nil
+	}
+	r.tasks[callee] = task
+	return r.visit(callee, task, append(stack, callee))
+}
+
+// walkSteps 按先后顺序访问 steps 中的全部事件，fn 的 path 为事件的位置（如 "events[3].steps[2]"、"events[4].else[0]"）
+func walkSteps(path string, steps []Event, fn func(path string, event *Event)) {
+	for i := range steps {
+		stepPath := fmt.Sprintf("%s[%d]", path, i)
+		fn(stepPath, &steps[i])
+		walkSteps(stepPath+".steps", steps[i].Steps, fn)
+		walkSteps(stepPath+".else", steps[i].Else, fn)
+	}
+}
@@ -0,0 +1,74 @@
+package model
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestResolveCalls(t *testing.T) {
+	call := func(name string) Event { return Event{Type: EventCall, Task: name} }
+	library := map[string]*TaskData{
+		"登录": {Events: []Event{{Type: EventTypeText, Text: "user"}}},
+		"导出": {Events: []Event{call("登录"), {Type: EventRepeat, Count: 2, Steps: []Event{call("归档")}}}},
+		"日报": {Events: []Event{call("登录"), call("导出"), {Type: EventIf, Cond: "is_workday", Else: []Event{call("缺失")}}}},
+		"甲":  {Events: []Event{call("乙")}},
+		"乙":  {Events: []Event{call("登录"), call("甲")}},
+	}
+	var loaded []string
+	load := func(name string) (*TaskData, error) {
+		loaded = append(loaded, name)
+		if name == "加密" {
+			return nil, errors.New("passphrase required")
+		}
+		return library[name], nil
+	}
+
+	tests := []struct {
+		root      string
+		wantTasks []string
+		want      []string
+	}{
+		{"登录", nil, nil},
+		{"日报", []string{"导出", "登录"}, []string{
+			"error missing_task 导出:events[1].steps[0]",
+			"error missing_task events[2].else[0]",
+		}},
+		{"甲", []string{"乙", "登录"}, []string{"error call_cycle 乙:events[1]"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.root, func(t *testing.T) {
+			loaded = nil
+			tasks, findings, err := ResolveCalls(tt.root, library[tt.root], load)
+			if err != nil {
+				t.Fatalf("ResolveCalls() error = %v", err)
+			}
+			var names []string
+			for name := range tasks {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			if !reflect.DeepEqual(names, tt.wantTasks) {
+				t.Errorf("ResolveCalls() tasks = %v, want %v", names, tt.wantTasks)
+			}
+			if got := findingKeys(findings); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ResolveCalls() findings = %q, want %q", got, tt.want)
+			}
+			// 每个任务只加载一次
+			seen := make(map[string]bool)
+			for _, name := range loaded {
+				if seen[name] {
+					t.Errorf("task %s loaded more than once: %v", name, loaded)
+				}
+				seen[name] = true
+			}
+		})
+	}
+
+	// 加载失败不是检查结果，原样返回
+	task := &TaskData{Events: []Event{call("加密")}}
+	if _, _, err := ResolveCalls("周报", task, load); err == nil {
+		t.Error("ResolveCalls() error = nil for a task that cannot be loaded")
+	}
+}
@@ -0,0 +1,130 @@
+package model
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/Knetic/govaluate.v3"
+)
+
+// 条件表达式：if 步骤的 Cond 按 govaluate 的语法求值，如
+//
+//	weekday == 1 || is_month_end
+//	i % 2 == 0 && 金额 > 1000
+//	!found
+//
+// 可以使用下面的日期变量，以及自定义变量、数据文件的列、repeat 的计数变量和 wait_window 的结果；
+// 看起来是数字的值按数字比较，"true" / "false" 按布尔值，其余按字符串（如 [客户] == "华东"）
+
+// conditionFunctions 条件中内置的日期变量，以回放开始的时刻为准
+var conditionFunctions = map[string]func(now time.Time) interface{}{
+	"year":            func(now time.Time) interface{} { return float64(now.Year()) },
+	"month":           func(now time.Time) interface{} { return float64(now.Month()) },
+	"day":             func(now time.Time) interface{} { return float64(now.Day()) },
+	"weekday":         func(now time.Time) interface{} { return float64(isoWeekday(now)) },
+	"is_weekend":      func(now time.Time) interface{} { return !isWorkday(now) },
+	"is_workday":      func(now time.Time) interface{} { return isWorkday(now) },
+	"is_month_end":    func(now time.Time) interface{} { return now.AddDate(0, 0, 1).Month() != now.Month() },
+	"is_last_workday": func(now time.Time) interface{} { return isLastWorkday(now) },
+}
+
+// Evaluate 求条件表达式的值，结果必须是布尔值
+func (v Variables) Evaluate(cond string) (bool, error) {
+	expression, err := parseCondition(cond)
+	if err != nil {
+		return false, err
+	}
+
+	parameters := make(map[string]interface{})
+	for _, name := range expression.Vars() {
+		if value, ok := v.Values[name]; ok {
+			parameters[name] = conditionValue(value)
+		} else if fn, ok := conditionFunctions[name]; ok {
+			parameters[name] = fn(v.Now)
+		} else {
+			return false, fmt.Errorf("%w: %s", ErrUndefinedVariable, name)
+		}
+	}
+
+	result, err := expression.Evaluate(parameters)
+	if err != nil {
+		return false, fmt.Errorf("condition %q: %w", cond, err)
+	}
+	matched, ok := result.(bool)
+	if !ok {
+		return false, fmt.Errorf("condition %q is %v, not true or false", cond, result)
+	}
+	return matched, nil
+}
+
+// ConditionVariables 检查条件的语法，返回其中引用的变量名（按出现顺序，不含内置变量，不重复）
+func ConditionVariables(cond string) ([]string, error) {
+	expression, err := parseCondition(cond)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	seen := make(map[string]bool)
+	for _, name := range expression.Vars() {
+		if _, builtin := conditionFunctions[name]; builtin || seen[name] {
+			continue
+		}
+		seen[name] = true
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+// parseCondition 解析条件表达式
+func parseCondition(cond string) (*govaluate.EvaluableExpression, error) {
+	if strings.TrimSpace(cond) == "" {
+		return nil, fmt.Errorf("condition is empty")
+	}
+	expression, err := govaluate.NewEvaluableExpression(cond)
+	if err != nil {
+		return nil, fmt.Errorf("invalid condition %q: %w", cond, err)
+	}
+	return expression, nil
+}
+
+// decimalPattern 条件中按数字比较的文本：可带符号和小数部分的十进制数，整数部分没有多余的前导零
+// "007" 这样的编号、"NaN"、"Inf"、"0x1A"、"1e3" 等都按字符串比较
+var decimalPattern = regexp.MustCompile(`^[+-]?(0|[1-9][0-9]*)(\.[0-9]+)?$`)
+
+// conditionValue 把变量的文本值转换为条件中的数字、布尔值或字符串
+func conditionValue(value string) interface{} {
+	trimmed := strings.TrimSpace(value)
+	if decimalPattern.MatchString(trimmed) {
+		if n, err := strconv.ParseFloat(trimmed, 64); err == nil {
+			return n
+		}
+	}
+	switch strings.ToLower(trimmed) {
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+	return value
+}
+
+// isoWeekday 返回星期几，周一为 1，周日为 7
+func isoWeekday(t time.Time) int {
+	if t.Weekday() == time.Sunday {
+		return 7
+	}
+	return int(t.Weekday())
+}
+
+// isWorkday 判断是否为工作日（周一至周五，不识别节假日）
+func isWorkday(t time.Time) bool {
+	return t.Weekday() != time.Saturday && t.Weekday() != time.Sunday
+}
+
+// isLastWorkday 判断是否为本月最后一个工作日
+func isLastWorkday(t time.Time) bool {
+	return isWorkday(t) && addWorkdays(t, 1).Month() != t.Month()
+}
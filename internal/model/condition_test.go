@@ -0,0 +1,101 @@
+package model
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestEvaluate(t *testing.T) {
+	// 2024-11-29 是周五，也是 11 月最后一个工作日
+	vars := Variables{
+		Now:    time.Date(2024, 11, 29, 8, 0, 0, 0, time.Local),
+		Values: map[string]string{"i": "3", "客户": "华东", "found": "true", "金额": " 1200.5 ", "day": "15",
+			"code": "007", "nan": "NaN", "inf": "Inf", "infinity": "infinity", "hex": "0x1A", "exp": "1e3", "neg": "-2.5"},
+	}
+
+	tests := []struct {
+		cond    string
+		want    bool
+		wantErr bool
+	}{
+		{"weekday == 5", true, false},
+		{"weekday == 1 || is_month_end", false, false},
+		{"is_last_workday && is_workday && !is_weekend", true, false},
+		{"year == 2024 && month == 11", true, false},
+		{"day == 15", true, false}, // 自定义变量优先于同名的内置变量
+		{"i % 2 == 1 && 金额 > 1000", true, false},
+		{`[客户] == "华东"`, true, false},
+		{"found", true, false},
+		// 只有普通的十进制数按数字比较，其余保持字符串
+		{`code == "007"`, true, false},
+		{"code == 7", false, false},
+		{`nan == "NaN" && inf == "Inf" && infinity == "infinity"`, true, false},
+		{`hex == "0x1A" && exp == "1e3"`, true, false},
+		{"neg < -2 && 金额 == 1200.5", true, false},
+		{"!found || i >= 4", false, false},
+		{"i + 1", false, true},
+		{"undefined_name == 1", false, true},
+		{"weekday ==", false, true},
+		{"  ", false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.cond, func(t *testing.T) {
+			got, err := vars.Evaluate(tt.cond)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Evaluate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Evaluate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := vars.Evaluate("网点 == 1"); !errors.Is(err, ErrUndefinedVariable) {
+		t.Errorf("Evaluate() error = %v, want ErrUndefinedVariable", err)
+	}
+}
+
+func TestEvaluateCalendar(t *testing.T) {
+	tests := []struct {
+		date string
+		cond string
+		want bool
+	}{
+		{"2024-12-01", "weekday == 7 && is_weekend", true},
+		{"2024-12-02", "weekday == 1 && is_workday", true},
+		{"2024-12-31", "is_month_end && is_last_workday", true},
+		{"2024-11-30", "is_month_end && !is_last_workday", true}, // 周六
+		{"2024-02-29", "is_month_end", true},
+		{"2024-02-28", "is_month_end", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.date+" "+tt.cond, func(t *testing.T) {
+			now, err := time.ParseInLocation("2006-01-02", tt.date, time.Local)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := (Variables{Now: now}).Evaluate(tt.cond)
+			if err != nil || got != tt.want {
+				t.Errorf("Evaluate() = %v, %v, want %v", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestConditionVariables(t *testing.T) {
+	got, err := ConditionVariables(`weekday == 1 && (i > 2 || [客户] == "华东") && i < 10 && found`)
+	if err != nil {
+		t.Fatalf("ConditionVariables() error = %v", err)
+	}
+	if want := []string{"i", "客户", "found"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ConditionVariables() = %v, want %v", got, want)
+	}
+
+	for _, cond := range []string{"", "(i > 1", "i >> >> 2"} {
+		if _, err := ConditionVariables(cond); err == nil {
+			t.Errorf("ConditionVariables(%q) error = nil, want syntax error", cond)
+		}
+	}
+}
@@ -1,27 +1,120 @@
 package model
 
-// Config 表示应用配置结构（对应 config.json）
-type Config struct {
+import (
+	"fmt"
+	"time"
+)
+
+// 分辨率策略：回放时屏幕分辨率与录制时不同的处理方式
+const (
+	ResolutionScale  = "scale"  // 按比例缩放坐标
+	ResolutionStrict = "strict" // 拒绝回放
+)
+
+// TaskSchedule 表示单个任务的定时与回放设置
+type TaskSchedule struct {
 	ScheduleTime string  `json:"schedule_time"` // 定时执行时间（格式："08:30"）
 	IsEnabled    bool    `json:"is_enabled"`    // 是否启用定时任务
-	AutoStart    bool    `json:"auto_start"`    // 是否开机自启动
 	SpeedFactor  float64 `json:"speed_factor"`  // 播放速度因子（0.5=慢速, 1.0=原速）
 	LastRunDate  string  `json:"last_run_date"` // 上次运行日期（格式："2023-12-01"）
+	DataFile     string  `json:"data_file"`     // 数据驱动回放的 CSV/TSV 文件，为空时只回放一次；相对路径相对于数据目录
 }
 
-// NewConfig 创建一个新的默认配置
-func NewConfig() *Config {
-	return &Config{
+// Config 表示应用配置结构（对应 config.json）
+type Config struct {
+	AutoStart        bool                     `json:"auto_start"`        // 是否开机自启动
+	CurrentTask      string                   `json:"current_task"`      // 当前选中的任务名称（录制和手动回放的对象）
+	ResolutionPolicy string                   `json:"resolution_policy"` // 分辨率策略: "scale", "strict"
+	PathTolerance    float64                  `json:"path_tolerance"`    // 录制优化时鼠标路径简化容差（像素），0 表示不简化
+	SensitiveWindows []string                 `json:"sensitive_windows"` // 标题包含这些关键字（不区分大小写）的窗口中的按键录制为敏感输入
+	Variables        map[string]string        `json:"variables"`         // 输入文本中 {{名称}} 引用的自定义变量，如网点代码
+	Tasks            map[string]*TaskSchedule `json:"tasks"`             // 各任务的定时与回放设置，键为任务名称
+}
+
+// NewTaskSchedule 创建一个新的默认任务设置
+func NewTaskSchedule() *TaskSchedule {
+	return &TaskSchedule{
 		ScheduleTime: "08:30",
 		IsEnabled:    false,
-		AutoStart:    false,
 		SpeedFactor:  1.0,
 		LastRunDate:  "",
 	}
 }
 
-// HasTaskToday 检查今天是否已经运行过任务
-func (c *Config) HasTaskToday(today string) bool {
-	return c.LastRunDate == today
+// NewConfig 创建一个新的默认配置
+func NewConfig() *Config {
+	return &Config{
+		AutoStart:        false,
+		CurrentTask:      "",
+		ResolutionPolicy: ResolutionScale,
+		PathTolerance:    DefaultPathTolerance,
+		Tasks:            make(map[string]*TaskSchedule),
+	}
+}
+
+// HasRunToday 检查今天是否已经运行过任务
+func (s *TaskSchedule) HasRunToday(today string) bool {
+	return s.LastRunDate == today
+}
+
+// NextRun 返回 now 所在日期的计划执行时刻
+func (s *TaskSchedule) NextRun(now time.Time) (time.Time, error) {
+	scheduleTime, err := time.Parse("15:04", s.ScheduleTime)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid schedule time %q: %w", s.ScheduleTime, err)
+	}
+	return time.Date(
+		now.Year(), now.Month(), now.Day(),
+		scheduleTime.Hour(), scheduleTime.Minute(), 0, 0,
+		now.Location(),
+	), nil
+}
+
+// IsDue 检查任务在 now 时刻是否应该执行：已启用、今天未运行且已到执行时间
+func (s *TaskSchedule) IsDue(now time.Time) (bool, error) {
+	if !s.IsEnabled || s.HasRunToday(now.Format("2006-01-02")) {
+		return false, nil
+	}
+	target, err := s.NextRun(now)
+	if err != nil {
+		return false, err
+	}
+	return !now.Before(target), nil
+}
+
+// Schedule 返回任务的设置，不存在时创建默认设置
+func (c *Config) Schedule(taskName string) *TaskSchedule {
+	if c.Tasks == nil {
+		c.Tasks = make(map[string]*TaskSchedule)
+	}
+	schedule, ok := c.Tasks[taskName]
+	if !ok {
+		schedule = NewTaskSchedule()
+		c.Tasks[taskName] = schedule
+	}
+	return schedule
+}
+
+// RenameTask 任务重命名后同步迁移其设置
+func (c *Config) RenameTask(oldName, newName string) {
+	if schedule, ok := c.Tasks[oldName]; ok {
+		delete(c.Tasks, oldName)
+		c.Tasks[newName] = schedule
+	}
+	if c.CurrentTask == oldName {
+		c.CurrentTask = newName
+	}
 }
 
+// RemoveTask 任务删除后移除其设置
+func (c *Config) RemoveTask(taskName string) {
+	delete(c.Tasks, taskName)
+	if c.CurrentTask == taskName {
+		c.CurrentTask = ""
+	}
+}
+
+// OptimizeOptions 返回配置对应的录制优化参数
+func (c *Config) OptimizeOptions() OptimizeOptions {
+	return OptimizeOptions{PathTolerance: c.PathTolerance}
+}
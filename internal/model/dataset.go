@@ -0,0 +1,93 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrInvalidDataSet 数据文件的表头或数据行不符合要求
+var ErrInvalidDataSet = errors.New("invalid data set")
+
+// DataSet 数据驱动回放的数据：表头的每一列是一个变量名，任务按数据行逐行回放
+// 每一行回放时，输入文本中的 {{列名}} 替换为这一行对应列的值
+type DataSet struct {
+	Columns []string   // 表头，即模板中引用的变量名
+	Rows    [][]string // 数据行，列数与表头相同
+	Lines   []int      // 每个数据行在数据文件中的行号（从 1 开始），报告结果时与用户编辑的文件对应
+}
+
+// NewDataSet 以第一条记录为表头创建数据集，每条记录在文件中占一行
+func NewDataSet(records [][]string) (*DataSet, error) {
+	lines := make([]int, len(records))
+	for i := range lines {
+		lines[i] = i + 1
+	}
+	return NewDataSetWithLines(records, lines)
+}
+
+// NewDataSetWithLines 以第一条记录为表头创建数据集，lines 为每条记录开始的行号，跳过全部为空的行
+// 表头的列名必须是合法且不重复的变量名；数据行比表头短时补空值，比表头长时报错
+func NewDataSetWithLines(records [][]string, lines []int) (*DataSet, error) {
+	if len(records) == 0 {
+		return nil, fmt.Errorf("%w: missing header row", ErrInvalidDataSet)
+	}
+
+	data := &DataSet{}
+	seen := make(map[string]bool)
+	for i, name := range records[0] {
+		name = strings.TrimSpace(name)
+		if !isVariableName(name) {
+			return nil, fmt.Errorf("%w: column %d: invalid variable name %q", ErrInvalidDataSet, i+1, name)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("%w: column %d: duplicate name %q", ErrInvalidDataSet, i+1, name)
+		}
+		seen[name] = true
+		data.Columns = append(data.Columns, name)
+	}
+
+	for i, record := range records[1:] {
+		if isBlankRecord(record) {
+			continue
+		}
+		line := lines[i+1]
+		if len(record) > len(data.Columns) {
+			return nil, fmt.Errorf("%w: line %d has %d fields, header has %d",
+				ErrInvalidDataSet, line, len(record), len(data.Columns))
+		}
+		row := make([]string, len(data.Columns))
+		copy(row, record)
+		data.Rows = append(data.Rows, row)
+		data.Lines = append(data.Lines, line)
+	}
+	return data, nil
+}
+
+// Values 返回第 row 行（从 0 开始）的变量表
+func (d *DataSet) Values(row int) map[string]string {
+	values := make(map[string]string, len(d.Columns))
+	for i, name := range d.Columns {
+		values[name] = d.Rows[row][i]
+	}
+	return values
+}
+
+// Placeholders 返回每一列都为空值的变量表，用于回放前检查任务引用的变量是否都有定义
+func (d *DataSet) Placeholders() map[string]string {
+	values := make(map[string]string, len(d.Columns))
+	for _, name := range d.Columns {
+		values[name] = ""
+	}
+	return values
+}
+
+// isBlankRecord 判断一条记录是否全部为空（Excel 导出时常在末尾留下空行）
+func isBlankRecord(record []string) bool {
+	for _, field := range record {
+		if strings.TrimSpace(field) != "" {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,42 @@
+package model
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestNewDataSet(t *testing.T) {
+	data, err := NewDataSet([][]string{
+		{" 单号 ", "金额", "备注"},
+		{"A1", "10", "加急"},
+		{"", " ", ""},
+		{"A2", "20"},
+	})
+	if err != nil {
+		t.Fatalf("NewDataSet() error = %v", err)
+	}
+
+	// 表头去掉空白，空行跳过，缺少的列补空值
+	if want := []string{"单号", "金额", "备注"}; !reflect.DeepEqual(data.Columns, want) {
+		t.Errorf("Columns = %q, want %q", data.Columns, want)
+	}
+	if len(data.Rows) != 2 {
+		t.Fatalf("Rows = %q, want 2 rows", data.Rows)
+	}
+	if want := []int{2, 4}; !reflect.DeepEqual(data.Lines, want) {
+		t.Errorf("Lines = %v, want %v", data.Lines, want)
+	}
+	if want := map[string]string{"单号": "A2", "金额": "20", "备注": ""}; !reflect.DeepEqual(data.Values(1), want) {
+		t.Errorf("Values(1) = %v, want %v", data.Values(1), want)
+	}
+	if want := map[string]string{"单号": "", "金额": "", "备注": ""}; !reflect.DeepEqual(data.Placeholders(), want) {
+		t.Errorf("Placeholders() = %v, want %v", data.Placeholders(), want)
+	}
+
+	for _, records := range [][][]string{nil, {{"单号", ""}}, {{"a b"}}, {{"x", "x"}}, {{"x"}, {"1", "2"}}} {
+		if _, err := NewDataSet(records); !errors.Is(err, ErrInvalidDataSet) {
+			t.Errorf("NewDataSet(%q) error = %v, want ErrInvalidDataSet", records, err)
+		}
+	}
+}
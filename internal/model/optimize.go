@@ -0,0 +1,235 @@
+package model
+
+import "math"
+
+// ClickTolerance 按下与释放之间允许的最大位移（像素），不超过该值视为一次点击而非拖拽
+const ClickTolerance = 3
+
+// CollapseClicks 把同一位置的 mouse_down + mouse_up 合并为 mouse_click
+// 两者之间只允许出现未超出 ClickTolerance 的 mouse_move；
+// 被移除事件的延迟累加到下一个事件上，保证后续事件的执行时刻不变；
+// 以点击结尾时没有下一个事件，剩余的延迟累加到最后一个事件上，保证总时长不变
+func CollapseClicks(events []Event) []Event {
+	result := make([]Event, 0, len(events))
+	carry := 0
+
+	for i := 0; i < len(events); i++ {
+		event := events[i]
+
+		if event.Type == EventMouseDown {
+			if end, ok := findClickRelease(events, i); ok {
+				click := event
+				click.Type = EventMouseClick
+				click.Delay += carry
+				result = append(result, click)
+
+				carry = 0
+				for j := i + 1; j <= end; j++ {
+					carry += events[j].Delay
+				}
+				i = end
+				continue
+			}
+		}
+
+		event.Delay += carry
+		carry = 0
+		result = append(result, event)
+	}
+
+	if carry > 0 && len(result) > 0 {
+		result[len(result)-1].Delay += carry
+	}
+	return result
+}
+
+// findClickRelease 查找与 events[down] 配对且位置不变的 mouse_up，返回其下标
+func findClickRelease(events []Event, down int) (int, bool) {
+	press := events[down]
+	for j := down + 1; j < len(events); j++ {
+		event := events[j]
+		switch event.Type {
+		case EventMouseMove:
+			if !withinTolerance(press, event, ClickTolerance) {
+				return 0, false
+			}
+		case EventMouseUp:
+			return j, event.Button == press.Button && withinTolerance(press, event, ClickTolerance)
+		default:
+			return 0, false
+		}
+	}
+	return 0, false
+}
+
+// withinTolerance 判断两个事件的坐标在两个方向上的偏差是否都不超过 tolerance
+func withinTolerance(a, b Event, tolerance int) bool {
+	return abs(a.X-b.X) <= tolerance && abs(a.Y-b.Y) <= tolerance
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+// DefaultPathTolerance 鼠标路径简化的默认容差（像素）
+const DefaultPathTolerance = 2.0
+
+// OptimizeOptions 录制优化参数
+type OptimizeOptions struct {
+	PathTolerance float64 // 路径简化容差（像素），偏离直线不超过该值的移动点会被删除；<=0 表示不简化路径
+}
+
+// Optimize 压缩录制数据，返回删除的事件数量：
+//  1. 原地的按下+释放合并为点击
+//  2. 丢弃不通向点击、拖拽或滚轮的鼠标移动（如打字前的随手移动）
+//  3. 保留的移动路径用 Ramer–Douglas–Peucker 算法简化
+//
+// 被删除事件的延迟并入下一个保留的事件，因此保留事件的执行时刻和任务总时长都不变；
+// 末尾没有后续动作的移动只保留最后一个，用来承载剩余的等待时间
+//
+// 块中的步骤（含 if 的 else 分支）分别按同样的规则压缩
+func (t *TaskData) Optimize(opts OptimizeOptions) int {
+	before := CountEvents(t.Events)
+	t.Events = optimizeEvents(t.Events, opts)
+	t.Meta.TotalEvents = len(t.Events)
+	return before - CountEvents(t.Events)
+}
+
+// optimizeEvents 压缩同一层级的事件，返回新的事件列表
+func optimizeEvents(events []Event, opts OptimizeOptions) []Event {
+	events = CollapseClicks(events)
+	for i := range events {
+		if events[i].IsBlock() {
+			events[i].Steps = optimizeEvents(events[i].Steps, opts)
+			if len(events[i].Else) > 0 {
+				events[i].Else = optimizeEvents(events[i].Else, opts)
+			}
+		}
+	}
+
+	result := make([]Event, 0, len(events))
+	carry := 0
+
+	for i := 0; i < len(events); {
+		if events[i].Type != EventMouseMove {
+			event := events[i]
+			event.Delay += carry
+			carry = 0
+			result = append(result, event)
+			i++
+			continue
+		}
+
+		// 找出连续的一段移动，以及紧随其后的动作
+		end := i
+		for end < len(events) && events[end].Type == EventMouseMove {
+			end++
+		}
+		run := events[i:end]
+
+		var keep []bool
+		switch {
+		case end == len(events):
+			// 末尾的移动：只保留最后一个
+			keep = make([]bool, len(run))
+			keep[len(run)-1] = true
+		case isPointerAction(events[end].Type):
+			keep = simplifyPath(run, events[end], opts.PathTolerance)
+		case events[end].IsBlock() || events[end].Type == EventCall:
+			// 块或被调用任务的第一步可能依赖光标位置，路径原样保留
+			keep = make([]bool, len(run))
+			for j := range keep {
+				keep[j] = true
+			}
+		default:
+			// 后面是键盘等与光标位置无关的动作，整段丢弃
+			keep = make([]bool, len(run))
+		}
+
+		for j, event := range run {
+			if !keep[j] {
+				carry += event.Delay
+				continue
+			}
+			event.Delay += carry
+			carry = 0
+			result = append(result, event)
+		}
+		i = end
+	}
+
+	return result
+}
+
+// isPointerAction 判断事件是否依赖光标位置（其前面的移动路径需要保留）
+func isPointerAction(eventType string) bool {
+	switch eventType {
+	case EventMouseClick, EventMouseDown, EventMouseUp, EventMouseWheel:
+		return true
+	default:
+		return false
+	}
+}
+
+// simplifyPath 简化一段移动路径，返回每个移动点是否保留
+// 路径终点为随后动作的位置（动作本身总会保留），起点为第一个移动点
+func simplifyPath(run []Event, target Event, tolerance float64) []bool {
+	keep := make([]bool, len(run))
+	if tolerance <= 0 {
+		for i := range keep {
+			keep[i] = true
+		}
+		return keep
+	}
+
+	points := make([]point, 0, len(run)+1)
+	for _, event := range run {
+		points = append(points, point{float64(event.X), float64(event.Y)})
+	}
+	points = append(points, point{float64(target.X), float64(target.Y)})
+
+	marked := make([]bool, len(points))
+	marked[0] = true
+	marked[len(points)-1] = true
+	douglasPeucker(points, 0, len(points)-1, tolerance, marked)
+
+	copy(keep, marked[:len(run)])
+	return keep
+}
+
+// point 平面上的点
+type point struct {
+	x, y float64
+}
+
+// douglasPeucker 标记 points[first..last] 之间需要保留的点
+func douglasPeucker(points []point, first, last int, tolerance float64, marked []bool) {
+	if last-first < 2 {
+		return
+	}
+
+	maxDistance, index := 0.0, first
+	for i := first + 1; i < last; i++ {
+		if d := perpendicularDistance(points[i], points[first], points[last]); d > maxDistance {
+			maxDistance, index = d, i
+		}
+	}
+
+	if maxDistance > tolerance {
+		marked[index] = true
+		douglasPeucker(points, first, index, tolerance, marked)
+		douglasPeucker(points, index, last, tolerance, marked)
+	}
+}
+
+// perpendicularDistance 计算点 p 到线段 a-b 所在直线的距离，a 与 b 重合时为到 a 的距离
+func perpendicularDistance(p, a, b point) float64 {
+	dx, dy := b.x-a.x, b.y-a.y
+	if dx == 0 && dy == 0 {
+		return math.Hypot(p.x-a.x, p.y-a.y)
+	}
+	return math.Abs(dy*p.x-dx*p.y+b.x*a.y-b.y*a.x) / math.Hypot(dx, dy)
+}
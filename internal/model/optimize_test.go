@@ -0,0 +1,231 @@
+package model
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func mouseEvent(eventType string, x, y int, button string, delay int) Event {
+	return Event{Type: eventType, X: x, Y: y, Button: button, Delay: delay}
+}
+
+func TestCollapseClicks(t *testing.T) {
+	tests := []struct {
+		name   string
+		events []Event
+		want   []Event
+	}{
+		{
+			name: "down and up at same spot become a click",
+			events: []Event{
+				mouseEvent(EventMouseDown, 100, 100, "left", 50),
+				mouseEvent(EventMouseUp, 100, 100, "left", 80),
+				mouseEvent(EventMouseMove, 200, 200, "none", 40),
+			},
+			want: []Event{
+				mouseEvent(EventMouseClick, 100, 100, "left", 50),
+				mouseEvent(EventMouseMove, 200, 200, "none", 120),
+			},
+		},
+		{
+			name: "jitter within tolerance is still a click",
+			events: []Event{
+				mouseEvent(EventMouseDown, 100, 100, "right", 0),
+				mouseEvent(EventMouseMove, 102, 99, "none", 20),
+				mouseEvent(EventMouseUp, 101, 101, "right", 30),
+				{Type: EventKeyDown, Button: "none", KeyCode: 13, Delay: 10},
+			},
+			want: []Event{
+				mouseEvent(EventMouseClick, 100, 100, "right", 0),
+				{Type: EventKeyDown, Button: "none", KeyCode: 13, Delay: 60},
+			},
+		},
+		{
+			name: "click at the end keeps the hold time",
+			events: []Event{
+				{Type: EventKeyDown, Button: "none", KeyCode: 13, Delay: 10},
+				mouseEvent(EventMouseDown, 100, 100, "left", 50),
+				mouseEvent(EventMouseMove, 101, 100, "none", 20),
+				mouseEvent(EventMouseUp, 100, 100, "left", 80),
+			},
+			want: []Event{
+				{Type: EventKeyDown, Button: "none", KeyCode: 13, Delay: 10},
+				mouseEvent(EventMouseClick, 100, 100, "left", 150),
+			},
+		},
+		{
+			name: "drag keeps down, moves and up",
+			events: []Event{
+				mouseEvent(EventMouseDown, 100, 100, "left", 0),
+				mouseEvent(EventMouseMove, 150, 120, "none", 50),
+				mouseEvent(EventMouseUp, 300, 200, "left", 50),
+			},
+			want: []Event{
+				mouseEvent(EventMouseDown, 100, 100, "left", 0),
+				mouseEvent(EventMouseMove, 150, 120, "none", 50),
+				mouseEvent(EventMouseUp, 300, 200, "left", 50),
+			},
+		},
+		{
+			name: "keystroke between down and up is kept",
+			events: []Event{
+				mouseEvent(EventMouseDown, 10, 10, "left", 0),
+				{Type: EventKeyDown, Button: "none", KeyCode: 17, Delay: 5},
+				mouseEvent(EventMouseUp, 10, 10, "left", 5),
+			},
+			want: []Event{
+				mouseEvent(EventMouseDown, 10, 10, "left", 0),
+				{Type: EventKeyDown, Button: "none", KeyCode: 17, Delay: 5},
+				mouseEvent(EventMouseUp, 10, 10, "left", 5),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := CollapseClicks(tt.events)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CollapseClicks() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// line 生成从 (x0,y0) 到 (x1,y1) 的 n 个等间隔移动事件
+func line(x0, y0, x1, y1, n, delay int) []Event {
+	events := make([]Event, 0, n)
+	for i := 1; i <= n; i++ {
+		x := x0 + (x1-x0)*i/n
+		y := y0 + (y1-y0)*i/n
+		events = append(events, mouseEvent(EventMouseMove, x, y, "none", delay))
+	}
+	return events
+}
+
+// timeline 返回每个非移动事件的 (类型, 坐标, 执行时刻)，以及任务总时长
+func timeline(events []Event) ([]string, int) {
+	var actions []string
+	elapsed := 0
+	for _, event := range events {
+		elapsed += event.Delay
+		if event.Type != EventMouseMove {
+			actions = append(actions, fmt.Sprintf("%s %s %d,%d @%d", event.Type, event.Button, event.X, event.Y, elapsed))
+		}
+	}
+	return actions, elapsed
+}
+
+func TestOptimizeKeepsActionsAndTiming(t *testing.T) {
+	var events []Event
+	// 移向按钮并点击
+	events = append(events, line(0, 0, 400, 300, 20, 50)...)
+	events = append(events, mouseEvent(EventMouseDown, 400, 300, "left", 30))
+	events = append(events, mouseEvent(EventMouseUp, 400, 300, "left", 80))
+	// 打字前随手晃动鼠标
+	events = append(events, line(400, 300, 420, 500, 10, 50)...)
+	events = append(events, Event{Type: EventKeyDown, Button: "none", KeyCode: 65, Delay: 20})
+	events = append(events, Event{Type: EventKeyUp, Button: "none", KeyCode: 65, Delay: 60})
+	// 折线拖拽
+	events = append(events, mouseEvent(EventMouseDown, 100, 100, "left", 200))
+	events = append(events, line(100, 100, 300, 100, 10, 50)...)
+	events = append(events, line(300, 100, 300, 300, 10, 50)...)
+	events = append(events, mouseEvent(EventMouseUp, 300, 300, "left", 40))
+	// 结束前的移动
+	events = append(events, line(300, 300, 0, 0, 5, 50)...)
+
+	task := &TaskData{Meta: TaskMeta{TotalEvents: len(events)}, Events: events}
+	wantActions, wantTotal := timeline(CollapseClicks(events))
+
+	removed := task.Optimize(OptimizeOptions{PathTolerance: DefaultPathTolerance})
+
+	gotActions, gotTotal := timeline(task.Events)
+	if !reflect.DeepEqual(gotActions, wantActions) {
+		t.Errorf("actions = %v, want %v", gotActions, wantActions)
+	}
+	if gotTotal != wantTotal {
+		t.Errorf("total duration = %dms, want %dms", gotTotal, wantTotal)
+	}
+	if removed != len(events)-len(task.Events) {
+		t.Errorf("removed = %d, events %d → %d", removed, len(events), len(task.Events))
+	}
+	if task.Meta.TotalEvents != len(task.Events) {
+		t.Errorf("TotalEvents = %d, want %d", task.Meta.TotalEvents, len(task.Events))
+	}
+
+	var moves []Event
+	for _, event := range task.Events {
+		if event.Type == EventMouseMove {
+			moves = append(moves, Event{Type: event.Type, X: event.X, Y: event.Y, Button: event.Button})
+		}
+	}
+	wantMoves := []Event{
+		// 直线接近路径只保留起点
+		mouseEvent(EventMouseMove, 20, 15, "none", 0),
+		// 拖拽保留起点和拐点
+		mouseEvent(EventMouseMove, 120, 100, "none", 0),
+		mouseEvent(EventMouseMove, 300, 100, "none", 0),
+		// 末尾只保留最后位置
+		mouseEvent(EventMouseMove, 0, 0, "none", 0),
+	}
+	if !reflect.DeepEqual(moves, wantMoves) {
+		t.Errorf("moves = %+v, want %+v", moves, wantMoves)
+	}
+}
+
+func TestOptimizeWithoutSimplification(t *testing.T) {
+	events := append(line(0, 0, 100, 0, 5, 10), mouseEvent(EventMouseClick, 100, 0, "left", 10))
+	task := &TaskData{Events: events}
+
+	removed := task.Optimize(OptimizeOptions{})
+
+	if removed != 0 || !reflect.DeepEqual(task.Events, events) {
+		t.Errorf("Optimize() with zero tolerance changed path: removed %d, events %+v", removed, task.Events)
+	}
+}
+
+func TestOptimizeBlocks(t *testing.T) {
+	steps := append(line(0, 0, 400, 300, 20, 50),
+		mouseEvent(EventMouseDown, 400, 300, "left", 30),
+		mouseEvent(EventMouseUp, 400, 300, "left", 80))
+	events := append(line(0, 0, 100, 100, 5, 10),
+		Event{Type: EventRepeat, Button: "none", Count: 3, Delay: 15, Steps: steps})
+	task := &TaskData{Events: events}
+
+	removed := task.Optimize(OptimizeOptions{PathTolerance: DefaultPathTolerance})
+
+	// 块之前的移动原样保留，块中的点击合并、路径简化；块以点击结尾，释放的延迟并入点击，每次重复的时长不变
+	if len(task.Events) != 6 || !reflect.DeepEqual(task.Events[:5], events[:5]) {
+		t.Fatalf("Optimize() changed the moves before the block: %+v", task.Events)
+	}
+	block := task.Events[5]
+	want := []Event{
+		mouseEvent(EventMouseMove, 20, 15, "none", 50),
+		mouseEvent(EventMouseClick, 400, 300, "left", 19*50+30+80),
+	}
+	if !reflect.DeepEqual(block.Steps, want) {
+		t.Errorf("block steps = %+v, want %+v", block.Steps, want)
+	}
+	if _, got := timeline(block.Steps); got != 20*50+30+80 {
+		t.Errorf("block duration = %dms, want %dms", got, 20*50+30+80)
+	}
+	if removed != CountEvents(events)-CountEvents(task.Events) || removed != 20 {
+		t.Errorf("Optimize() removed = %d, want 20", removed)
+	}
+}
+
+func TestPerpendicularDistance(t *testing.T) {
+	tests := []struct {
+		p, a, b point
+		want    float64
+	}{
+		{point{5, 3}, point{0, 0}, point{10, 0}, 3},
+		{point{0, 5}, point{0, 0}, point{0, 10}, 0},
+		{point{3, 4}, point{0, 0}, point{0, 0}, 5},
+	}
+	for _, tt := range tests {
+		if got := perpendicularDistance(tt.p, tt.a, tt.b); got != tt.want {
+			t.Errorf("perpendicularDistance(%v, %v, %v) = %v, want %v", tt.p, tt.a, tt.b, got, tt.want)
+		}
+	}
+}
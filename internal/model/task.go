@@ -1,21 +1,82 @@
 package model
 
+import "dailyflow/internal/screen"
+
+// TaskVersion 当前任务文件的数据版本
+// 修改 TaskData 的结构或语义时需要提升版本，并在 storage 中补充对应的迁移
+const TaskVersion = "1.11"
+
 // TaskMeta 存储任务的元信息
 type TaskMeta struct {
-	Version     string `json:"version"`      // 数据版本，默认 "1.0"
-	CreatedAt   int64  `json:"created_at"`   // 创建时间戳（Unix timestamp）
-	Resolution  string `json:"resolution"`   // 录制时的屏幕分辨率（如 "1920x1080"）
-	TotalEvents int    `json:"total_events"` // 总事件数量
+	Version     string           `json:"version"`            // 数据版本，见 TaskVersion
+	CreatedAt   int64            `json:"created_at"`         // 创建时间戳（Unix timestamp）
+	Resolution  string           `json:"resolution"`         // 录制时的主屏分辨率（如 "1920x1080"）
+	Monitors    []screen.Monitor `json:"monitors,omitempty"` // 录制时的显示器布局（虚拟桌面坐标）
+	TotalEvents int              `json:"total_events"`       // 总事件数量
 }
 
+// 事件类型
+const (
+	EventMouseMove  = "mouse_move"
+	EventMouseClick = "mouse_click"
+	EventMouseDown  = "mouse_down"
+	EventMouseUp    = "mouse_up"
+	EventMouseWheel = "mouse_wheel"
+	EventKeyDown    = "key_down"
+	EventKeyUp      = "key_up"
+	EventTypeText   = "type_text"
+	EventSecret     = "secret"      // 敏感输入占位：回放时从凭据存储按名称取出内容输入
+	EventKeyPress   = "key_press"   // 旧版格式：按下后立即释放，仅用于兼容已有录制
+	EventRepeat     = "repeat"      // 块：把 Steps 依次执行 Count 次
+	EventCall       = "call"        // 调用任务库中的另一个任务（Task），按它自己的速度回放
+	EventIf         = "if"          // 块：条件 Cond 成立时执行 Steps，否则执行 Else
+	EventWaitWindow = "wait_window" // 等待与 Window 匹配的窗口出现，最长 Timeout 毫秒；结果保存到变量 Var
+)
+
+// MaxRepeatCount repeat 块允许的最大重复次数，防止误写的次数让回放停不下来
+const MaxRepeatCount = 10000
+
+// MaxWaitTimeout wait_window 允许的最长等待（毫秒）
+const MaxWaitTimeout = 10 * 60 * 1000
+
+// call 步骤允许指定的速度因子范围
+const (
+	MinSpeedFactor = 0.1
+	MaxSpeedFactor = 10.0
+)
+
+// 滚轮方向
+const (
+	WheelVertical   = "vertical"
+	WheelHorizontal = "horizontal"
+)
+
+// WheelDeltaStep 滚轮转动一格对应的增量（Windows WHEEL_DELTA）
+const WheelDeltaStep = 120
+
 // Event 表示单个录制的事件（鼠标或键盘）
 type Event struct {
-	Type    string `json:"type"`     // 事件类型: "mouse_move", "mouse_click", "key_press"
-	X       int    `json:"x"`        // 鼠标 X 坐标（屏幕绝对坐标）
-	Y       int    `json:"y"`        // 鼠标 Y 坐标（屏幕绝对坐标）
-	Button  string `json:"button"`   // 鼠标按键: "left", "right", "middle", "double", "none"
-	KeyCode int    `json:"key_code"` // 虚拟键码（VK_* 常量）
-	Delay   int    `json:"delay"`    // 距离上一动作的毫秒数（Delta Time）
+	Type        string         `json:"type"`                  // 事件类型: "mouse_move", "mouse_click", "mouse_down", "mouse_up", "mouse_wheel", "key_down", "key_up", "type_text", "secret", "repeat", "call", "if", "wait_window", "key_press"（旧版）
+	X           int            `json:"x"`                     // 鼠标 X 坐标（虚拟桌面绝对坐标，副屏可为负）
+	Y           int            `json:"y"`                     // 鼠标 Y 坐标（虚拟桌面绝对坐标，副屏可为负）
+	Button      string         `json:"button"`                // 鼠标按键: "left", "right", "middle", "double", "none"
+	KeyCode     int            `json:"key_code"`              // 虚拟键码（VK_* 常量）
+	Delay       int            `json:"delay"`                 // 距离上一动作的毫秒数（Delta Time）
+	WheelDelta  int            `json:"wheel_delta,omitempty"` // 滚轮增量，120 为一格；纵向正数向上，横向正数向右
+	Orientation string         `json:"orientation,omitempty"` // 滚轮方向: "vertical", "horizontal"
+	Text        string         `json:"text,omitempty"`        // type_text 要输入的文本（UTF-8），可含 {{date}} 等模板变量
+	Secret      string         `json:"secret,omitempty"`      // secret 事件引用的敏感输入名称（内容不保存在任务中）
+	Window      *screen.Window `json:"window,omitempty"`      // 点击时光标所在的顶层窗口（标题、类名、位置）；wait_window 等待的窗口
+	RelX        int            `json:"rel_x,omitempty"`       // 相对 Window 左上角的 X 坐标
+	RelY        int            `json:"rel_y,omitempty"`       // 相对 Window 左上角的 Y 坐标
+	Count       int            `json:"count,omitempty"`       // repeat 的重复次数
+	Var         string         `json:"var,omitempty"`         // repeat 的计数变量名，块内文本可用 {{名称}} 引用当前是第几次（从 1 开始）；wait_window 保存结果（true/false）的变量名
+	Steps       []Event        `json:"steps,omitempty"`       // 块中的步骤，Delay 为块开始前的等待
+	Cond        string         `json:"cond,omitempty"`        // if 的条件表达式，如 "weekday == 1 || is_month_end"
+	Else        []Event        `json:"else,omitempty"`        // if 条件不成立时执行的步骤
+	Timeout     int            `json:"timeout,omitempty"`     // wait_window 最长等待的毫秒数
+	Task        string         `json:"task,omitempty"`        // call 调用的任务名称
+	Speed       float64        `json:"speed,omitempty"`       // call 回放被调用任务的速度因子，为 0 时使用该任务自己的设置
 }
 
 // TaskData 表示完整的任务数据结构（对应 task.json）
@@ -28,7 +89,7 @@ type TaskData struct {
 func NewTaskData(resolution string) *TaskData {
 	return &TaskData{
 		Meta: TaskMeta{
-			Version:     "1.0",
+			Version:     TaskVersion,
 			CreatedAt:   0, // 将在录制开始时设置
 			Resolution:  resolution,
 			TotalEvents: 0,
@@ -43,3 +104,49 @@ func (t *TaskData) AddEvent(event Event) {
 	t.Meta.TotalEvents = len(t.Events)
 }
 
+// IsBlock 判断事件是否为包含子步骤的块
+func (e *Event) IsBlock() bool {
+	return e.Type == EventRepeat || e.Type == EventIf
+}
+
+// WalkEvents 按先后顺序访问 events 中的全部事件，块中的步骤（if 先 Steps 后 Else）紧随块本身
+func WalkEvents(events []Event, fn func(event *Event)) {
+	for i := range events {
+		fn(&events[i])
+		WalkEvents(events[i].Steps, fn)
+		WalkEvents(events[i].Else, fn)
+	}
+}
+
+// CountEvents 返回 events 中（含块中）的事件总数
+func CountEvents(events []Event) int {
+	count := 0
+	WalkEvents(events, func(*Event) { count++ })
+	return count
+}
+
+// Calls 返回任务中 call 步骤调用的任务名称（按首次出现的顺序，不重复）
+func (t *TaskData) Calls() []string {
+	var names []string
+	seen := make(map[string]bool)
+	WalkEvents(t.Events, func(event *Event) {
+		if event.Type == EventCall && event.Task != "" && !seen[event.Task] {
+			seen[event.Task] = true
+			names = append(names, event.Task)
+		}
+	})
+	return names
+}
+
+// Secrets 返回任务引用的敏感输入名称（按首次出现的顺序，不重复）
+func (t *TaskData) Secrets() []string {
+	var names []string
+	seen := make(map[string]bool)
+	WalkEvents(t.Events, func(event *Event) {
+		if event.Type == EventSecret && event.Secret != "" && !seen[event.Secret] {
+			seen[event.Secret] = true
+			names = append(names, event.Secret)
+		}
+	})
+	return names
+}
@@ -0,0 +1,306 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// 模板变量：type_text 的文本中 {{名称}} 在回放开始时替换为变量的值
+//
+//	{{date}}               今天，默认格式 YYYY-MM-DD
+//	{{prev_workday:MMDD}}  上一个工作日，按指定格式
+//	{{date-7}}             7 天前；workday±N 为前后第 N 个工作日
+//	{{branch}}             自定义变量（config.json 的 variables）
+//	{{"{{"}}               原样输入引号中的文本，用于输入 "{{" 本身
+
+// ErrUndefinedVariable 模板引用了既不是内置变量也没有定义的变量
+var ErrUndefinedVariable = errors.New("undefined variable")
+
+// 模板的起止标记
+const (
+	templateOpen  = "{{"
+	templateClose = "}}"
+)
+
+// dateFunction 内置的日期时间变量
+type dateFunction struct {
+	layout   string                               // 未指定格式时使用的默认格式
+	offset   bool                                 // 是否允许 ±N 偏移
+	evaluate func(now time.Time, n int) time.Time // 按偏移量 n 计算时刻
+}
+
+// dateFunctions 内置变量，名称不区分大小写
+var dateFunctions = map[string]dateFunction{
+	"date":         {layout: "YYYY-MM-DD", offset: true, evaluate: addDays},
+	"yesterday":    {layout: "YYYY-MM-DD", evaluate: func(now time.Time, _ int) time.Time { return addDays(now, -1) }},
+	"tomorrow":     {layout: "YYYY-MM-DD", evaluate: func(now time.Time, _ int) time.Time { return addDays(now, 1) }},
+	"workday":      {layout: "YYYY-MM-DD", offset: true, evaluate: addWorkdays},
+	"prev_workday": {layout: "YYYY-MM-DD", evaluate: func(now time.Time, _ int) time.Time { return addWorkdays(now, -1) }},
+	"next_workday": {layout: "YYYY-MM-DD", evaluate: func(now time.Time, _ int) time.Time { return addWorkdays(now, 1) }},
+	"time":         {layout: "HH:mm", evaluate: func(now time.Time, _ int) time.Time { return now }},
+	"now":          {layout: "YYYY-MM-DD HH:mm:ss", evaluate: func(now time.Time, _ int) time.Time { return now }},
+}
+
+// templateRef 模板中的一个 {{...}}
+type templateRef struct {
+	literal   string // 引号中的原样文本（isLiteral 时有效）
+	isLiteral bool
+	name      string // 变量名
+	offset    int    // date / workday 的偏移量
+	hasOffset bool
+	format    string // 冒号后的格式，为空表示默认格式
+}
+
+// Variables 展开模板时可用的变量
+type Variables struct {
+	Now    time.Time         // 日期时间变量的基准时刻，一次回放中保持不变
+	Values map[string]string // 自定义变量，优先于同名的内置变量
+}
+
+// Expand 把 text 中的全部 {{...}} 替换为变量的值
+func (v Variables) Expand(text string) (string, error) {
+	var b strings.Builder
+	err := scanTemplate(text, func(plain string, ref *templateRef) error {
+		b.WriteString(plain)
+		if ref == nil {
+			return nil
+		}
+		value, err := v.value(ref)
+		if err != nil {
+			return err
+		}
+		b.WriteString(value)
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// value 返回单个引用的值
+func (v Variables) value(ref *templateRef) (string, error) {
+	if ref.isLiteral {
+		return ref.literal, nil
+	}
+	if value, ok := v.Values[ref.name]; ok {
+		if ref.hasOffset || ref.format != "" {
+			return "", fmt.Errorf("variable %q is a custom value and takes no offset or format", ref.name)
+		}
+		return value, nil
+	}
+	fn, ok := dateFunctions[strings.ToLower(ref.name)]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrUndefinedVariable, ref.name)
+	}
+	layout := ref.format
+	if layout == "" {
+		layout = fn.layout
+	}
+	return FormatDate(fn.evaluate(v.Now, ref.offset), layout), nil
+}
+
+// MergeValues 返回 base 与 extra 合并后的新变量表，同名时 extra 优先，两者都不会被修改
+func MergeValues(base, extra map[string]string) map[string]string {
+	merged := make(map[string]string, len(base)+len(extra))
+	for name, value := range base {
+		merged[name] = value
+	}
+	for name, value := range extra {
+		merged[name] = value
+	}
+	return merged
+}
+
+// TemplateVariables 返回 text 引用的变量名（按出现顺序，不含内置变量，不重复）
+func TemplateVariables(text string) ([]string, error) {
+	var names []string
+	seen := make(map[string]bool)
+	err := scanTemplate(text, func(_ string, ref *templateRef) error {
+		if ref == nil || ref.isLiteral || seen[ref.name] {
+			return nil
+		}
+		if _, builtin := dateFunctions[strings.ToLower(ref.name)]; builtin {
+			return nil
+		}
+		seen[ref.name] = true
+		names = append(names, ref.name)
+		return nil
+	})
+	return names, err
+}
+
+// HasTemplate 判断文本中是否含有模板标记
+func HasTemplate(text string) bool {
+	return strings.Contains(text, templateOpen)
+}
+
+// EscapeTemplate 转义 text 中的 "{{"，使其回放时按原样输入
+func EscapeTemplate(text string) string {
+	return strings.ReplaceAll(text, templateOpen, templateOpen+strconv.Quote(templateOpen)+templateClose)
+}
+
+// scanTemplate 依次把普通文本和解析出的引用交给 fn，普通文本之后没有引用时 ref 为 nil
+func scanTemplate(text string, fn func(plain string, ref *templateRef) error) error {
+	offset := 0
+	for {
+		start := strings.Index(text, templateOpen)
+		if start < 0 {
+			return fn(text, nil)
+		}
+		ref, length, err := parseTemplateRef(text[start+len(templateOpen):])
+		if err != nil {
+			return fmt.Errorf("template at offset %d: %w", offset+start, err)
+		}
+		if err := fn(text[:start], ref); err != nil {
+			return err
+		}
+		consumed := start + len(templateOpen) + length
+		text = text[consumed:]
+		offset += consumed
+	}
+}
+
+// parseTemplateRef 解析 "{{" 之后的内容，返回引用和包括 "}}" 在内消耗的长度
+func parseTemplateRef(s string) (*templateRef, int, error) {
+	trimmed := strings.TrimLeft(s, " ")
+	lead := len(s) - len(trimmed)
+
+	if strings.HasPrefix(trimmed, `"`) {
+		quoted, err := strconv.QuotedPrefix(trimmed)
+		if err != nil {
+			return nil, 0, fmt.Errorf("unterminated quoted text")
+		}
+		rest := strings.TrimLeft(trimmed[len(quoted):], " ")
+		if !strings.HasPrefix(rest, templateClose) {
+			return nil, 0, fmt.Errorf("expected %s after quoted text", templateClose)
+		}
+		literal, _ := strconv.Unquote(quoted)
+		length := len(s) - len(rest) + len(templateClose)
+		return &templateRef{literal: literal, isLiteral: true}, length, nil
+	}
+
+	end := strings.Index(trimmed, templateClose)
+	if end < 0 {
+		return nil, 0, fmt.Errorf("missing %s", templateClose)
+	}
+	ref, err := parseTemplateBody(strings.TrimSpace(trimmed[:end]))
+	if err != nil {
+		return nil, 0, err
+	}
+	return ref, lead + end + len(templateClose), nil
+}
+
+// parseTemplateBody 解析 "名称±N:格式" 形式的引用
+func parseTemplateBody(body string) (*templateRef, error) {
+	ref := &templateRef{}
+	body, ref.format, _ = strings.Cut(body, ":")
+
+	name := body
+	if i := strings.IndexAny(body, "+-"); i >= 0 {
+		name = strings.TrimSpace(body[:i])
+		n, err := strconv.Atoi(strings.ReplaceAll(body[i:], " ", ""))
+		if err != nil {
+			return nil, fmt.Errorf("invalid offset in %q", body)
+		}
+		ref.offset, ref.hasOffset = n, true
+	}
+	if !isVariableName(name) {
+		return nil, fmt.Errorf("invalid variable name %q", name)
+	}
+	ref.name = name
+
+	// 偏移和格式只适用于内置的日期时间变量
+	fn, builtin := dateFunctions[strings.ToLower(name)]
+	if ref.hasOffset && !(builtin && fn.offset) {
+		return nil, fmt.Errorf("variable %q takes no offset", name)
+	}
+	if ref.format != "" && !builtin {
+		return nil, fmt.Errorf("variable %q takes no format", name)
+	}
+	return ref, nil
+}
+
+// isVariableName 变量名由字母（含中文）、数字和下划线组成，不以数字开头
+func isVariableName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, r := range name {
+		if !(r == '_' || unicode.IsLetter(r) || (i > 0 && unicode.IsDigit(r))) {
+			return false
+		}
+	}
+	return true
+}
+
+// addDays 返回 n 天后（n 为负数时为之前）的同一时刻
+func addDays(now time.Time, n int) time.Time {
+	return now.AddDate(0, 0, n)
+}
+
+// addWorkdays 返回第 n 个工作日（周一至周五），n 为 0 时返回今天
+func addWorkdays(now time.Time, n int) time.Time {
+	step := 1
+	if n < 0 {
+		step, n = -1, -n
+	}
+	for n > 0 {
+		now = now.AddDate(0, 0, step)
+		if wd := now.Weekday(); wd != time.Saturday && wd != time.Sunday {
+			n--
+		}
+	}
+	return now
+}
+
+// dateTokens 日期格式中的占位符，按长度从长到短匹配，其余字符原样输出
+var dateTokens = []struct {
+	token  string
+	format func(t time.Time) string
+}{
+	{"YYYY", func(t time.Time) string { return fmt.Sprintf("%04d", t.Year()) }},
+	{"dddd", func(t time.Time) string { return "星期" + chineseWeekdays[t.Weekday()] }},
+	{"ddd", func(t time.Time) string { return "周" + chineseWeekdays[t.Weekday()] }},
+	{"YY", func(t time.Time) string { return fmt.Sprintf("%02d", t.Year()%100) }},
+	{"MM", func(t time.Time) string { return fmt.Sprintf("%02d", int(t.Month())) }},
+	{"DD", func(t time.Time) string { return fmt.Sprintf("%02d", t.Day()) }},
+	{"HH", func(t time.Time) string { return fmt.Sprintf("%02d", t.Hour()) }},
+	{"mm", func(t time.Time) string { return fmt.Sprintf("%02d", t.Minute()) }},
+	{"ss", func(t time.Time) string { return fmt.Sprintf("%02d", t.Second()) }},
+	{"M", func(t time.Time) string { return strconv.Itoa(int(t.Month())) }},
+	{"D", func(t time.Time) string { return strconv.Itoa(t.Day()) }},
+	{"H", func(t time.Time) string { return strconv.Itoa(t.Hour()) }},
+	{"m", func(t time.Time) string { return strconv.Itoa(t.Minute()) }},
+	{"s", func(t time.Time) string { return strconv.Itoa(t.Second()) }},
+}
+
+var chineseWeekdays = [...]string{"日", "一", "二", "三", "四", "五", "六"}
+
+// FormatDate 按 YYYY、MM、DD、HH、mm、ss 等占位符格式化时刻，如 "YYYY年M月D日"
+func FormatDate(t time.Time, layout string) string {
+	var b strings.Builder
+	for layout != "" {
+		matched := false
+		for _, tok := range dateTokens {
+			if strings.HasPrefix(layout, tok.token) {
+				b.WriteString(tok.format(t))
+				layout = layout[len(tok.token):]
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			// 按完整字符输出，避免截断中文
+			r, size := utf8.DecodeRuneInString(layout)
+			b.WriteRune(r)
+			layout = layout[size:]
+		}
+	}
+	return b.String()
+}
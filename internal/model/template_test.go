@@ -0,0 +1,88 @@
+package model
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestExpand(t *testing.T) {
+	// 2024-12-02 是周一，上一个工作日是上周五
+	vars := Variables{
+		Now:    time.Date(2024, 12, 2, 8, 5, 9, 0, time.Local),
+		Values: map[string]string{"branch": "0101", "date_label": "日报"},
+	}
+
+	tests := []struct {
+		text    string
+		want    string
+		wantErr bool
+	}{
+		{"无变量 {单个括号}", "无变量 {单个括号}", false},
+		{"{{date}}", "2024-12-02", false},
+		{"{{ yesterday }}", "2024-12-01", false},
+		{"{{tomorrow:M/D}}", "12/3", false},
+		{"{{prev_workday}}", "2024-11-29", false},
+		{"{{next_workday:ddd}}", "周二", false},
+		{"{{workday-2:YYYYMMDD}}", "20241128", false},
+		{"{{date-7:YY.MM.DD}}", "24.11.25", false},
+		{"{{date+30:YYYY年M月D日 dddd}}", "2025年1月1日 星期三", false},
+		{"{{time}}", "08:05", false},
+		{"{{now:H时m分s秒}}", "8时5分9秒", false},
+		{"{{DATE}}", "2024-12-02", false},
+		{"{{date_label}}-{{branch}}-{{date}}", "日报-0101-2024-12-02", false},
+		{`{{"{{"}}date}}`, "{{date}}", false},
+		{"{{unknown}}", "", true},
+		{"{{branch:YYYY}}", "", true},
+		{"{{date", "", true},
+		{"{{9lives}}", "", true},
+		{"{{date+x}}", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			got, err := vars.Expand(tt.text)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Expand() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Expand() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := vars.Expand("{{unknown}}"); !errors.Is(err, ErrUndefinedVariable) {
+		t.Errorf("Expand() error = %v, want ErrUndefinedVariable", err)
+	}
+}
+
+func TestPrevWorkday(t *testing.T) {
+	tests := []struct {
+		today time.Time
+		want  string
+	}{
+		{time.Date(2024, 12, 3, 0, 0, 0, 0, time.Local), "2024-12-02"}, // 周二
+		{time.Date(2024, 12, 7, 0, 0, 0, 0, time.Local), "2024-12-06"}, // 周六
+		{time.Date(2024, 12, 8, 0, 0, 0, 0, time.Local), "2024-12-06"}, // 周日
+		{time.Date(2025, 1, 1, 0, 0, 0, 0, time.Local), "2024-12-31"},  // 跨年
+	}
+	for _, tt := range tests {
+		got, err := Variables{Now: tt.today}.Expand("{{prev_workday}}")
+		if err != nil || got != tt.want {
+			t.Errorf("prev_workday on %s = %q, %v, want %q", tt.today.Format("2006-01-02"), got, err, tt.want)
+		}
+	}
+}
+
+func TestTemplateVariables(t *testing.T) {
+	names, err := TemplateVariables("{{branch}} {{date}} {{网点}} {{branch}} {{\"{{x}}\"}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"branch", "网点"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("TemplateVariables() = %q, want %q", names, want)
+	}
+	if got := EscapeTemplate("a{{b}}"); got != `a{{"{{"}}b}}` {
+		t.Errorf("EscapeTemplate() = %q", got)
+	}
+}
@@ -0,0 +1,344 @@
+package model
+
+import (
+	"dailyflow/internal/screen"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrInvalidTask 任务数据存在错误，不能回放
+var ErrInvalidTask = errors.New("invalid task")
+
+// 检查结果的严重程度
+const (
+	SeverityError   = "error"   // 错误：回放结果不可预期，拒绝回放
+	SeverityWarning = "warning" // 警告：可以回放，但可能不是录制者的本意
+)
+
+// 检查项代码
+const (
+	CheckUnknownType   = "unknown_type"
+	CheckUnknownButton = "unknown_button"
+	CheckKeyCodeRange  = "key_code_range"
+	CheckNegativeDelay = "negative_delay"
+	CheckOutOfScreen   = "out_of_screen"
+	CheckBadResolution = "bad_resolution"
+	CheckTotalEvents   = "total_events"
+	CheckLongGap       = "long_gap"
+	CheckUnknownWheel  = "unknown_wheel"
+	CheckEmptyText     = "empty_text"
+	CheckEmptySecret   = "empty_secret"
+	CheckBadTemplate   = "bad_template"
+	CheckUndefinedVar  = "undefined_variable"
+	CheckRepeatCount   = "repeat_count"
+	CheckBadVariable   = "bad_variable"
+	CheckEmptyBlock    = "empty_block"
+	CheckNotBlock      = "not_block"
+	CheckEmptyCall     = "empty_call"
+	CheckCallSpeed     = "call_speed"
+	CheckMissingTask   = "missing_task"
+	CheckCallCycle     = "call_cycle"
+	CheckBadCondition  = "bad_condition"
+	CheckEmptyWindow   = "empty_window"
+	CheckWaitTimeout   = "wait_timeout"
+)
+
+// 有效的 Windows 虚拟键码范围
+const (
+	MinKeyCode = 0x01
+	MaxKeyCode = 0xFE
+)
+
+// LongGapThreshold 两个事件之间超过该时长（毫秒）视为可疑的长时间停顿
+const LongGapThreshold = 2 * 60 * 1000
+
+// Finding 一条检查结果
+type Finding struct {
+	Severity string `json:"severity"` // SeverityError / SeverityWarning
+	Check    string `json:"check"`    // 检查项代码，如 CheckUnknownType
+	Path     string `json:"path"`     // 出问题的位置，如 "events[3]"、"meta.total_events"
+	Message  string `json:"message"`  // 说明
+}
+
+// String 返回 "error events[3]: unknown event type "foo"" 形式的描述
+func (f Finding) String() string {
+	return fmt.Sprintf("%s %s: %s", f.Severity, f.Path, f.Message)
+}
+
+// ValidationError 任务检查发现错误时返回，包含全部检查结果（含警告）
+type ValidationError struct {
+	Findings []Finding
+}
+
+func (e *ValidationError) Error() string {
+	var errs []string
+	for _, f := range e.Findings {
+		if f.Severity == SeverityError {
+			errs = append(errs, f.Path+": "+f.Message)
+		}
+	}
+	return fmt.Sprintf("%s: %s", ErrInvalidTask, strings.Join(errs, "; "))
+}
+
+// Unwrap 使 errors.Is(err, ErrInvalidTask) 成立
+func (e *ValidationError) Unwrap() error {
+	return ErrInvalidTask
+}
+
+// HasErrors 判断检查结果中是否有错误级别的条目
+func HasErrors(findings []Finding) bool {
+	for _, f := range findings {
+		if f.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate 检查任务数据，返回全部检查结果（无问题时为空）
+func (t *TaskData) Validate() []Finding {
+	v := &validator{}
+
+	if t.Meta.TotalEvents != len(t.Events) {
+		v.add(SeverityWarning, CheckTotalEvents, "meta.total_events",
+			"total_events is %d but the task has %d events", t.Meta.TotalEvents, len(t.Events))
+	}
+
+	v.screens = v.recordedScreens(&t.Meta)
+
+	for i := range t.Events {
+		v.event(fmt.Sprintf("events[%d]", i), &t.Events[i])
+	}
+
+	return v.findings
+}
+
+// Check 检查任务数据，存在错误时返回 *ValidationError
+func (t *TaskData) Check() error {
+	findings := t.Validate()
+	if HasErrors(findings) {
+		return &ValidationError{Findings: findings}
+	}
+	return nil
+}
+
+// ValidateVariables 检查文本和条件中引用的变量是否都在 values、外层 repeat 的计数变量或之前 wait_window 的结果中定义，
+// 以及能否用这些变量展开；模板和条件的语法错误由 Validate 报告，这里跳过
+func (t *TaskData) ValidateVariables(values map[string]string) []Finding {
+	v := &validator{outputs: make(map[string]string)}
+	v.variables("events", t.Events, values)
+	return v.findings
+}
+
+// variables 检查 steps 中引用的变量，scope 为当前层级可用的变量
+func (v *validator) variables(path string, steps []Event, scope map[string]string) {
+	for i := range steps {
+		step := &steps[i]
+		stepPath := fmt.Sprintf("%s[%d]", path, i)
+		// wait_window 的结果在它之后的步骤中都可以使用
+		defined := MergeValues(v.outputs, scope)
+		switch step.Type {
+		case EventTypeText:
+			names, err := TemplateVariables(step.Text)
+			if err != nil {
+				continue
+			}
+			if !v.defined(stepPath, names, defined, "{{%s}}") {
+				continue
+			}
+			// 试展开一次，发现给同名的自定义变量指定了格式等问题
+			if _, err := (Variables{Values: defined}).Expand(step.Text); err != nil {
+				v.add(SeverityError, CheckBadTemplate, stepPath, "%v", err)
+			}
+		case EventRepeat:
+			inner := scope
+			if step.Var != "" {
+				inner = MergeValues(scope, map[string]string{step.Var: ""})
+			}
+			v.variables(stepPath+".steps", step.Steps, inner)
+		case EventIf:
+			if names, err := ConditionVariables(step.Cond); err == nil {
+				v.defined(stepPath, names, defined, "%s")
+			}
+			v.variables(stepPath+".steps", step.Steps, scope)
+			v.variables(stepPath+".else", step.Else, scope)
+		case EventWaitWindow:
+			if step.Var != "" {
+				v.outputs[step.Var] = ""
+			}
+		}
+	}
+}
+
+// defined 检查 names 是否都在 scope 中定义，未定义的逐个报告，ref 为提示中引用变量的写法
+func (v *validator) defined(path string, names []string, scope map[string]string, ref string) bool {
+	ok := true
+	for _, name := range names {
+		if _, found := scope[name]; !found {
+			v.add(SeverityError, CheckUndefinedVar, path, "%v: %s", ErrUndefinedVariable, fmt.Sprintf(ref, name))
+			ok = false
+		}
+	}
+	return ok
+}
+
+// validator 保存一次检查的上下文和结果
+type validator struct {
+	screens  []screen.Rect     // 录制时的屏幕区域，为空表示无法检查坐标
+	layout   bool              // screens 来自完整的显示器布局（旧任务只记录了主屏分辨率）
+	outputs  map[string]string // 已经过的 wait_window 保存结果的变量
+	findings []Finding
+}
+
+func (v *validator) add(severity, check, path, format string, args ...interface{}) {
+	v.findings = append(v.findings, Finding{
+		Severity: severity,
+		Check:    check,
+		Path:     path,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// recordedScreens 返回录制时的屏幕区域：优先使用显示器布局，旧任务使用分辨率
+func (v *validator) recordedScreens(meta *TaskMeta) []screen.Rect {
+	if len(meta.Monitors) > 0 {
+		v.layout = true
+		rects := make([]screen.Rect, 0, len(meta.Monitors))
+		for _, m := range meta.Monitors {
+			rects = append(rects, m.Rect)
+		}
+		return rects
+	}
+	if meta.Resolution == "" {
+		// 新建的空任务没有分辨率
+		return nil
+	}
+	rect, err := screen.ParseResolution(meta.Resolution)
+	if err != nil {
+		v.add(SeverityError, CheckBadResolution, "meta.resolution", "%v", err)
+		return nil
+	}
+	return []screen.Rect{rect}
+}
+
+// event 检查单个事件
+func (v *validator) event(path string, event *Event) {
+	if event.Delay < 0 {
+		v.add(SeverityError, CheckNegativeDelay, path, "delay %dms is negative", event.Delay)
+	} else if event.Delay > LongGapThreshold {
+		v.add(SeverityWarning, CheckLongGap, path, "waits %s before this event",
+			time.Duration(event.Delay)*time.Millisecond)
+	}
+
+	switch event.Type {
+	case EventMouseMove:
+		v.position(path, event)
+	case EventMouseClick, EventMouseDown, EventMouseUp:
+		v.position(path, event)
+		if !isMouseButton(event.Type, event.Button) {
+			v.add(SeverityError, CheckUnknownButton, path, "unknown mouse button %q", event.Button)
+		}
+	case EventMouseWheel:
+		v.position(path, event)
+		if event.Orientation != "" && event.Orientation != WheelVertical && event.Orientation != WheelHorizontal {
+			v.add(SeverityError, CheckUnknownWheel, path, "unknown wheel orientation %q", event.Orientation)
+		}
+	case EventKeyDown, EventKeyUp, EventKeyPress:
+		if event.KeyCode < MinKeyCode || event.KeyCode > MaxKeyCode {
+			v.add(SeverityError, CheckKeyCodeRange, path, "virtual key code %d is outside %d-%d",
+				event.KeyCode, MinKeyCode, MaxKeyCode)
+		}
+	case EventTypeText:
+		if event.Text == "" {
+			v.add(SeverityWarning, CheckEmptyText, path, "type_text has no text")
+		}
+		if _, err := TemplateVariables(event.Text); err != nil {
+			v.add(SeverityError, CheckBadTemplate, path, "%v", err)
+		}
+	case EventSecret:
+		if event.Secret == "" {
+			v.add(SeverityError, CheckEmptySecret, path, "secret has no name")
+		}
+	case EventRepeat:
+		if event.Count < 1 || event.Count > MaxRepeatCount {
+			v.add(SeverityError, CheckRepeatCount, path, "repeat count %d is outside 1-%d", event.Count, MaxRepeatCount)
+		}
+		if event.Var != "" && !isVariableName(event.Var) {
+			v.add(SeverityError, CheckBadVariable, path, "invalid variable name %q", event.Var)
+		}
+	case EventCall:
+		if event.Task == "" {
+			v.add(SeverityError, CheckEmptyCall, path, "call has no task name")
+		}
+		if event.Speed != 0 && (event.Speed < MinSpeedFactor || event.Speed > MaxSpeedFactor) {
+			v.add(SeverityError, CheckCallSpeed, path, "call speed %g is outside %g-%g", event.Speed, MinSpeedFactor, MaxSpeedFactor)
+		}
+	case EventIf:
+		if _, err := ConditionVariables(event.Cond); err != nil {
+			v.add(SeverityError, CheckBadCondition, path, "%v", err)
+		}
+	case EventWaitWindow:
+		if event.Window == nil || (event.Window.Title == "" && event.Window.Class == "") {
+			v.add(SeverityError, CheckEmptyWindow, path, "wait_window has no window title or class")
+		}
+		if event.Timeout < 1 || event.Timeout > MaxWaitTimeout {
+			v.add(SeverityError, CheckWaitTimeout, path, "timeout %dms is outside 1-%d", event.Timeout, MaxWaitTimeout)
+		}
+		if event.Var != "" && !isVariableName(event.Var) {
+			v.add(SeverityError, CheckBadVariable, path, "invalid variable name %q", event.Var)
+		}
+	default:
+		v.add(SeverityError, CheckUnknownType, path, "unknown event type %q", event.Type)
+	}
+
+	if len(event.Else) > 0 && event.Type != EventIf {
+		v.add(SeverityError, CheckNotBlock, path, "%s cannot contain else steps", event.Type)
+	}
+	if !event.IsBlock() {
+		if len(event.Steps) > 0 {
+			v.add(SeverityError, CheckNotBlock, path, "%s cannot contain steps", event.Type)
+		}
+		return
+	}
+	if len(event.Steps) == 0 && len(event.Else) == 0 {
+		v.add(SeverityWarning, CheckEmptyBlock, path, "%s has no steps", event.Type)
+	}
+	for i := range event.Steps {
+		v.event(fmt.Sprintf("%s.steps[%d]", path, i), &event.Steps[i])
+	}
+	for i := range event.Else {
+		v.event(fmt.Sprintf("%s.else[%d]", path, i), &event.Else[i])
+	}
+}
+
+// position 检查坐标是否落在录制时的某个屏幕内
+func (v *validator) position(path string, event *Event) {
+	if len(v.screens) == 0 {
+		return
+	}
+	for _, rect := range v.screens {
+		if rect.Contains(event.X, event.Y) {
+			return
+		}
+	}
+	// 旧任务只记录了主屏分辨率，副屏上的坐标本来就在范围外，只给出警告
+	severity := SeverityWarning
+	if v.layout {
+		severity = SeverityError
+	}
+	v.add(severity, CheckOutOfScreen, path, "position (%d, %d) is outside the recorded screen", event.X, event.Y)
+}
+
+// isMouseButton 判断是否为回放支持的鼠标按键，"double"（左键双击）只用于点击
+func isMouseButton(eventType, button string) bool {
+	switch button {
+	case "left", "right", "middle":
+		return true
+	case "double":
+		return eventType == EventMouseClick
+	default:
+		return false
+	}
+}
@@ -0,0 +1,284 @@
+package model
+
+import (
+	"dailyflow/internal/screen"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// findingKeys 把检查结果简化为 "severity check path" 便于比较
+func findingKeys(findings []Finding) []string {
+	var keys []string
+	for _, f := range findings {
+		keys = append(keys, f.Severity+" "+f.Check+" "+f.Path)
+	}
+	return keys
+}
+
+func TestValidate(t *testing.T) {
+	dualMonitors := []screen.Monitor{
+		{Rect: screen.Rect{Left: 0, Top: 0, Width: 1920, Height: 1080}, Primary: true},
+		{Rect: screen.Rect{Left: -1280, Top: 0, Width: 1280, Height: 1024}},
+	}
+
+	tests := []struct {
+		name     string
+		meta     TaskMeta
+		events   []Event
+		want     []string
+		hasError bool
+	}{
+		{
+			name: "valid task",
+			meta: TaskMeta{Resolution: "1920x1080"},
+			events: []Event{
+				mouseEvent(EventMouseMove, 10, 10, "none", 0),
+				mouseEvent(EventMouseClick, 1919, 1079, "left", 50),
+				mouseEvent(EventMouseClick, 100, 100, "double", 50),
+				{Type: EventKeyDown, Button: "none", KeyCode: 0x41, Delay: 10},
+				{Type: EventKeyUp, Button: "none", KeyCode: 0x41, Delay: 10},
+				{Type: EventMouseWheel, X: 5, Y: 5, WheelDelta: -120, Orientation: WheelVertical},
+				{Type: EventTypeText, Text: "日报 {{date:YYYYMMDD}} {{branch}}"},
+				{Type: EventSecret, Secret: "门户#1"},
+			},
+		},
+		{
+			name:     "secret without name",
+			meta:     TaskMeta{Resolution: "1920x1080"},
+			events:   []Event{{Type: EventSecret, Button: "none"}},
+			want:     []string{"error empty_secret events[0]"},
+			hasError: true,
+		},
+		{
+			name:     "unknown type",
+			meta:     TaskMeta{Resolution: "1920x1080"},
+			events:   []Event{{Type: "mouse_teleport"}},
+			want:     []string{"error unknown_type events[0]"},
+			hasError: true,
+		},
+		{
+			name: "unknown button",
+			meta: TaskMeta{Resolution: "1920x1080"},
+			events: []Event{
+				mouseEvent(EventMouseDown, 10, 10, "x1", 0),
+				mouseEvent(EventMouseUp, 10, 10, "", 0),
+				// 双击只能用于点击，不能单独按下或释放
+				mouseEvent(EventMouseDown, 10, 10, "double", 0),
+			},
+			want:     []string{"error unknown_button events[0]", "error unknown_button events[1]", "error unknown_button events[2]"},
+			hasError: true,
+		},
+		{
+			name: "key code out of range",
+			meta: TaskMeta{Resolution: "1920x1080"},
+			events: []Event{
+				{Type: EventKeyDown, KeyCode: 0},
+				{Type: EventKeyUp, KeyCode: 300},
+				{Type: EventKeyUp, KeyCode: 0xFE},
+			},
+			want:     []string{"error key_code_range events[0]", "error key_code_range events[1]"},
+			hasError: true,
+		},
+		{
+			name:     "negative delay",
+			meta:     TaskMeta{Resolution: "1920x1080"},
+			events:   []Event{{Type: EventTypeText, Text: "a", Delay: -5}},
+			want:     []string{"error negative_delay events[0]"},
+			hasError: true,
+		},
+		{
+			name: "outside recorded monitors",
+			meta: TaskMeta{Resolution: "1920x1080", Monitors: dualMonitors},
+			events: []Event{
+				mouseEvent(EventMouseClick, -100, 500, "left", 0),
+				mouseEvent(EventMouseClick, -100, 1050, "left", 0),
+				mouseEvent(EventMouseMove, 1920, 0, "none", 0),
+			},
+			want:     []string{"error out_of_screen events[1]", "error out_of_screen events[2]"},
+			hasError: true,
+		},
+		{
+			name:   "legacy task outside primary resolution is a warning",
+			meta:   TaskMeta{Resolution: "1920x1080"},
+			events: []Event{mouseEvent(EventMouseClick, -100, 500, "left", 0)},
+			want:   []string{"warning out_of_screen events[0]"},
+		},
+		{
+			name:     "bad resolution",
+			meta:     TaskMeta{Resolution: "wide"},
+			events:   []Event{mouseEvent(EventMouseClick, 10, 10, "left", 0)},
+			want:     []string{"error bad_resolution meta.resolution"},
+			hasError: true,
+		},
+		{
+			name:   "total events mismatch and long gap",
+			meta:   TaskMeta{Resolution: "1920x1080", TotalEvents: 5},
+			events: []Event{{Type: EventTypeText, Text: "a", Delay: 5 * 60 * 1000}},
+			want:   []string{"warning total_events meta.total_events", "warning long_gap events[0]"},
+		},
+		{
+			name: "bad template",
+			meta: TaskMeta{Resolution: "1920x1080"},
+			events: []Event{
+				{Type: EventTypeText, Text: "{{date"},
+				{Type: EventTypeText, Text: "{{branch:YYYY}}"},
+				{Type: EventTypeText, Text: "{{yesterday-1}}"},
+			},
+			want:     []string{"error bad_template events[0]", "error bad_template events[1]", "error bad_template events[2]"},
+			hasError: true,
+		},
+		{
+			name: "repeat blocks",
+			meta: TaskMeta{Resolution: "1920x1080"},
+			events: []Event{
+				{Type: EventRepeat, Count: 3, Var: "i", Steps: []Event{
+					{Type: EventTypeText, Text: "{{i}}"},
+					{Type: EventRepeat, Count: 2, Steps: []Event{
+						mouseEvent(EventMouseClick, 1, 1, "x1", 0),
+					}},
+				}},
+				{Type: EventRepeat, Count: 0, Var: "9i"},
+				{Type: EventTypeText, Text: "a", Steps: []Event{{Type: EventTypeText, Text: "b"}}},
+			},
+			want: []string{
+				"error unknown_button events[0].steps[1].steps[0]",
+				"error repeat_count events[1]",
+				"error bad_variable events[1]",
+				"warning empty_block events[1]",
+				"error not_block events[2]",
+			},
+			hasError: true,
+		},
+		{
+			name: "calls",
+			meta: TaskMeta{Resolution: "1920x1080"},
+			events: []Event{
+				{Type: EventCall, Task: "登录门户"},
+				{Type: EventCall, Task: "登录门户", Speed: 0.8},
+				{Type: EventCall},
+				{Type: EventCall, Task: "导出", Speed: 50},
+			},
+			want:     []string{"error empty_call events[2]", "error call_speed events[3]"},
+			hasError: true,
+		},
+		{
+			name: "if and wait_window",
+			meta: TaskMeta{Resolution: "1920x1080"},
+			events: []Event{
+				{Type: EventWaitWindow, Window: &screen.Window{Title: "日报"}, Timeout: 5000, Var: "found"},
+				{Type: EventIf, Cond: "found && weekday == 1", Steps: []Event{{Type: EventKeyPress, KeyCode: 0x0D}},
+					Else: []Event{{Type: EventMouseClick, X: 5000, Y: 1, Button: "left"}}},
+				{Type: EventIf, Cond: "!found", Else: []Event{{Type: EventKeyPress, KeyCode: 0x0D}}},
+				{Type: EventIf, Cond: "weekday =="},
+				{Type: EventWaitWindow, Window: &screen.Window{}, Timeout: MaxWaitTimeout + 1, Var: "9x"},
+				{Type: EventWaitWindow, Timeout: 1},
+				{Type: EventRepeat, Count: 1, Steps: []Event{{Type: EventKeyPress, KeyCode: 0x0D}},
+					Else: []Event{{Type: EventKeyPress, KeyCode: 0x0D}}},
+			},
+			want: []string{
+				"warning out_of_screen events[1].else[0]",
+				"error bad_condition events[3]",
+				"warning empty_block events[3]",
+				"error empty_window events[4]",
+				"error wait_timeout events[4]",
+				"error bad_variable events[4]",
+				"error empty_window events[5]",
+				"error not_block events[6]",
+			},
+			hasError: true,
+		},
+		{
+			name:     "unknown wheel orientation",
+			meta:     TaskMeta{Resolution: "1920x1080"},
+			events:   []Event{{Type: EventMouseWheel, X: 1, Y: 1, WheelDelta: 120, Orientation: "diagonal"}},
+			want:     []string{"error unknown_wheel events[0]"},
+			hasError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			task := &TaskData{Meta: tt.meta, Events: tt.events}
+			if tt.meta.TotalEvents == 0 {
+				task.Meta.TotalEvents = len(tt.events)
+			}
+
+			findings := task.Validate()
+			if got := findingKeys(findings); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Validate() = %v, want %v", got, tt.want)
+			}
+
+			err := task.Check()
+			if (err != nil) != tt.hasError {
+				t.Fatalf("Check() error = %v, want error %v", err, tt.hasError)
+			}
+			if err == nil {
+				return
+			}
+			if !errors.Is(err, ErrInvalidTask) {
+				t.Errorf("Check() error %v is not ErrInvalidTask", err)
+			}
+			var validationErr *ValidationError
+			if !errors.As(err, &validationErr) || !reflect.DeepEqual(validationErr.Findings, findings) {
+				t.Errorf("Check() error does not carry the findings: %#v", err)
+			}
+		})
+	}
+}
+
+func TestValidateVariables(t *testing.T) {
+	task := &TaskData{Events: []Event{
+		{Type: EventTypeText, Text: "{{branch}}-{{date}}"},
+		{Type: EventKeyDown, KeyCode: 0x41},
+		{Type: EventTypeText, Text: "{{网点}} {{branch}} {{\"{{x}}\"}}"},
+	}}
+
+	got := findingKeys(task.ValidateVariables(map[string]string{"branch": "0101"}))
+	want := []string{"error undefined_variable events[2]"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ValidateVariables() = %v, want %v", got, want)
+	}
+	if got := task.ValidateVariables(map[string]string{"branch": "0101", "网点": "朝阳"}); len(got) != 0 {
+		t.Errorf("ValidateVariables() with all values = %v, want none", got)
+	}
+
+	// repeat 的计数变量只在块内可用；给自定义变量指定格式在试展开时报错
+	task = &TaskData{Events: []Event{
+		{Type: EventRepeat, Count: 2, Var: "i", Steps: []Event{
+			{Type: EventTypeText, Text: "{{i}}"},
+			{Type: EventRepeat, Count: 2, Steps: []Event{{Type: EventTypeText, Text: "{{i}}-{{j}}"}}},
+		}},
+		{Type: EventTypeText, Text: "{{i}}"},
+		{Type: EventTypeText, Text: "{{date:MMDD}}"},
+	}}
+	got = findingKeys(task.ValidateVariables(map[string]string{"date": "今天"}))
+	want = []string{
+		"error undefined_variable events[0].steps[1].steps[0]",
+		"error undefined_variable events[1]",
+		"error bad_template events[2]",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ValidateVariables() = %v, want %v", got, want)
+	}
+
+	// 条件中的变量；wait_window 的结果只能在它之后使用，语法错误的条件由 Validate 报告
+	task = &TaskData{Events: []Event{
+		{Type: EventIf, Cond: "found && weekday == 1"},
+		{Type: EventWaitWindow, Window: &screen.Window{Title: "日报"}, Timeout: 1000, Var: "found"},
+		{Type: EventRepeat, Count: 2, Var: "i", Steps: []Event{
+			{Type: EventIf, Cond: `found && i > 1 && [客户] == "华东"`, Else: []Event{
+				{Type: EventTypeText, Text: "{{found}}{{i}}{{金额}}"},
+			}},
+		}},
+		{Type: EventIf, Cond: "i >"},
+	}}
+	got = findingKeys(task.ValidateVariables(map[string]string{"客户": "华东"}))
+	want = []string{
+		"error undefined_variable events[0]",
+		"error undefined_variable events[2].steps[0].else[0]",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ValidateVariables() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,115 @@
+package screen
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// ErrLayoutChanged 回放时的显示器布局与录制时不一致，且策略不允许缩放
+var ErrLayoutChanged = errors.New("monitor layout changed")
+
+// Monitor 一个显示器在虚拟桌面中的位置
+type Monitor struct {
+	Rect
+	Primary bool `json:"primary"`
+}
+
+// VirtualBounds 返回包含所有显示器的虚拟桌面区域
+func VirtualBounds(monitors []Monitor) Rect {
+	if len(monitors) == 0 {
+		return Rect{}
+	}
+	left, top := monitors[0].Left, monitors[0].Top
+	right, bottom := left+monitors[0].Width, top+monitors[0].Height
+	for _, m := range monitors[1:] {
+		left = min(left, m.Left)
+		top = min(top, m.Top)
+		right = max(right, m.Left+m.Width)
+		bottom = max(bottom, m.Top+m.Height)
+	}
+	return Rect{Left: left, Top: top, Width: right - left, Height: bottom - top}
+}
+
+// SameLayout 判断两组显示器布局是否一致（顺序无关）
+func SameLayout(a, b []Monitor) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for _, m := range a {
+		found := false
+		for _, other := range b {
+			if m == other {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// FitLayout 根据录制时和当前的显示器布局生成回放用的坐标换算
+// 未记录布局或布局一致时原样回放；不一致时 strict 为 true 返回 ErrLayoutChanged，
+// 否则按显示器逐一对应缩放：主屏对主屏，其余按顺序对应，多出的录制显示器映射到当前主屏
+func FitLayout(recorded, current []Monitor, strict bool) (Scaler, error) {
+	if len(recorded) == 0 || SameLayout(recorded, current) {
+		return Scaler{}, nil
+	}
+	if strict {
+		return Scaler{}, fmt.Errorf("%w: recorded with %d monitor(s) %s, current %d monitor(s) %s",
+			ErrLayoutChanged, len(recorded), VirtualBounds(recorded).Resolution(),
+			len(current), VirtualBounds(current).Resolution())
+	}
+	if len(current) == 0 {
+		return Scaler{}, fmt.Errorf("%w: no monitor detected", ErrLayoutChanged)
+	}
+
+	recordedPrimary, recordedOthers := splitPrimary(recorded)
+	currentPrimary, currentOthers := splitPrimary(current)
+
+	// 主屏放在第一组，坐标不落在任何录制显示器上时按主屏换算
+	scaler := Scaler{}
+	scaler.regions = append(scaler.regions, region{from: recordedPrimary.Rect, to: currentPrimary.Rect})
+	for i, m := range recordedOthers {
+		to := currentPrimary.Rect
+		if i < len(currentOthers) {
+			to = currentOthers[i].Rect
+		}
+		scaler.regions = append(scaler.regions, region{from: m.Rect, to: to})
+	}
+
+	return scaler, nil
+}
+
+// splitPrimary 拆分出主显示器和其余显示器；没有标记主屏时取第一个
+func splitPrimary(monitors []Monitor) (Monitor, []Monitor) {
+	primary := 0
+	for i, m := range monitors {
+		if m.Primary {
+			primary = i
+			break
+		}
+	}
+	others := make([]Monitor, 0, len(monitors)-1)
+	others = append(others, monitors[:primary]...)
+	others = append(others, monitors[primary+1:]...)
+	return monitors[primary], others
+}
+
+// Normalize 把虚拟桌面坐标换算为 SendInput 使用的 0–65535 归一化坐标
+// 配合 MOUSEEVENTF_ABSOLUTE|MOUSEEVENTF_VIRTUALDESK 使用，virtual 为当前虚拟桌面区域
+func Normalize(x, y int, virtual Rect) (int32, int32) {
+	return normalizeAxis(x, virtual.Left, virtual.Width), normalizeAxis(y, virtual.Top, virtual.Height)
+}
+
+// normalizeAxis 在一个方向上归一化：区域起点为 0，最后一个像素为 65535
+func normalizeAxis(v, start, size int) int32 {
+	if size <= 1 {
+		return 0
+	}
+	v = clamp(v, start, start+size-1)
+	return int32(math.Round(float64(v-start) * 65535 / float64(size-1)))
+}
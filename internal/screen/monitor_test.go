@@ -0,0 +1,99 @@
+package screen
+
+import (
+	"errors"
+	"testing"
+)
+
+var (
+	primaryFullHD = Monitor{Rect: Rect{Width: 1920, Height: 1080}, Primary: true}
+	leftFullHD    = Monitor{Rect: Rect{Left: -1920, Width: 1920, Height: 1080}}
+	laptopPrimary = Monitor{Rect: Rect{Width: 1366, Height: 768}, Primary: true}
+)
+
+func TestVirtualBounds(t *testing.T) {
+	got := VirtualBounds([]Monitor{primaryFullHD, leftFullHD})
+	want := Rect{Left: -1920, Top: 0, Width: 3840, Height: 1080}
+	if got != want {
+		t.Errorf("VirtualBounds() = %+v, want %+v", got, want)
+	}
+
+	if got := VirtualBounds(nil); got != (Rect{}) {
+		t.Errorf("VirtualBounds(nil) = %+v, want zero", got)
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	virtual := Rect{Left: -1920, Top: 0, Width: 3840, Height: 1080}
+
+	tests := []struct {
+		name         string
+		x, y         int
+		wantX, wantY int32
+	}{
+		{name: "top left of left monitor", x: -1920, y: 0, wantX: 0, wantY: 0},
+		{name: "bottom right of primary", x: 1919, y: 1079, wantX: 65535, wantY: 65535},
+		{name: "primary origin", x: 0, y: 540, wantX: 32776, wantY: 32798},
+		{name: "outside is clamped", x: 5000, y: -10, wantX: 65535, wantY: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotX, gotY := Normalize(tt.x, tt.y, virtual)
+			if gotX != tt.wantX || gotY != tt.wantY {
+				t.Errorf("Normalize(%d, %d) = (%d, %d), want (%d, %d)", tt.x, tt.y, gotX, gotY, tt.wantX, tt.wantY)
+			}
+		})
+	}
+
+	if x, y := Normalize(10, 10, Rect{Width: 1, Height: 1}); x != 0 || y != 0 {
+		t.Errorf("Normalize on degenerate rect = (%d, %d), want (0, 0)", x, y)
+	}
+}
+
+func TestSameLayout(t *testing.T) {
+	if !SameLayout([]Monitor{primaryFullHD, leftFullHD}, []Monitor{leftFullHD, primaryFullHD}) {
+		t.Error("SameLayout should ignore monitor order")
+	}
+	if SameLayout([]Monitor{primaryFullHD, leftFullHD}, []Monitor{primaryFullHD}) {
+		t.Error("SameLayout should detect a removed monitor")
+	}
+	if SameLayout([]Monitor{primaryFullHD}, []Monitor{laptopPrimary}) {
+		t.Error("SameLayout should detect a resolution change")
+	}
+}
+
+func TestFitLayout(t *testing.T) {
+	dual := []Monitor{primaryFullHD, leftFullHD}
+
+	scaler, err := FitLayout(dual, []Monitor{leftFullHD, primaryFullHD}, true)
+	if err != nil || !scaler.Identity() {
+		t.Errorf("FitLayout(same) = %+v, %v; want identity, nil", scaler, err)
+	}
+
+	if _, err := FitLayout(dual, []Monitor{primaryFullHD}, true); !errors.Is(err, ErrLayoutChanged) {
+		t.Errorf("FitLayout(changed, strict) error = %v, want ErrLayoutChanged", err)
+	}
+
+	// 副屏被拔掉：副屏上的点映射到当前主屏，主屏上的点按主屏缩放
+	scaler, err = FitLayout(dual, []Monitor{laptopPrimary}, false)
+	if err != nil {
+		t.Fatalf("FitLayout(changed, scale) unexpected error: %v", err)
+	}
+	if x, y := scaler.Map(-960, 540); x != 683 || y != 384 {
+		t.Errorf("secondary center mapped to (%d, %d), want (683, 384)", x, y)
+	}
+	if x, y := scaler.Map(1919, 0); x != 1365 || y != 0 {
+		t.Errorf("primary top right mapped to (%d, %d), want (1365, 0)", x, y)
+	}
+
+	// 副屏换到了右侧
+	rightFullHD := Monitor{Rect: Rect{Left: 1920, Width: 1920, Height: 1080}}
+	scaler, err = FitLayout(dual, []Monitor{primaryFullHD, rightFullHD}, false)
+	if err != nil {
+		t.Fatalf("FitLayout(moved, scale) unexpected error: %v", err)
+	}
+	if x, y := scaler.Map(-1900, 100); x != 1940 || y != 100 {
+		t.Errorf("moved secondary point mapped to (%d, %d), want (1940, 100)", x, y)
+	}
+}
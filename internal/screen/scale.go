@@ -0,0 +1,120 @@
+// Package screen 提供与平台无关的屏幕坐标换算，供录制和回放引擎使用
+package screen
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ErrResolutionMismatch 回放时的屏幕与录制时不一致，且策略不允许缩放
+var ErrResolutionMismatch = errors.New("screen resolution mismatch")
+
+// Rect 屏幕上的矩形区域，Left/Top 可以为负（副屏位于主屏左侧或上方）
+type Rect struct {
+	Left   int `json:"left"`
+	Top    int `json:"top"`
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+// ParseResolution 解析 "1920x1080" 格式的分辨率，返回以原点为左上角的区域
+func ParseResolution(resolution string) (Rect, error) {
+	widthStr, heightStr, found := strings.Cut(resolution, "x")
+	if !found {
+		return Rect{}, fmt.Errorf("invalid resolution %q", resolution)
+	}
+	width, err := strconv.Atoi(widthStr)
+	if err != nil || width <= 0 {
+		return Rect{}, fmt.Errorf("invalid resolution %q", resolution)
+	}
+	height, err := strconv.Atoi(heightStr)
+	if err != nil || height <= 0 {
+		return Rect{}, fmt.Errorf("invalid resolution %q", resolution)
+	}
+	return Rect{Width: width, Height: height}, nil
+}
+
+// Resolution 返回 "宽x高" 格式的分辨率
+func (r Rect) Resolution() string {
+	return fmt.Sprintf("%dx%d", r.Width, r.Height)
+}
+
+// Contains 判断点是否在区域内
+func (r Rect) Contains(x, y int) bool {
+	return x >= r.Left && x < r.Left+r.Width && y >= r.Top && y < r.Top+r.Height
+}
+
+// Scaler 把录制区域内的坐标按比例换算到回放区域
+// 可包含多组区域对应关系（每个显示器一组），零值表示不做换算
+type Scaler struct {
+	regions []region
+}
+
+// region 一组录制区域到回放区域的对应关系
+type region struct {
+	from Rect
+	to   Rect
+}
+
+// NewScaler 创建从 from 区域到 to 区域的坐标换算
+func NewScaler(from, to Rect) Scaler {
+	if from == to || from.Width <= 0 || from.Height <= 0 || to.Width <= 0 || to.Height <= 0 {
+		return Scaler{}
+	}
+	return Scaler{regions: []region{{from: from, to: to}}}
+}
+
+// Identity 判断换算是否为原样输出
+func (s Scaler) Identity() bool {
+	return len(s.regions) == 0
+}
+
+// Map 换算单个坐标，结果限制在目标区域内
+// 坐标落在哪个录制区域就按该区域换算，都不在时按第一组（主屏）换算
+func (s Scaler) Map(x, y int) (int, int) {
+	if s.Identity() {
+		return x, y
+	}
+	r := s.regions[0]
+	for _, candidate := range s.regions {
+		if candidate.from.Contains(x, y) {
+			r = candidate
+			break
+		}
+	}
+	return mapAxis(x, r.from.Left, r.from.Width, r.to.Left, r.to.Width),
+		mapAxis(y, r.from.Top, r.from.Height, r.to.Top, r.to.Height)
+}
+
+// mapAxis 在一个方向上按比例换算坐标
+func mapAxis(v, fromStart, fromSize, toStart, toSize int) int {
+	scaled := float64(v-fromStart) * float64(toSize) / float64(fromSize)
+	mapped := toStart + int(math.Round(scaled))
+	return clamp(mapped, toStart, toStart+toSize-1)
+}
+
+func clamp(v, low, high int) int {
+	if v < low {
+		return low
+	}
+	if v > high {
+		return high
+	}
+	return v
+}
+
+// Fit 根据录制区域和当前区域生成回放用的坐标换算
+// 区域一致时原样回放；不一致时 strict 为 true 返回 ErrResolutionMismatch，否则按比例缩放
+func Fit(recorded, current Rect, strict bool) (Scaler, error) {
+	if recorded == current {
+		return Scaler{}, nil
+	}
+	if strict {
+		return Scaler{}, fmt.Errorf("%w: recorded on %s, current screen is %s",
+			ErrResolutionMismatch, recorded.Resolution(), current.Resolution())
+	}
+	return NewScaler(recorded, current), nil
+}
@@ -0,0 +1,87 @@
+package screen
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseResolution(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    Rect
+		wantErr bool
+	}{
+		{input: "1920x1080", want: Rect{Width: 1920, Height: 1080}},
+		{input: "1366x768", want: Rect{Width: 1366, Height: 768}},
+		{input: "", wantErr: true},
+		{input: "1920", wantErr: true},
+		{input: "0x1080", wantErr: true},
+		{input: "axb", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseResolution(tt.input)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseResolution(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseResolution(%q) = %+v, want %+v", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestScalerMap(t *testing.T) {
+	fullHD := Rect{Width: 1920, Height: 1080}
+	laptop := Rect{Width: 1366, Height: 768}
+
+	tests := []struct {
+		name         string
+		from, to     Rect
+		x, y         int
+		wantX, wantY int
+	}{
+		{name: "identity", from: fullHD, to: fullHD, x: 812, y: 440, wantX: 812, wantY: 440},
+		{name: "origin", from: fullHD, to: laptop, x: 0, y: 0, wantX: 0, wantY: 0},
+		{name: "center", from: fullHD, to: laptop, x: 960, y: 540, wantX: 683, wantY: 384},
+		{name: "bottom right stays inside", from: fullHD, to: laptop, x: 1919, y: 1079, wantX: 1365, wantY: 767},
+		{name: "upscale", from: laptop, to: fullHD, x: 683, y: 384, wantX: 960, wantY: 540},
+		{
+			name: "offset regions",
+			from: Rect{Left: -1920, Top: 0, Width: 3840, Height: 1080},
+			to:   Rect{Left: 0, Top: 0, Width: 1920, Height: 540},
+			x:    -1920, y: 1078, wantX: 0, wantY: 539,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotX, gotY := NewScaler(tt.from, tt.to).Map(tt.x, tt.y)
+			if gotX != tt.wantX || gotY != tt.wantY {
+				t.Errorf("Map(%d, %d) = (%d, %d), want (%d, %d)", tt.x, tt.y, gotX, gotY, tt.wantX, tt.wantY)
+			}
+		})
+	}
+}
+
+func TestFit(t *testing.T) {
+	fullHD := Rect{Width: 1920, Height: 1080}
+	laptop := Rect{Width: 1366, Height: 768}
+
+	scaler, err := Fit(fullHD, fullHD, true)
+	if err != nil || !scaler.Identity() {
+		t.Errorf("Fit(same, strict) = %+v, %v; want identity, nil", scaler, err)
+	}
+
+	if _, err := Fit(fullHD, laptop, true); !errors.Is(err, ErrResolutionMismatch) {
+		t.Errorf("Fit(mismatch, strict) error = %v, want ErrResolutionMismatch", err)
+	}
+
+	scaler, err = Fit(fullHD, laptop, false)
+	if err != nil {
+		t.Fatalf("Fit(mismatch, scale) unexpected error: %v", err)
+	}
+	if x, y := scaler.Map(1920/2, 1080/2); x != 683 || y != 384 {
+		t.Errorf("scaled center = (%d, %d), want (683, 384)", x, y)
+	}
+}
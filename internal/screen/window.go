@@ -0,0 +1,90 @@
+package screen
+
+import "strings"
+
+// Window 顶层窗口的标识和位置
+type Window struct {
+	Title string `json:"title"`
+	Class string `json:"class"`
+	Rect
+}
+
+// WindowFinder 枚举当前可见的顶层窗口
+// Windows 下由回放引擎通过 EnumWindows 实现，测试中可替换为固定列表
+type WindowFinder interface {
+	Windows() ([]Window, error)
+}
+
+// 窗口匹配程度，数值越大越可信
+const (
+	matchNone = iota
+	matchClass
+	matchTitleContains
+	matchExact
+)
+
+// MatchWindow 在候选窗口中查找与录制时目标窗口最匹配的一个
+// 类名必须一致（目标类名为空时不限类名，但标题必须匹配）；标题完全一致优先，其次是互相包含（如标题中带日期或用户名）；
+// 匹配程度相同时取位置离录制时最近的窗口
+func MatchWindow(target Window, candidates []Window) (Window, bool) {
+	var best Window
+	bestScore, bestDistance := matchNone, 0
+
+	for _, candidate := range candidates {
+		score := windowMatchScore(target, candidate)
+		if score == matchNone {
+			continue
+		}
+		distance := abs(candidate.Left-target.Left) + abs(candidate.Top-target.Top)
+		if score > bestScore || (score == bestScore && distance < bestDistance) {
+			best, bestScore, bestDistance = candidate, score, distance
+		}
+	}
+
+	return best, bestScore != matchNone
+}
+
+// windowMatchScore 计算候选窗口与目标窗口的匹配程度
+func windowMatchScore(target, candidate Window) int {
+	// 只有标题的目标（如从 AutoHotkey 导入）不限类名
+	anyClass := target.Class == ""
+	if !anyClass && target.Class != candidate.Class {
+		return matchNone
+	}
+	switch {
+	case anyClass && target.Title == "":
+		return matchNone
+	case target.Title == candidate.Title:
+		return matchExact
+	case target.Title != "" && candidate.Title != "" &&
+		(strings.Contains(candidate.Title, target.Title) || strings.Contains(target.Title, candidate.Title)):
+		return matchTitleContains
+	case !anyClass && (target.Title == "" || candidate.Title == ""):
+		return matchClass
+	default:
+		return matchNone
+	}
+}
+
+// LocateClick 查找与录制时目标窗口匹配的当前窗口，把窗口内相对坐标换算为屏幕坐标
+// 找不到匹配窗口，或相对坐标超出当前窗口范围（窗口被缩小）时返回 false，调用方应退回绝对坐标
+func LocateClick(finder WindowFinder, target Window, relX, relY int) (int, int, bool, error) {
+	candidates, err := finder.Windows()
+	if err != nil {
+		return 0, 0, false, err
+	}
+
+	window, ok := MatchWindow(target, candidates)
+	if !ok || relX < 0 || relY < 0 || relX >= window.Width || relY >= window.Height {
+		return 0, 0, false, nil
+	}
+
+	return window.Left + relX, window.Top + relY, true, nil
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
@@ -0,0 +1,128 @@
+package screen
+
+import (
+	"errors"
+	"testing"
+)
+
+// fakeFinder 返回固定的窗口列表
+type fakeFinder struct {
+	windows []Window
+	err     error
+}
+
+func (f fakeFinder) Windows() ([]Window, error) {
+	return f.windows, f.err
+}
+
+func TestMatchWindow(t *testing.T) {
+	target := Window{Title: "核心业务系统", Class: "CoreClient", Rect: Rect{Left: 100, Top: 100, Width: 1200, Height: 800}}
+
+	tests := []struct {
+		name       string
+		candidates []Window
+		wantTitle  string
+		wantLeft   int
+		wantOK     bool
+	}{
+		{
+			name: "exact title beats contained title",
+			candidates: []Window{
+				{Title: "核心业务系统 - 张三", Class: "CoreClient", Rect: Rect{Left: 100, Top: 100}},
+				{Title: "核心业务系统", Class: "CoreClient", Rect: Rect{Left: 400, Top: 300}},
+			},
+			wantTitle: "核心业务系统", wantLeft: 400, wantOK: true,
+		},
+		{
+			name: "title with suffix matches",
+			candidates: []Window{
+				{Title: "记事本", Class: "Notepad"},
+				{Title: "核心业务系统 2024-12-02", Class: "CoreClient", Rect: Rect{Left: 130, Top: 90}},
+			},
+			wantTitle: "核心业务系统 2024-12-02", wantLeft: 130, wantOK: true,
+		},
+		{
+			name: "nearest window wins a tie",
+			candidates: []Window{
+				{Title: "核心业务系统", Class: "CoreClient", Rect: Rect{Left: 900, Top: 600}},
+				{Title: "核心业务系统", Class: "CoreClient", Rect: Rect{Left: 120, Top: 110}},
+			},
+			wantTitle: "核心业务系统", wantLeft: 120, wantOK: true,
+		},
+		{
+			name: "class must match",
+			candidates: []Window{
+				{Title: "核心业务系统", Class: "Chrome_WidgetWin_1"},
+			},
+		},
+		{
+			name: "unrelated title does not match",
+			candidates: []Window{
+				{Title: "登录", Class: "CoreClient"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := MatchWindow(target, tt.candidates)
+			if ok != tt.wantOK {
+				t.Fatalf("MatchWindow() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if ok && (got.Title != tt.wantTitle || got.Left != tt.wantLeft) {
+				t.Errorf("MatchWindow() = %q at %d, want %q at %d", got.Title, got.Left, tt.wantTitle, tt.wantLeft)
+			}
+		})
+	}
+}
+
+func TestMatchWindowWithoutClass(t *testing.T) {
+	candidates := []Window{
+		{Title: "无标题 - 记事本", Class: "Notepad"},
+		{Title: "", Class: "Shell_TrayWnd"},
+	}
+
+	got, ok := MatchWindow(Window{Title: "记事本"}, candidates)
+	if !ok || got.Class != "Notepad" {
+		t.Errorf("MatchWindow(title only) = %+v, %v, want the Notepad window", got, ok)
+	}
+
+	if got, ok := MatchWindow(Window{}, candidates); ok {
+		t.Errorf("MatchWindow(empty target) = %+v, want no match", got)
+	}
+}
+
+func TestLocateClick(t *testing.T) {
+	target := Window{Title: "核心业务系统", Class: "CoreClient", Rect: Rect{Left: 100, Top: 100, Width: 1200, Height: 800}}
+	moved := Window{Title: "核心业务系统", Class: "CoreClient", Rect: Rect{Left: 160, Top: 80, Width: 1200, Height: 800}}
+	shrunk := Window{Title: "核心业务系统", Class: "CoreClient", Rect: Rect{Left: 100, Top: 100, Width: 400, Height: 300}}
+
+	tests := []struct {
+		name         string
+		finder       WindowFinder
+		relX, relY   int
+		wantX, wantY int
+		wantOK       bool
+		wantErr      bool
+	}{
+		{name: "window moved", finder: fakeFinder{windows: []Window{moved}}, relX: 712, relY: 340, wantX: 872, wantY: 420, wantOK: true},
+		{name: "window missing", finder: fakeFinder{}, relX: 712, relY: 340},
+		{name: "click outside shrunk window", finder: fakeFinder{windows: []Window{shrunk}}, relX: 712, relY: 340},
+		{name: "finder error", finder: fakeFinder{err: errors.New("boom")}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			x, y, ok, err := LocateClick(tt.finder, target, tt.relX, tt.relY)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LocateClick() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if ok != tt.wantOK {
+				t.Fatalf("LocateClick() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if ok && (x != tt.wantX || y != tt.wantY) {
+				t.Errorf("LocateClick() = (%d, %d), want (%d, %d)", x, y, tt.wantX, tt.wantY)
+			}
+		})
+	}
+}
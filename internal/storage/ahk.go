@@ -0,0 +1,936 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"dailyflow/internal/model"
+	"dailyflow/internal/screen"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// AHKFileExt AutoHotkey 脚本扩展名
+const AHKFileExt = ".ahk"
+
+// AHKIssue 导入 AutoHotkey 脚本时无法转换的一行，Line 从 1 开始
+type AHKIssue struct {
+	Line   int
+	Text   string // 原始行内容
+	Reason string
+}
+
+func (i AHKIssue) String() string {
+	return fmt.Sprintf("line %d: %s (%s)", i.Line, i.Text, i.Reason)
+}
+
+// ExportAHK 把任务导出为 AutoHotkey v2 脚本
+// 坐标一律按屏幕绝对坐标输出（按窗口定位的点击会在注释中注明目标窗口），无法表示的事件输出为注释
+func ExportAHK(task *model.TaskData) []byte {
+	var b bytes.Buffer
+	b.WriteString("; DailyFlow 导出的 AutoHotkey 脚本\n")
+	if task.Meta.Resolution != "" {
+		fmt.Fprintf(&b, "; 录制分辨率: %s\n", task.Meta.Resolution)
+	}
+	b.WriteString("#Requires AutoHotkey v2.0\n")
+	b.WriteString("CoordMode \"Mouse\", \"Screen\"\n")
+	b.WriteString("SendMode \"Input\"\n")
+	b.WriteString("\n")
+
+	writeAHKSteps(&b, task.Events, "")
+	return b.Bytes()
+}
+
+// writeAHKSteps 输出同一层级的步骤，repeat 块输出为 Loop，if 块输出为 if / else，块内多缩进四个空格
+func writeAHKSteps(b *bytes.Buffer, events []model.Event, indent string) {
+	for _, event := range events {
+		if event.Delay > 0 {
+			fmt.Fprintf(b, "%sSleep %d\n", indent, event.Delay)
+		}
+		if event.Type == model.EventRepeat {
+			fmt.Fprintf(b, "%sLoop %d {", indent, event.Count)
+			if event.Var != "" {
+				fmt.Fprintf(b, "  ; {{%s}} 对应 A_Index", event.Var)
+			}
+			b.WriteString("\n")
+			writeAHKSteps(b, event.Steps, indent+"    ")
+			b.WriteString(indent + "}\n")
+			continue
+		}
+		if event.Type == model.EventIf {
+			// 条件中的变量和日期函数在 AutoHotkey 中不存在，原样输出并提示
+			fmt.Fprintf(b, "%sif (%s) {  ; DailyFlow 条件，请改写其中的变量\n", indent, event.Cond)
+			writeAHKSteps(b, event.Steps, indent+"    ")
+			if len(event.Else) > 0 {
+				b.WriteString(indent + "} else {\n")
+				writeAHKSteps(b, event.Else, indent+"    ")
+			}
+			b.WriteString(indent + "}\n")
+			continue
+		}
+		b.WriteString(indent + ahkStatement(event))
+		b.WriteString("\n")
+	}
+}
+
+// ahkStatement 输出单个事件对应的 AutoHotkey 语句
+func ahkStatement(event model.Event) string {
+	switch event.Type {
+	case model.EventMouseMove:
+		return fmt.Sprintf("MouseMove %d, %d, 0", event.X, event.Y)
+	case model.EventMouseClick, model.EventMouseDown, model.EventMouseUp:
+		button, ok := ahkButtons[event.Button]
+		if !ok {
+			break
+		}
+		switch event.Type {
+		case model.EventMouseDown:
+			button += " Down"
+		case model.EventMouseUp:
+			button += " Up"
+		}
+		line := fmt.Sprintf("Click %d, %d", event.X, event.Y)
+		if button != "Left" {
+			line += ", " + ahkQuote(button)
+		}
+		if event.Window != nil {
+			line += fmt.Sprintf("  ; 窗口: %s (%s)", event.Window.Title, event.Window.Class)
+		}
+		return line
+	case model.EventMouseWheel:
+		direction := "WheelUp"
+		switch {
+		case event.Orientation == model.WheelHorizontal && event.WheelDelta >= 0:
+			direction = "WheelRight"
+		case event.Orientation == model.WheelHorizontal:
+			direction = "WheelLeft"
+		case event.WheelDelta < 0:
+			direction = "WheelDown"
+		}
+		notches := (abs(event.WheelDelta) + model.WheelDeltaStep/2) / model.WheelDeltaStep
+		if notches == 0 {
+			notches = 1
+		}
+		return fmt.Sprintf("Click %d, %d, %s, %d", event.X, event.Y, ahkQuote(direction), notches)
+	case model.EventKeyDown:
+		return fmt.Sprintf("Send %s", ahkQuote("{"+ahkKeyName(event.KeyCode)+" down}"))
+	case model.EventKeyUp:
+		return fmt.Sprintf("Send %s", ahkQuote("{"+ahkKeyName(event.KeyCode)+" up}"))
+	case model.EventKeyPress:
+		return fmt.Sprintf("Send %s", ahkQuote("{"+ahkKeyName(event.KeyCode)+"}"))
+	case model.EventTypeText:
+		line := "SendText " + ahkQuote(event.Text)
+		if model.HasTemplate(event.Text) {
+			// AutoHotkey 不认识 DailyFlow 的模板变量，原样输出并提示
+			line += "  ; 含模板变量，请改为 FormatTime 等语句"
+		}
+		return line
+	case model.EventSecret:
+		// 敏感内容只在 DailyFlow 的凭据存储中，脚本中需要自行补充
+		return fmt.Sprintf("; 敏感输入「%s」未导出，请在此处自行输入", event.Secret)
+	case model.EventCall:
+		// 被调用的任务需要单独导出，脚本中无法引用任务库
+		return fmt.Sprintf("; 调用任务「%s」，请单独导出该任务并把其语句合并到这里", event.Task)
+	case model.EventWaitWindow:
+		if event.Window == nil {
+			break
+		}
+		title := event.Window.Title
+		if event.Window.Class != "" {
+			title = strings.TrimSpace(title + " ahk_class " + event.Window.Class)
+		}
+		seconds := strconv.FormatFloat(float64(event.Timeout)/1000, 'f', -1, 64)
+		if event.Var != "" {
+			return fmt.Sprintf("%s := WinWait(%s, , %s) != 0", event.Var, ahkQuote(title), seconds)
+		}
+		return fmt.Sprintf("WinWait %s, , %s", ahkQuote(title), seconds)
+	}
+
+	raw, _ := json.Marshal(event)
+	return "; 不支持的事件: " + string(raw)
+}
+
+// ahkButtons DailyFlow 鼠标按键到 AutoHotkey 按键名
+var ahkButtons = map[string]string{
+	"left":   "Left",
+	"right":  "Right",
+	"middle": "Middle",
+}
+
+// ahkQuote 输出 AutoHotkey v2 双引号字符串
+func ahkQuote(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			b.WriteString("`\"")
+		case '`':
+			b.WriteString("``")
+		case ';':
+			b.WriteString("`;")
+		case '\n':
+			b.WriteString("`n")
+		case '\r':
+			b.WriteString("`r")
+		case '\t':
+			b.WriteString("`t")
+		default:
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// ahkKeyNames 虚拟键码对应的 AutoHotkey 按键名（字母、数字、小键盘数字和 F 键另行处理）
+var ahkKeyNames = map[int]string{
+	0x08: "Backspace",
+	0x09: "Tab",
+	0x0D: "Enter",
+	0x10: "Shift",
+	0x11: "Ctrl",
+	0x12: "Alt",
+	0x13: "Pause",
+	0x14: "CapsLock",
+	0x1B: "Esc",
+	0x20: "Space",
+	0x21: "PgUp",
+	0x22: "PgDn",
+	0x23: "End",
+	0x24: "Home",
+	0x25: "Left",
+	0x26: "Up",
+	0x27: "Right",
+	0x28: "Down",
+	0x2C: "PrintScreen",
+	0x2D: "Insert",
+	0x2E: "Delete",
+	0x5B: "LWin",
+	0x5C: "RWin",
+	0x5D: "AppsKey",
+	0x6A: "NumpadMult",
+	0x6B: "NumpadAdd",
+	0x6D: "NumpadSub",
+	0x6E: "NumpadDot",
+	0x6F: "NumpadDiv",
+	0x90: "NumLock",
+	0x91: "ScrollLock",
+	0xA0: "LShift",
+	0xA1: "RShift",
+	0xA2: "LCtrl",
+	0xA3: "RCtrl",
+	0xA4: "LAlt",
+	0xA5: "RAlt",
+}
+
+// ahkKeyAliases 导入时额外接受的 AutoHotkey 按键名
+var ahkKeyAliases = map[string]int{
+	"control":  0x11,
+	"lcontrol": 0xA2,
+	"rcontrol": 0xA3,
+	"return":   0x0D,
+	"escape":   0x1B,
+	"bs":       0x08,
+	"del":      0x2E,
+	"ins":      0x2D,
+	"pageup":   0x21,
+	"pagedown": 0x22,
+}
+
+// ahkKeyName 返回虚拟键码的 AutoHotkey 按键名，没有名称的键码写作 vkXX
+func ahkKeyName(code int) string {
+	switch {
+	case code >= 'A' && code <= 'Z':
+		return string(rune(code - 'A' + 'a'))
+	case code >= '0' && code <= '9':
+		return string(rune(code))
+	case code >= 0x60 && code <= 0x69:
+		return "Numpad" + strconv.Itoa(code-0x60)
+	case code >= 0x70 && code <= 0x87:
+		return "F" + strconv.Itoa(code-0x6F)
+	}
+	if name, ok := ahkKeyNames[code]; ok {
+		return name
+	}
+	return fmt.Sprintf("vk%02X", code)
+}
+
+// parseAHKKey 解析 AutoHotkey 按键名（不区分大小写）或 vkXX
+func parseAHKKey(name string) (int, bool) {
+	lower := strings.ToLower(name)
+	if len(lower) == 1 {
+		c := lower[0]
+		switch {
+		case c >= 'a' && c <= 'z':
+			return int(c-'a') + 'A', true
+		case c >= '0' && c <= '9':
+			return int(c), true
+		}
+		// 标点键沿用脚本格式的按键名称
+		if code, err := ParseKeyName(lower); err == nil {
+			return code, true
+		}
+		return 0, false
+	}
+	for code, keyName := range ahkKeyNames {
+		if strings.ToLower(keyName) == lower {
+			return code, true
+		}
+	}
+	if code, ok := ahkKeyAliases[lower]; ok {
+		return code, true
+	}
+	if n, err := strconv.Atoi(strings.TrimPrefix(lower, "numpad")); err == nil && strings.HasPrefix(lower, "numpad") && n >= 0 && n <= 9 {
+		return 0x60 + n, true
+	}
+	if n, err := strconv.Atoi(strings.TrimPrefix(lower, "f")); err == nil && strings.HasPrefix(lower, "f") && n >= 1 && n <= 24 {
+		return 0x6F + n, true
+	}
+	if strings.HasPrefix(lower, "vk") && len(lower) >= 4 {
+		// vkXX 或 vkXXscYYY
+		if code, err := strconv.ParseInt(lower[2:4], 16, 32); err == nil {
+			return int(code), true
+		}
+	}
+	return 0, false
+}
+
+// ahkImporter 保存导入过程中的状态
+type ahkImporter struct {
+	task      *model.TaskData
+	issues    []AHKIssue
+	pending   int            // 尚未分配给事件的 Sleep 时长
+	sleepLine int            // 未分配的 Sleep 所在行
+	relative  bool           // 鼠标坐标相对于活动窗口（CoordMode Mouse 不是 Screen）
+	window    *screen.Window // 最近一次 WinActivate 的目标窗口
+	last      *model.Event   // 最近一次鼠标事件，用于省略坐标的 Click
+}
+
+// ahkIgnored 不影响导入结果、直接跳过的指令和命令
+var ahkIgnored = map[string]bool{
+	"sendmode":               true,
+	"setworkingdir":          true,
+	"settitlematchmode":      true,
+	"setkeydelay":            true,
+	"setmousedelay":          true,
+	"setdefaultmousespeed":   true,
+	"detecthiddenwindows":    true,
+	"return":                 true,
+	"exit":                   true,
+	"exitapp":                true,
+	"setbatchlines":          true,
+	"setcontroldelay":        true,
+	"setwindelay":            true,
+	"persistent":             true,
+	"sendlevel":              true,
+	"setstorecapslockmode":   true,
+	"listlines":              true,
+	"keyhistory":             true,
+	"#requires":              true,
+	"#noenv":                 true,
+	"#singleinstance":        true,
+	"#warn":                  true,
+	"#notrayicon":            true,
+	"#persistent":            true,
+	"#usehook":               true,
+	"#installkeybdhook":      true,
+	"#installmousehook":      true,
+	"#maxthreadsperhotkey":   true,
+	"#keyhistory":            true,
+	"#maxhotkeysperinterval": true,
+}
+
+// ImportAHK 把 AutoHotkey 脚本（v1 或 v2 语法）转换为任务
+// 支持 Click、MouseMove、Send 系列、Sleep、WinActivate 和 CoordMode，
+// 其他语句跳过并作为 AHKIssue 返回；返回的任务不含录制分辨率
+func ImportAHK(data []byte) (*model.TaskData, []AHKIssue) {
+	imp := &ahkImporter{task: model.NewTaskData("")}
+	// v1 默认鼠标坐标相对活动窗口，v2 默认相对客户区，都不是屏幕坐标
+	imp.relative = true
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	line, inComment := 0, false
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if line == 1 {
+			text = strings.TrimPrefix(text, "\ufeff")
+		}
+
+		// /* ... */ 块注释
+		if inComment {
+			if strings.HasPrefix(text, "*/") || strings.HasSuffix(text, "*/") {
+				inComment = false
+			}
+			continue
+		}
+		if strings.HasPrefix(text, "/*") {
+			inComment = !strings.HasSuffix(text, "*/")
+			continue
+		}
+
+		text = stripAHKComment(text)
+		if text == "" {
+			continue
+		}
+		if err := imp.statement(text); err != nil {
+			imp.issues = append(imp.issues, AHKIssue{Line: line, Text: text, Reason: err.Error()})
+		} else if imp.pending != 0 && imp.sleepLine == 0 {
+			imp.sleepLine = line
+		}
+	}
+
+	if imp.pending != 0 {
+		imp.issues = append(imp.issues, AHKIssue{Line: imp.sleepLine, Text: "Sleep", Reason: "not followed by any supported step, dropped"})
+	}
+
+	imp.task.Meta.TotalEvents = len(imp.task.Events)
+	return imp.task, imp.issues
+}
+
+// stripAHKComment 去掉行尾注释：行首或空白之后、引号之外的分号
+func stripAHKComment(text string) string {
+	var quote rune
+	escaped := false
+	for i, r := range text {
+		switch {
+		case escaped:
+			escaped = false
+		case r == '`':
+			escaped = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == ';' && (i == 0 || text[i-1] == ' ' || text[i-1] == '\t'):
+			return strings.TrimSpace(text[:i])
+		}
+	}
+	return text
+}
+
+// statement 解析一条语句
+func (imp *ahkImporter) statement(text string) error {
+	name, rest := splitAHKCommand(text)
+	lower := strings.ToLower(name)
+
+	if ahkIgnored[lower] {
+		return nil
+	}
+
+	switch lower {
+	case "sleep":
+		ms, err := strconv.Atoi(unquoteAHK(rest))
+		if err != nil || ms < 0 {
+			return fmt.Errorf("Sleep needs a literal number of milliseconds")
+		}
+		imp.pending += ms
+		return nil
+	case "coordmode":
+		args := splitAHKArgs(rest)
+		if len(args) == 0 || !strings.EqualFold(unquoteAHK(args[0]), "Mouse") {
+			return nil // 只有鼠标坐标模式影响导入
+		}
+		mode := "screen"
+		if len(args) > 1 {
+			mode = strings.ToLower(unquoteAHK(args[1]))
+		}
+		switch mode {
+		case "screen":
+			imp.relative = false
+		case "window", "client":
+			imp.relative = true
+		default:
+			return fmt.Errorf("unknown CoordMode %q", mode)
+		}
+		return nil
+	case "winactivate":
+		args := splitAHKArgs(rest)
+		if len(args) == 0 {
+			return fmt.Errorf("WinActivate without a window title is not supported")
+		}
+		window, err := parseWinTitle(unquoteAHK(args[0]))
+		if err != nil {
+			return err
+		}
+		if len(args) > 1 {
+			return fmt.Errorf("WinText and exclusion parameters are not supported")
+		}
+		imp.window = window
+		return nil
+	case "mousemove":
+		return imp.mouseMove(splitAHKArgs(rest))
+	case "click":
+		return imp.click(splitAHKArgs(rest))
+	case "send", "sendinput", "sendevent", "sendplay", "sendraw", "sendtext":
+		arg := rest
+		if strings.HasPrefix(arg, "\"") || strings.HasPrefix(arg, "'") {
+			var err error
+			if arg, err = parseAHKString(arg); err != nil {
+				return err
+			}
+		} else {
+			arg = unescapeAHK(arg)
+		}
+		events, err := parseAHKSend(arg, lower == "sendraw" || lower == "sendtext")
+		if err != nil {
+			return err
+		}
+		for _, event := range events {
+			imp.add(event)
+		}
+		return nil
+	}
+
+	switch {
+	case strings.Contains(text, "::"):
+		return fmt.Errorf("hotkeys and hotstrings are not supported")
+	case strings.HasSuffix(text, ":"):
+		return fmt.Errorf("labels are not supported")
+	case name == "":
+		return fmt.Errorf("unsupported syntax")
+	default:
+		return fmt.Errorf("unsupported command %q", name)
+	}
+}
+
+// add 追加事件，并把累计的 Sleep 时长分配给它
+func (imp *ahkImporter) add(event model.Event) {
+	event.Delay += imp.pending
+	imp.pending = 0
+	imp.sleepLine = 0
+	imp.task.AddEvent(event)
+}
+
+// mouseMove 解析 MouseMove X, Y [, Speed, R]
+func (imp *ahkImporter) mouseMove(args []string) error {
+	if len(args) < 2 {
+		return fmt.Errorf("MouseMove needs X and Y")
+	}
+	if len(args) > 3 && strings.EqualFold(unquoteAHK(args[3]), "R") {
+		return fmt.Errorf("relative MouseMove is not supported")
+	}
+	if imp.relative {
+		return fmt.Errorf("MouseMove with window-relative coordinates is not supported, use CoordMode \"Mouse\", \"Screen\"")
+	}
+	x, errX := strconv.Atoi(unquoteAHK(args[0]))
+	y, errY := strconv.Atoi(unquoteAHK(args[1]))
+	if errX != nil || errY != nil {
+		return fmt.Errorf("MouseMove needs literal coordinates")
+	}
+	event := model.Event{Type: model.EventMouseMove, X: x, Y: y, Button: "none"}
+	imp.add(event)
+	imp.last = &event
+	return nil
+}
+
+// click 解析 Click 的各个组成部分：坐标、按键、次数、Down/Up、滚轮
+func (imp *ahkImporter) click(args []string) error {
+	var numbers []int
+	button, action, wheel := "left", "", ""
+	for _, arg := range args {
+		for _, part := range strings.Fields(unquoteAHK(arg)) {
+			if n, err := strconv.Atoi(part); err == nil {
+				numbers = append(numbers, n)
+				continue
+			}
+			switch strings.ToLower(part) {
+			case "left", "l":
+				button = "left"
+			case "right", "r":
+				button = "right"
+			case "middle", "m":
+				button = "middle"
+			case "down", "d":
+				action = model.EventMouseDown
+			case "up", "u":
+				action = model.EventMouseUp
+			case "wheelup", "wu", "wheeldown", "wd", "wheelleft", "wl", "wheelright", "wr":
+				wheel = strings.ToLower(part)
+			case "rel", "relative":
+				return fmt.Errorf("relative Click is not supported")
+			default:
+				return fmt.Errorf("unsupported Click option %q", part)
+			}
+		}
+	}
+
+	// 一个数字是次数，两个数字是坐标，三个数字是坐标加次数
+	count := 1
+	var event model.Event
+	switch len(numbers) {
+	case 1:
+		count = numbers[0]
+	case 2, 3:
+		event.X, event.Y = numbers[0], numbers[1]
+		if len(numbers) == 3 {
+			count = numbers[2]
+		}
+	}
+
+	if len(numbers) < 2 {
+		// 省略坐标：在上一次鼠标事件的位置点击
+		if imp.last == nil {
+			return fmt.Errorf("Click without coordinates needs an earlier Click or MouseMove")
+		}
+		event.X, event.Y = imp.last.X, imp.last.Y
+		event.Window, event.RelX, event.RelY = imp.last.Window, imp.last.RelX, imp.last.RelY
+	} else if imp.relative {
+		// 相对坐标：点击 WinActivate 的目标窗口
+		if imp.window == nil {
+			return fmt.Errorf("Click coordinates are relative to the active window, use WinActivate first or CoordMode \"Mouse\", \"Screen\"")
+		}
+		window := *imp.window
+		event.Window, event.RelX, event.RelY = &window, event.X, event.Y
+	}
+
+	if wheel != "" {
+		if event.Window != nil {
+			return fmt.Errorf("wheel with window-relative coordinates is not supported")
+		}
+		event.Type, event.Button = model.EventMouseWheel, "none"
+		event.Orientation = model.WheelVertical
+		if wheel == "wheelleft" || wheel == "wl" || wheel == "wheelright" || wheel == "wr" {
+			event.Orientation = model.WheelHorizontal
+		}
+		event.WheelDelta = count * model.WheelDeltaStep
+		if wheel == "wheeldown" || wheel == "wd" || wheel == "wheelleft" || wheel == "wl" {
+			event.WheelDelta = -event.WheelDelta
+		}
+		imp.add(event)
+		imp.last = &event
+		return nil
+	}
+
+	event.Button = button
+	if action != "" {
+		// 拖拽的按下/释放由回放按绝对坐标执行
+		if event.Window != nil {
+			return fmt.Errorf("Down/Up with window-relative coordinates is not supported")
+		}
+		event.Type = action
+		imp.add(event)
+		imp.last = &event
+		return nil
+	}
+
+	event.Type = model.EventMouseClick
+	if count == 0 {
+		// Click X, Y, 0 只移动鼠标
+		if event.Window != nil {
+			return fmt.Errorf("moving to window-relative coordinates is not supported")
+		}
+		event.Type, event.Button = model.EventMouseMove, "none"
+		count = 1
+	}
+	for i := 0; i < count; i++ {
+		imp.add(event)
+	}
+	imp.last = &event
+	return nil
+}
+
+// parseWinTitle 解析 WinTitle：标题加可选的 ahk_class
+func parseWinTitle(title string) (*screen.Window, error) {
+	window := &screen.Window{}
+	fields := strings.Fields(title)
+	var words []string
+	for i := 0; i < len(fields); i++ {
+		field := strings.ToLower(fields[i])
+		switch {
+		case field == "ahk_class" && i+1 < len(fields):
+			window.Class = fields[i+1]
+			i++
+		case strings.HasPrefix(field, "ahk_"):
+			return nil, fmt.Errorf("%s is not supported, use the window title or ahk_class", fields[i])
+		default:
+			words = append(words, fields[i])
+		}
+	}
+	window.Title = strings.Join(words, " ")
+	if window.Title == "A" && window.Class == "" {
+		return nil, fmt.Errorf("the active window \"A\" is not supported")
+	}
+	if window.Title == "" && window.Class == "" {
+		return nil, fmt.Errorf("WinActivate needs a window title")
+	}
+	return window, nil
+}
+
+// parseAHKSend 把 Send 的按键字符串转换为事件；raw 为 true 时按原样输入文本
+func parseAHKSend(keys string, raw bool) ([]model.Event, error) {
+	if !raw {
+		lower := strings.ToLower(keys)
+		for _, prefix := range []string{"{raw}", "{text}"} {
+			if strings.HasPrefix(lower, prefix) {
+				keys, raw = keys[len(prefix):], true
+				break
+			}
+		}
+	}
+	if raw {
+		if keys == "" {
+			return nil, nil
+		}
+		return []model.Event{{Type: model.EventTypeText, Button: "none", Text: model.EscapeTemplate(keys)}}, nil
+	}
+
+	s := &ahkSender{}
+	runes := []rune(keys)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch r {
+		case '^':
+			s.modifiers = append(s.modifiers, 0x11)
+			continue
+		case '!':
+			s.modifiers = append(s.modifiers, 0x12)
+			continue
+		case '+':
+			s.modifiers = append(s.modifiers, 0x10)
+			continue
+		case '#':
+			s.modifiers = append(s.modifiers, 0x5B)
+			continue
+		case '{':
+			// {}} 和 {{} 表示花括号本身
+			end := i + 1
+			if end < len(runes) && runes[end] == '}' {
+				end++
+			}
+			for end < len(runes) && runes[end] != '}' {
+				end++
+			}
+			if end >= len(runes) {
+				return nil, fmt.Errorf("unterminated {key} in %q", keys)
+			}
+			if err := s.brace(string(runes[i+1 : end])); err != nil {
+				return nil, err
+			}
+			i = end
+			continue
+		}
+
+		if len(s.modifiers) == 0 {
+			s.text.WriteRune(r)
+			continue
+		}
+		// 带修饰键的字符按键位发送，大写字母额外按住 Shift
+		code, ok := parseAHKKey(string(unicode.ToLower(r)))
+		if !ok {
+			return nil, fmt.Errorf("cannot combine %q with modifier keys", r)
+		}
+		if unicode.IsUpper(r) {
+			s.modifiers = append(s.modifiers, 0x10)
+		}
+		s.press(code)
+	}
+	if len(s.modifiers) > 0 {
+		return nil, fmt.Errorf("modifier at the end of %q has no key", keys)
+	}
+	s.flushText()
+	return s.events, nil
+}
+
+// ahkSender 保存解析 Send 按键字符串的中间状态
+type ahkSender struct {
+	events    []model.Event
+	text      strings.Builder // 尚未输出的普通字符
+	modifiers []int           // 作用于下一个按键的修饰键
+}
+
+// flushText 把累积的普通字符输出为 type_text
+func (s *ahkSender) flushText() {
+	if s.text.Len() > 0 {
+		s.events = append(s.events, model.Event{Type: model.EventTypeText, Button: "none", Text: model.EscapeTemplate(s.text.String())})
+		s.text.Reset()
+	}
+}
+
+// key 输出一个按下或释放事件
+func (s *ahkSender) key(eventType string, code int) {
+	s.events = append(s.events, model.Event{Type: eventType, Button: "none", KeyCode: code})
+}
+
+// press 在当前修饰键下按一次 code
+func (s *ahkSender) press(code int) {
+	s.flushText()
+	for _, m := range s.modifiers {
+		s.key(model.EventKeyDown, m)
+	}
+	s.key(model.EventKeyDown, code)
+	s.key(model.EventKeyUp, code)
+	for i := len(s.modifiers) - 1; i >= 0; i-- {
+		s.key(model.EventKeyUp, s.modifiers[i])
+	}
+	s.modifiers = nil
+}
+
+// brace 处理 {Key}、{Key down}、{Key up}、{Key N}
+func (s *ahkSender) brace(content string) error {
+	name, arg, _ := strings.Cut(content, " ")
+	arg = strings.ToLower(strings.TrimSpace(arg))
+
+	code, ok := parseAHKKey(name)
+	if !ok {
+		// {!}、{#}、{{} 等表示字符本身
+		if len([]rune(name)) == 1 && arg == "" && len(s.modifiers) == 0 {
+			s.text.WriteString(name)
+			return nil
+		}
+		return fmt.Errorf("unknown key {%s}", content)
+	}
+
+	switch arg {
+	case "":
+		s.press(code)
+	case "down", "up":
+		if len(s.modifiers) > 0 {
+			return fmt.Errorf("modifiers cannot be combined with {%s}", content)
+		}
+		s.flushText()
+		if arg == "down" {
+			s.key(model.EventKeyDown, code)
+		} else {
+			s.key(model.EventKeyUp, code)
+		}
+	default:
+		count, err := strconv.Atoi(arg)
+		if err != nil || count < 0 {
+			return fmt.Errorf("unsupported {%s}", content)
+		}
+		modifiers := s.modifiers
+		for n := 0; n < count; n++ {
+			s.modifiers = modifiers
+			s.press(code)
+		}
+		s.modifiers = nil
+	}
+	return nil
+}
+
+// splitAHKCommand 拆分命令名和参数，兼容 "Cmd, a, b"（v1）、"Cmd a, b" 和 "Cmd(a, b)"（v2）
+func splitAHKCommand(text string) (string, string) {
+	end := 0
+	for end < len(text) {
+		c := text[end]
+		if !(c == '#' && end == 0) && !(c == '_' || c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z') {
+			break
+		}
+		end++
+	}
+	name, rest := text[:end], strings.TrimSpace(text[end:])
+
+	switch {
+	case strings.HasPrefix(rest, "(") && strings.HasSuffix(rest, ")"):
+		rest = strings.TrimSpace(rest[1 : len(rest)-1])
+	case strings.HasPrefix(rest, ","):
+		rest = strings.TrimSpace(rest[1:])
+	}
+	return name, rest
+}
+
+// splitAHKArgs 按引号之外的逗号拆分参数
+func splitAHKArgs(rest string) []string {
+	if rest == "" {
+		return nil
+	}
+	var args []string
+	var quote rune
+	escaped := false
+	start := 0
+	for i, r := range rest {
+		switch {
+		case escaped:
+			escaped = false
+		case r == '`':
+			escaped = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == ',':
+			args = append(args, strings.TrimSpace(rest[start:i]))
+			start = i + 1
+		}
+	}
+	return append(args, strings.TrimSpace(rest[start:]))
+}
+
+// unquoteAHK 去掉参数两侧的引号（v2 字符串），没有引号时处理 v1 转义
+func unquoteAHK(arg string) string {
+	if strings.HasPrefix(arg, "\"") || strings.HasPrefix(arg, "'") {
+		if s, err := parseAHKString(arg); err == nil {
+			return s
+		}
+	}
+	return unescapeAHK(arg)
+}
+
+// parseAHKString 解析一个完整的 v2 字符串字面量（不支持拼接等表达式）
+func parseAHKString(arg string) (string, error) {
+	quote := rune(arg[0])
+	var b strings.Builder
+	runes := []rune(arg)
+	for i := 1; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == '`' && i+1 < len(runes):
+			i++
+			b.WriteString(ahkEscape(runes[i]))
+		case r == quote:
+			if strings.TrimSpace(string(runes[i+1:])) != "" {
+				return "", errors.New("expressions are not supported, use a single quoted string")
+			}
+			return b.String(), nil
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return "", errors.New("unterminated string")
+}
+
+// unescapeAHK 处理 v1 命令参数中的转义字符
+func unescapeAHK(arg string) string {
+	var b strings.Builder
+	runes := []rune(arg)
+	for i := 0; i < len(runes); i++ {
+		if runes[i] == '`' && i+1 < len(runes) {
+			i++
+			b.WriteString(ahkEscape(runes[i]))
+			continue
+		}
+		b.WriteRune(runes[i])
+	}
+	return b.String()
+}
+
+// ahkEscape 返回 `x 转义序列表示的字符
+func ahkEscape(r rune) string {
+	switch r {
+	case 'n':
+		return "\n"
+	case 'r':
+		return "\r"
+	case 't':
+		return "\t"
+	default:
+		return string(r)
+	}
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
@@ -0,0 +1,212 @@
+package storage
+
+import (
+	"dailyflow/internal/model"
+	"dailyflow/internal/screen"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func ahkSampleTask() *model.TaskData {
+	return &model.TaskData{
+		Meta: model.TaskMeta{Version: model.TaskVersion, Resolution: "1920x1080"},
+		Events: []model.Event{
+			{Type: model.EventMouseMove, X: 800, Y: 430, Button: "none", Delay: 50},
+			{Type: model.EventMouseClick, X: 812, Y: 440, Button: "left", Delay: 300,
+				Window: &screen.Window{Title: "无标题 - 记事本", Class: "Notepad"}, RelX: 712, RelY: 390},
+			{Type: model.EventMouseClick, X: -600, Y: 300, Button: "right"},
+			{Type: model.EventMouseDown, X: 100, Y: 100, Button: "left", Delay: 20},
+			{Type: model.EventMouseMove, X: 200, Y: 150, Button: "none", Delay: 20},
+			{Type: model.EventMouseUp, X: 300, Y: 200, Button: "left", Delay: 20},
+			{Type: model.EventMouseWheel, X: 812, Y: 440, Button: "none", WheelDelta: -240, Orientation: model.WheelVertical},
+			{Type: model.EventMouseWheel, X: 812, Y: 440, Button: "none", WheelDelta: 120, Orientation: model.WheelHorizontal},
+			{Type: model.EventKeyDown, Button: "none", KeyCode: 0xA2, Delay: 500},
+			{Type: model.EventKeyDown, Button: "none", KeyCode: 'S', Delay: 80},
+			{Type: model.EventKeyUp, Button: "none", KeyCode: 'S', Delay: 60},
+			{Type: model.EventKeyUp, Button: "none", KeyCode: 0xA2, Delay: 40},
+			{Type: model.EventKeyDown, Button: "none", KeyCode: 0xE2},
+			{Type: model.EventKeyUp, Button: "none", KeyCode: 0xE2},
+			{Type: model.EventTypeText, Button: "none", Text: "日报; \"完成\"\n"},
+		},
+	}
+}
+
+func TestExportAHK(t *testing.T) {
+	want := `; DailyFlow 导出的 AutoHotkey 脚本
+; 录制分辨率: 1920x1080
+#Requires AutoHotkey v2.0
+CoordMode "Mouse", "Screen"
+SendMode "Input"
+
+Sleep 50
+MouseMove 800, 430, 0
+Sleep 300
+Click 812, 440  ; 窗口: 无标题 - 记事本 (Notepad)
+Click -600, 300, "Right"
+Sleep 20
+Click 100, 100, "Left Down"
+Sleep 20
+MouseMove 200, 150, 0
+Sleep 20
+Click 300, 200, "Left Up"
+Click 812, 440, "WheelDown", 2
+Click 812, 440, "WheelRight", 1
+Sleep 500
+Send "{LCtrl down}"
+Sleep 80
+Send "{s down}"
+Sleep 60
+Send "{s up}"
+Sleep 40
+Send "{LCtrl up}"
+Send "{vkE2 down}"
+Send "{vkE2 up}"
+SendText "日报` + "`;" + ` ` + "`\"完成`\"`n" + `"
+`
+	if got := string(ExportAHK(ahkSampleTask())); got != want {
+		t.Errorf("ExportAHK() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestExportAHKRepeat(t *testing.T) {
+	task := model.NewTaskData("1920x1080")
+	task.AddEvent(model.Event{Type: model.EventRepeat, Button: "none", Count: 3, Var: "i", Delay: 100, Steps: []model.Event{
+		{Type: model.EventMouseClick, X: 10, Y: 20, Button: "left"},
+		{Type: model.EventRepeat, Button: "none", Count: 2, Steps: []model.Event{
+			{Type: model.EventKeyPress, Button: "none", KeyCode: 0x09, Delay: 50},
+		}},
+	}})
+
+	want := `Sleep 100
+Loop 3 {  ; {{i}} 对应 A_Index
+    Click 10, 20
+    Loop 2 {
+        Sleep 50
+        Send "{Tab}"
+    }
+}
+`
+	if got := string(ExportAHK(task)); !strings.HasSuffix(got, "\n\n"+want) {
+		t.Errorf("ExportAHK() =\n%s\nwant suffix\n%s", got, want)
+	}
+}
+
+func TestExportAHKIf(t *testing.T) {
+	task := model.NewTaskData("1920x1080")
+	task.AddEvent(model.Event{Type: model.EventWaitWindow, Button: "none", Window: &screen.Window{Title: "日报", Class: "Notepad"}, Timeout: 1500, Var: "found"})
+	task.AddEvent(model.Event{Type: model.EventIf, Button: "none", Cond: "found && weekday == 1", Steps: []model.Event{
+		{Type: model.EventKeyPress, Button: "none", KeyCode: 0x0D},
+	}, Else: []model.Event{
+		{Type: model.EventWaitWindow, Button: "none", Window: &screen.Window{Class: "#32770"}, Timeout: 10000},
+	}})
+
+	want := `found := WinWait("日报 ahk_class Notepad", , 1.5) != 0
+if (found && weekday == 1) {  ; DailyFlow 条件，请改写其中的变量
+    Send "{Enter}"
+} else {
+    WinWait "ahk_class #32770", , 10
+}
+`
+	if got := string(ExportAHK(task)); !strings.HasSuffix(got, "\n\n"+want) {
+		t.Errorf("ExportAHK() =\n%s\nwant suffix\n%s", got, want)
+	}
+}
+
+func TestExportImportAHK(t *testing.T) {
+	task := ahkSampleTask()
+	got, issues := ImportAHK(ExportAHK(task))
+	if len(issues) != 0 {
+		t.Fatalf("ImportAHK(ExportAHK()) issues = %v", issues)
+	}
+
+	// 导出使用屏幕绝对坐标，窗口信息不保留
+	want := make([]model.Event, len(task.Events))
+	copy(want, task.Events)
+	want[1].Window, want[1].RelX, want[1].RelY = nil, 0, 0
+
+	if !reflect.DeepEqual(got.Events, want) {
+		t.Errorf("ImportAHK(ExportAHK()) =\n%+v\nwant\n%+v", got.Events, want)
+	}
+	if got.Meta.TotalEvents != len(want) {
+		t.Errorf("TotalEvents = %d, want %d", got.Meta.TotalEvents, len(want))
+	}
+}
+
+func TestImportAHK(t *testing.T) {
+	script := `#NoEnv
+; v1 语法
+CoordMode, Mouse, Screen
+SetTitleMatchMode, 2
+/*
+多行注释
+Click, 1, 1
+*/
+Sleep, 1000
+MouseMove, 500, 400
+Click, 520, 410 ; 打开菜单
+Click
+Send, ^s
+Sleep 200
+Send {Enter 2}日报{!}
+SendRaw, {raw} ^
+F1::
+x := 5
+MouseClick, left, 10, 10
+MouseMove, 5, 5, 0, R
+Send {Foo}
+Click 1, 2, X1
+Sleep 100
+`
+	task, issues := ImportAHK([]byte(script))
+
+	wantEvents := []model.Event{
+		{Type: model.EventMouseMove, X: 500, Y: 400, Button: "none", Delay: 1000},
+		{Type: model.EventMouseClick, X: 520, Y: 410, Button: "left"},
+		{Type: model.EventMouseClick, X: 520, Y: 410, Button: "left"},
+		{Type: model.EventKeyDown, Button: "none", KeyCode: 0x11},
+		{Type: model.EventKeyDown, Button: "none", KeyCode: 'S'},
+		{Type: model.EventKeyUp, Button: "none", KeyCode: 'S'},
+		{Type: model.EventKeyUp, Button: "none", KeyCode: 0x11},
+		{Type: model.EventKeyDown, Button: "none", KeyCode: 0x0D, Delay: 200},
+		{Type: model.EventKeyUp, Button: "none", KeyCode: 0x0D},
+		{Type: model.EventKeyDown, Button: "none", KeyCode: 0x0D},
+		{Type: model.EventKeyUp, Button: "none", KeyCode: 0x0D},
+		{Type: model.EventTypeText, Button: "none", Text: "日报!"},
+		{Type: model.EventTypeText, Button: "none", Text: "{raw} ^"},
+	}
+	if !reflect.DeepEqual(task.Events, wantEvents) {
+		t.Errorf("ImportAHK() events =\n%+v\nwant\n%+v", task.Events, wantEvents)
+	}
+
+	wantLines := []int{17, 18, 19, 20, 21, 22, 23}
+	var gotLines []int
+	for _, issue := range issues {
+		gotLines = append(gotLines, issue.Line)
+	}
+	if !reflect.DeepEqual(gotLines, wantLines) {
+		t.Errorf("ImportAHK() issue lines = %v, want %v\n%v", gotLines, wantLines, issues)
+	}
+}
+
+func TestImportAHKWindowRelative(t *testing.T) {
+	script := `#Requires AutoHotkey v2.0
+Click 10, 10
+WinActivate "核心业务系统 ahk_class CoreClient"
+Sleep(500)
+Click 120, 80
+WinActivate("ahk_exe core.exe")
+`
+	task, issues := ImportAHK([]byte(script))
+
+	wantEvents := []model.Event{
+		{Type: model.EventMouseClick, X: 120, Y: 80, Button: "left", Delay: 500,
+			Window: &screen.Window{Title: "核心业务系统", Class: "CoreClient"}, RelX: 120, RelY: 80},
+	}
+	if !reflect.DeepEqual(task.Events, wantEvents) {
+		t.Errorf("ImportAHK() events = %+v, want %+v", task.Events, wantEvents)
+	}
+	if len(issues) != 2 || issues[0].Line != 2 || issues[1].Line != 6 {
+		t.Errorf("ImportAHK() issues = %v, want lines 2 and 6", issues)
+	}
+}
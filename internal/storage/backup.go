@@ -0,0 +1,295 @@
+package storage
+
+import (
+	"bytes"
+	"dailyflow/internal/model"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	BackupsDirName = "backups"
+
+	// MaxBackups 每个文件保留的备份数量，超出时删除最旧的备份
+	MaxBackups = 5
+
+	// backupTimeFormat 备份文件名中的时间格式（不含扩展名）
+	backupTimeFormat = "20060102-150405.000"
+)
+
+var (
+	// ErrCorrupt 文件内容无法解析（写入中断或被改坏），可以尝试从备份恢复
+	ErrCorrupt = errors.New("file is corrupt")
+	// ErrNoBackup 没有可用于恢复的备份
+	ErrNoBackup = errors.New("no usable backup")
+)
+
+// Backup 一个历史版本的备份文件
+type Backup struct {
+	Path string
+	Time time.Time
+}
+
+// GetBackupsDir 获取备份目录（不创建）
+func GetBackupsDir() (string, error) {
+	dataDir, err := GetDataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dataDir, BackupsDirName), nil
+}
+
+// taskBackupDir 返回任务的备份目录，每个任务一个子目录
+func taskBackupDir(name string) (string, error) {
+	if err := ValidateTaskName(name); err != nil {
+		return "", err
+	}
+	backupsDir, err := GetBackupsDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(backupsDir, TasksDirName, name), nil
+}
+
+// configBackupDir 返回配置文件的备份目录
+func configBackupDir() (string, error) {
+	backupsDir, err := GetBackupsDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(backupsDir, strings.TrimSuffix(ConfigFileName, filepath.Ext(ConfigFileName))), nil
+}
+
+// ListTaskBackups 列出任务的备份（从新到旧），没有备份时返回空列表
+func ListTaskBackups(name string) ([]Backup, error) {
+	backupDir, err := taskBackupDir(name)
+	if err != nil {
+		return nil, err
+	}
+	return listBackups(backupDir)
+}
+
+// RestoreTaskBackup 用指定备份覆盖任务（当前文件会先被备份），备份或当前文件加密时恢复结果也加密
+func RestoreTaskBackup(name string, backup Backup) error {
+	taskData, err := loadTaskFile(backup.Path)
+	if err != nil {
+		return err
+	}
+	taskPath, err := taskFilePath(name)
+	if err != nil {
+		return err
+	}
+	return writeTaskFile(taskPath, taskData, isEncryptedFile(taskPath) || isEncryptedFile(backup.Path))
+}
+
+// RestoreLatestTaskBackup 用最新的可解析备份恢复任务，返回实际使用的备份
+func RestoreLatestTaskBackup(name string) (Backup, error) {
+	backups, err := ListTaskBackups(name)
+	if err != nil {
+		return Backup{}, err
+	}
+	for _, backup := range backups {
+		if _, err := loadTaskFile(backup.Path); err != nil {
+			continue
+		}
+		return backup, RestoreTaskBackup(name, backup)
+	}
+	return Backup{}, fmt.Errorf("%w: task %s", ErrNoBackup, name)
+}
+
+// ListConfigBackups 列出配置文件的备份（从新到旧）
+func ListConfigBackups() ([]Backup, error) {
+	backupDir, err := configBackupDir()
+	if err != nil {
+		return nil, err
+	}
+	return listBackups(backupDir)
+}
+
+// RestoreConfigBackup 用指定备份覆盖配置文件（当前文件会先被备份）
+func RestoreConfigBackup(backup Backup) error {
+	config, err := loadConfigFile(backup.Path)
+	if err != nil {
+		return err
+	}
+	return SaveConfig(config)
+}
+
+// RestoreLatestConfigBackup 用最新的可解析备份恢复配置文件，返回实际使用的备份
+func RestoreLatestConfigBackup() (Backup, error) {
+	backups, err := ListConfigBackups()
+	if err != nil {
+		return Backup{}, err
+	}
+	for _, backup := range backups {
+		if _, err := loadConfigFile(backup.Path); err != nil {
+			continue
+		}
+		return backup, RestoreConfigBackup(backup)
+	}
+	return Backup{}, fmt.Errorf("%w: %s", ErrNoBackup, ConfigFileName)
+}
+
+// loadConfigFile 读取并解析配置文件（不回写迁移结果）
+func loadConfigFile(configPath string) (*model.Config, error) {
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+	config, _, err := decodeConfig(data)
+	return config, err
+}
+
+// writeFileBackedUp 原子地写入文件，写入前把旧内容备份到 backupDir，并只保留 MaxBackups 个备份
+// 内容没有变化时不写入也不产生备份
+func writeFileBackedUp(path, backupDir string, data []byte) error {
+	old, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		if bytes.Equal(old, data) {
+			return nil
+		}
+		if err := saveBackup(backupDir, filepath.Ext(path), old, time.Now()); err != nil {
+			return err
+		}
+	case !os.IsNotExist(err):
+		return fmt.Errorf("failed to read %s: %w", filepath.Base(path), err)
+	}
+
+	if err := writeFileAtomic(path, data, 0644); err != nil {
+		return err
+	}
+
+	// 清理旧备份失败不影响本次保存
+	pruneBackups(backupDir, MaxBackups)
+	return nil
+}
+
+// writeFileAtomic 先写入同目录下的临时文件并刷盘，再重命名覆盖目标文件
+// 写入过程中崩溃或断电时，目标文件保持旧内容或新内容，不会出现写了一半的文件
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	dir, base := filepath.Split(path)
+	tmp, err := os.CreateTemp(dir, base+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	tmpPath := tmp.Name()
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to write %s: %w", base, err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to sync %s: %w", base, err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to write %s: %w", base, err)
+	}
+	if err := os.Chmod(tmpPath, perm); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to write %s: %w", base, err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to replace %s: %w", base, err)
+	}
+
+	syncDir(dir)
+	return nil
+}
+
+// syncDir 刷新目录项，确保重命名落盘（Windows 不支持，忽略错误）
+func syncDir(dir string) {
+	if dir == "" {
+		dir = "."
+	}
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	d.Sync()
+	d.Close()
+}
+
+// saveBackup 把 data 写为 backupDir 中以时间命名的备份文件
+func saveBackup(backupDir, ext string, data []byte, now time.Time) error {
+	if err := os.MkdirAll(backupDir, 0755); err != nil {
+		return fmt.Errorf("failed to create backup directory: %w", err)
+	}
+
+	// 同一毫秒内的多次保存顺延文件名
+	for i := 0; i < 1000; i++ {
+		name := now.Add(time.Duration(i)*time.Millisecond).Format(backupTimeFormat) + ext
+		backupPath := filepath.Join(backupDir, name)
+		f, err := os.OpenFile(backupPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if os.IsExist(err) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to create backup: %w", err)
+		}
+		if _, err := f.Write(data); err != nil {
+			f.Close()
+			os.Remove(backupPath)
+			return fmt.Errorf("failed to write backup: %w", err)
+		}
+		if err := f.Close(); err != nil {
+			os.Remove(backupPath)
+			return fmt.Errorf("failed to write backup: %w", err)
+		}
+		return nil
+	}
+	return fmt.Errorf("failed to create backup: too many backups at %s", now.Format(backupTimeFormat))
+}
+
+// listBackups 列出 backupDir 中的备份文件（从新到旧），目录不存在时返回空列表
+func listBackups(backupDir string) ([]Backup, error) {
+	entries, err := os.ReadDir(backupDir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read backup directory: %w", err)
+	}
+
+	backups := make([]Backup, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		stem := strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name()))
+		t, err := time.ParseInLocation(backupTimeFormat, stem, time.Local)
+		if err != nil {
+			// 不是备份文件（如用户放入的其他文件）
+			continue
+		}
+		backups = append(backups, Backup{Path: filepath.Join(backupDir, entry.Name()), Time: t})
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].Time.After(backups[j].Time)
+	})
+	return backups, nil
+}
+
+// pruneBackups 删除 backupDir 中超出 keep 个的旧备份
+func pruneBackups(backupDir string, keep int) error {
+	backups, err := listBackups(backupDir)
+	if err != nil {
+		return err
+	}
+	for _, backup := range backups[min(keep, len(backups)):] {
+		if err := os.Remove(backup.Path); err != nil {
+			return fmt.Errorf("failed to remove old backup: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package storage
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestWriteFileBackedUp(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "日报.json")
+	backupDir := filepath.Join(dir, "backups")
+
+	for i := 0; i <= MaxBackups+2; i++ {
+		if err := writeFileBackedUp(path, backupDir, []byte(strconv.Itoa(i))); err != nil {
+			t.Fatalf("writeFileBackedUp(%d) error = %v", i, err)
+		}
+	}
+	// 内容未变化时不产生备份
+	if err := writeFileBackedUp(path, backupDir, []byte(strconv.Itoa(MaxBackups+2))); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil || string(data) != strconv.Itoa(MaxBackups+2) {
+		t.Fatalf("current file = %q, %v", data, err)
+	}
+
+	backups, err := listBackups(backupDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backups) != MaxBackups {
+		t.Fatalf("len(backups) = %d, want %d", len(backups), MaxBackups)
+	}
+	// 从新到旧依次是上一次、上上次……的内容
+	for i, backup := range backups {
+		data, err := os.ReadFile(backup.Path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := strconv.Itoa(MaxBackups + 1 - i); string(data) != want {
+			t.Errorf("backups[%d] = %q, want %q", i, data, want)
+		}
+		if filepath.Ext(backup.Path) != TaskFileExt {
+			t.Errorf("backups[%d] = %s, want %s extension", i, backup.Path, TaskFileExt)
+		}
+	}
+
+	// 没有遗留临时文件
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Errorf("directory contains %d entries, want the file and the backup directory", len(entries))
+	}
+}
+
+func TestListBackups(t *testing.T) {
+	dir := t.TempDir()
+	base := time.Date(2024, 12, 1, 8, 30, 0, 0, time.Local)
+
+	for _, offset := range []time.Duration{time.Hour, 0, 24 * time.Hour} {
+		if err := saveBackup(dir, ".txt", []byte("x"), base.Add(offset)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 同一时刻的备份顺延一毫秒
+	if err := saveBackup(dir, ".txt", []byte("y"), base); err != nil {
+		t.Fatal(err)
+	}
+	// 非备份文件被忽略
+	if err := os.WriteFile(filepath.Join(dir, "说明.txt"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	backups, err := listBackups(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"20241202-083000.000.txt", "20241201-093000.000.txt", "20241201-083000.001.txt", "20241201-083000.000.txt"}
+	if len(backups) != len(want) {
+		t.Fatalf("listBackups() = %v, want %v", backups, want)
+	}
+	for i, backup := range backups {
+		if filepath.Base(backup.Path) != want[i] {
+			t.Errorf("backups[%d] = %s, want %s", i, filepath.Base(backup.Path), want[i])
+		}
+	}
+
+	if backups, err := listBackups(filepath.Join(dir, "missing")); err != nil || len(backups) != 0 {
+		t.Errorf("listBackups(missing) = %v, %v, want empty", backups, err)
+	}
+}
+
+func TestCorruptFiles(t *testing.T) {
+	truncated := []byte(`{"meta": {"version": "1.6"}, "events": [{"type": "mouse_cl`)
+	if _, err := decodeTask(truncated); !errors.Is(err, ErrCorrupt) {
+		t.Errorf("decodeTask(truncated) error = %v, want ErrCorrupt", err)
+	}
+	if _, _, err := decodeConfig(truncated); !errors.Is(err, ErrCorrupt) {
+		t.Errorf("decodeConfig(truncated) error = %v, want ErrCorrupt", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "日报"+ScriptFileExt)
+	if err := os.WriteFile(path, []byte("clik left 1,2\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadTaskFile(path); !errors.Is(err, ErrCorrupt) {
+		t.Errorf("loadTaskFile(bad script) error = %v, want ErrCorrupt", err)
+	}
+}
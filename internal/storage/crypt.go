@@ -0,0 +1,318 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// 加密任务文件的格式：
+//
+//	magic(6) | logN(1) r(1) p(1) | salt(16) | check(32) | nonce(12) | sum(4) | AES-256-GCM 密文
+//
+// 口令经 scrypt 派生 64 字节，前 32 字节是 AES 密钥，后 32 字节用于计算 check，
+// 解密前先比对 check 区分"口令错误"和"内容被篡改"；sum 是头部其余字段的 SHA-256 前 4 字节，
+// 头部被改动时报告篡改而不是口令错误。整个头部作为 GCM 的附加数据，改动任何一个字节都无法通过认证
+const (
+	cryptMagic     = "DFENC1"
+	cryptSaltSize  = 16
+	cryptCheckSize = sha256.Size
+	cryptNonceSize = 12
+	cryptSumSize   = 4
+	cryptHeaderLen = len(cryptMagic) + 3 + cryptSaltSize + cryptCheckSize + cryptNonceSize + cryptSumSize
+
+	// 头部各字段的起始位置
+	cryptParamsOffset = len(cryptMagic)
+	cryptCheckOffset  = cryptParamsOffset + 3 + cryptSaltSize
+	cryptNonceOffset  = cryptCheckOffset + cryptCheckSize
+	cryptSumOffset    = cryptNonceOffset + cryptNonceSize
+
+	// scrypt 参数的允许范围，超出范围的头部视为被篡改（防止伪造的参数耗尽内存和时间）
+	minScryptLogN   = 10
+	maxScryptLogN   = 20
+	maxScryptMemory = 1 << 30 // 128 * r * N 字节
+	maxScryptP      = 16
+)
+
+var (
+	// ErrPassphraseRequired 任务文件已加密，但尚未设置口令
+	ErrPassphraseRequired = errors.New("task file is encrypted, passphrase required")
+	// ErrWrongPassphrase 口令与加密时使用的不一致
+	ErrWrongPassphrase = errors.New("wrong passphrase")
+	// ErrTampered 加密的任务文件被截断或修改过
+	ErrTampered = errors.New("encrypted file has been tampered with")
+)
+
+// scrypt 参数（N = 2^scryptLogN），写入文件头，解密时按头部的参数派生
+var (
+	scryptLogN uint8 = 15
+	scryptR    uint8 = 8
+	scryptP    uint8 = 1
+)
+
+var (
+	passphraseMutex sync.Mutex
+	passphrase      string
+	derivedKeys     map[string][]byte // 头部的 scrypt 参数和盐 -> 派生密钥，避免每次加载都重新派生
+)
+
+// SetPassphrase 设置加密任务文件的口令，之后 LoadTask / SaveTask 透明地解密和加密
+// 口令只保存在内存中，传入空字符串清除
+func SetPassphrase(value string) {
+	passphraseMutex.Lock()
+	defer passphraseMutex.Unlock()
+	passphrase = value
+	derivedKeys = nil
+}
+
+// HasPassphrase 是否已设置口令
+func HasPassphrase() bool {
+	passphraseMutex.Lock()
+	defer passphraseMutex.Unlock()
+	return passphrase != ""
+}
+
+// IsEncrypted 判断数据是否为加密的任务文件
+func IsEncrypted(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(cryptMagic))
+}
+
+// IsTaskEncrypted 判断任务文件是否已加密
+func IsTaskEncrypted(name string) (bool, error) {
+	taskPath, err := existingTaskPath(name)
+	if err != nil {
+		return false, err
+	}
+	return isEncryptedFile(taskPath), nil
+}
+
+// EncryptTask 用当前口令加密任务文件及其备份，已加密的文件保持不变
+func EncryptTask(name string) error {
+	if !HasPassphrase() {
+		return ErrPassphraseRequired
+	}
+	return convertTaskEncryption(name, true)
+}
+
+// DecryptTask 把加密的任务文件及其备份还原为明文，需要已设置正确的口令
+func DecryptTask(name string) error {
+	return convertTaskEncryption(name, false)
+}
+
+// convertTaskEncryption 加密或解密任务文件，备份一并转换，
+// 否则加密前的明文备份会留下密码，或解密后的备份在忘记口令时无法恢复
+func convertTaskEncryption(name string, encrypt bool) error {
+	taskPath, err := existingTaskPath(name)
+	if err != nil {
+		return err
+	}
+	if isEncryptedFile(taskPath) != encrypt {
+		taskData, err := loadTaskFile(taskPath)
+		if err != nil {
+			return err
+		}
+		if err := writeTaskFile(taskPath, taskData, encrypt); err != nil {
+			return err
+		}
+	}
+
+	backups, err := ListTaskBackups(name)
+	if err != nil {
+		return err
+	}
+	for _, backup := range backups {
+		data, err := os.ReadFile(backup.Path)
+		if err != nil {
+			return fmt.Errorf("failed to read backup: %w", err)
+		}
+		if IsEncrypted(data) == encrypt {
+			continue
+		}
+		if encrypt {
+			data, err = encryptData(data)
+		} else {
+			data, err = decryptData(data)
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", backup.Path, err)
+		}
+		if err := writeFileAtomic(backup.Path, data, 0644); err != nil {
+			return fmt.Errorf("failed to write backup: %w", err)
+		}
+	}
+	return nil
+}
+
+// isEncryptedFile 判断文件是否以加密头开始，文件不存在或无法读取时返回 false
+func isEncryptedFile(path string) bool {
+	file, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+
+	magic := make([]byte, len(cryptMagic))
+	if _, err := io.ReadFull(file, magic); err != nil {
+		return false
+	}
+	return IsEncrypted(magic)
+}
+
+// encryptData 用当前口令加密数据
+// 同一口令下复用已派生的密钥和盐，每次使用新的随机 nonce，保存任务时不必重新执行 scrypt
+func encryptData(plaintext []byte) ([]byte, error) {
+	header := make([]byte, cryptHeaderLen)
+	copy(header, cryptMagic)
+
+	key, err := encryptionKey(header)
+	if err != nil {
+		return nil, err
+	}
+	nonce := header[cryptNonceOffset:cryptSumOffset]
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	copy(header[cryptSumOffset:], headerSum(header))
+
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	return aead.Seal(header, nonce, plaintext, header), nil
+}
+
+// decryptData 用当前口令解密数据
+func decryptData(data []byte) ([]byte, error) {
+	if !IsEncrypted(data) {
+		return nil, fmt.Errorf("data is not encrypted")
+	}
+	if len(data) < cryptHeaderLen {
+		return nil, fmt.Errorf("%w: truncated header", ErrTampered)
+	}
+	header, ciphertext := data[:cryptHeaderLen], data[cryptHeaderLen:]
+	if !bytes.Equal(header[cryptSumOffset:], headerSum(header)) {
+		return nil, fmt.Errorf("%w: header checksum mismatch", ErrTampered)
+	}
+
+	key, err := decryptionKey(header)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := header[cryptNonceOffset:cryptSumOffset]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, header)
+	if err != nil {
+		return nil, ErrTampered
+	}
+	return plaintext, nil
+}
+
+// encryptionKey 为新文件选择 scrypt 参数和盐并填入 header，返回 AES 密钥
+// 已为当前口令派生过密钥时直接复用
+func encryptionKey(header []byte) ([]byte, error) {
+	passphraseMutex.Lock()
+	defer passphraseMutex.Unlock()
+
+	if passphrase == "" {
+		return nil, ErrPassphraseRequired
+	}
+
+	params := header[cryptParamsOffset:cryptCheckOffset]
+	for cached, derived := range derivedKeys {
+		copy(params, cached)
+		copy(header[cryptCheckOffset:], keyCheck(derived))
+		return derived[:32], nil
+	}
+
+	params[0], params[1], params[2] = scryptLogN, scryptR, scryptP
+	if _, err := rand.Read(params[3:]); err != nil {
+		return nil, fmt.Errorf("failed to generate salt: %w", err)
+	}
+	derived, err := deriveKey(params)
+	if err != nil {
+		return nil, err
+	}
+	copy(header[cryptCheckOffset:], keyCheck(derived))
+	return derived[:32], nil
+}
+
+// decryptionKey 按 header 中的 scrypt 参数和盐派生密钥，并用 check 验证口令
+func decryptionKey(header []byte) ([]byte, error) {
+	passphraseMutex.Lock()
+	defer passphraseMutex.Unlock()
+
+	if passphrase == "" {
+		return nil, ErrPassphraseRequired
+	}
+
+	params := header[cryptParamsOffset:cryptCheckOffset]
+	logN, r, p := params[0], params[1], params[2]
+	if logN < minScryptLogN || logN > maxScryptLogN || r == 0 || p == 0 || p > maxScryptP || 128*int(r)<<logN > maxScryptMemory {
+		return nil, fmt.Errorf("%w: invalid key derivation parameters", ErrTampered)
+	}
+
+	derived, ok := derivedKeys[string(params)]
+	if !ok {
+		var err error
+		derived, err = deriveKey(params)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	check := header[cryptCheckOffset:cryptNonceOffset]
+	if !hmac.Equal(check, keyCheck(derived)) {
+		delete(derivedKeys, string(params))
+		return nil, ErrWrongPassphrase
+	}
+	return derived[:32], nil
+}
+
+// deriveKey 用当前口令和 params（logN、r、p、盐）派生 64 字节并缓存，调用方持有 passphraseMutex
+func deriveKey(params []byte) ([]byte, error) {
+	salt := params[3:]
+	derived, err := scrypt.Key([]byte(passphrase), salt, 1<<params[0], int(params[1]), int(params[2]), 64)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive key: %w", err)
+	}
+	if derivedKeys == nil {
+		derivedKeys = make(map[string][]byte)
+	}
+	derivedKeys[string(params)] = derived
+	return derived, nil
+}
+
+// keyCheck 由派生密钥的后半部分计算口令校验值，不泄露 AES 密钥
+func keyCheck(derived []byte) []byte {
+	mac := hmac.New(sha256.New, derived[32:])
+	mac.Write([]byte(cryptMagic))
+	return mac.Sum(nil)
+}
+
+// headerSum 计算头部（不含 sum 本身）的校验和
+func headerSum(header []byte) []byte {
+	sum := sha256.Sum256(header[:cryptSumOffset])
+	return sum[:cryptSumSize]
+}
+
+// newAEAD 创建 AES-256-GCM
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,166 @@
+package storage
+
+import (
+	"bytes"
+	"dailyflow/internal/model"
+	"errors"
+	"os"
+	"testing"
+)
+
+// useTestPassphrase 设置口令并降低 scrypt 强度以加快测试，测试结束后恢复
+func useTestPassphrase(t *testing.T, value string) {
+	t.Helper()
+	logN := scryptLogN
+	scryptLogN = minScryptLogN
+	SetPassphrase(value)
+	t.Cleanup(func() {
+		scryptLogN = logN
+		SetPassphrase("")
+	})
+}
+
+// useTempDataDir 把数据目录指向临时目录，测试结束后恢复为未选择状态
+func useTempDataDir(t *testing.T) {
+	t.Helper()
+	if _, err := SetDataDir(DataModeCustom, t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		dataDirMutex.Lock()
+		defer dataDirMutex.Unlock()
+		dataDir, dataMode, requestedMode = "", "", ""
+	})
+}
+
+func TestEncryptData(t *testing.T) {
+	useTestPassphrase(t, "correct horse")
+	plaintext := []byte(`{"events": [{"type": "type_text", "text": "P@ssw0rd"}]}`)
+
+	encrypted, err := encryptData(plaintext)
+	if err != nil {
+		t.Fatalf("encryptData() error = %v", err)
+	}
+	if !IsEncrypted(encrypted) || bytes.Contains(encrypted, []byte("P@ssw0rd")) {
+		t.Fatalf("encryptData() = %q, want encrypted data", encrypted)
+	}
+	// 复用派生密钥时 nonce 仍然每次不同
+	if again, err := encryptData(plaintext); err != nil || bytes.Equal(again, encrypted) {
+		t.Errorf("encrypting twice gave identical output (err = %v)", err)
+	}
+
+	decrypted, err := decryptData(encrypted)
+	if err != nil || !bytes.Equal(decrypted, plaintext) {
+		t.Fatalf("decryptData() = %q, %v, want %q", decrypted, err, plaintext)
+	}
+
+	modify := func(offset int) []byte {
+		data := append([]byte(nil), encrypted...)
+		data[offset] ^= 0x01
+		return data
+	}
+	tests := []struct {
+		name       string
+		passphrase string
+		data       []byte
+		wantErr    error
+	}{
+		{"no passphrase", "", encrypted, ErrPassphraseRequired},
+		{"wrong passphrase", "wrong horse", encrypted, ErrWrongPassphrase},
+		{"ciphertext modified", "correct horse", modify(len(encrypted) - 1), ErrTampered},
+		{"nonce modified", "correct horse", modify(cryptSumOffset - 1), ErrTampered},
+		{"salt modified", "correct horse", modify(cryptCheckOffset - 1), ErrTampered},
+		{"parameters modified", "correct horse", modify(cryptParamsOffset), ErrTampered},
+		{"truncated", "correct horse", encrypted[:cryptHeaderLen-4], ErrTampered},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			SetPassphrase(tt.passphrase)
+			if _, err := decryptData(tt.data); !errors.Is(err, tt.wantErr) {
+				t.Errorf("decryptData() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestEncryptTask(t *testing.T) {
+	useTempDataDir(t)
+	useTestPassphrase(t, "correct horse")
+
+	task := model.NewTaskData("1920x1080")
+	task.AddEvent(model.Event{Type: model.EventTypeText, Button: "none", Text: "P@ssw0rd"})
+	if err := SaveTask("登录", task); err != nil {
+		t.Fatal(err)
+	}
+	task.AddEvent(model.Event{Type: model.EventKeyPress, Button: "none", KeyCode: 0x0D})
+	if err := SaveTask("登录", task); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := EncryptTask("登录"); err != nil {
+		t.Fatalf("EncryptTask() error = %v", err)
+	}
+	// 任务文件和加密前的明文备份都不再包含密码
+	assertTaskFiles(t, "登录", true)
+
+	loaded, err := LoadTask("登录")
+	if err != nil || len(loaded.Events) != 2 {
+		t.Fatalf("LoadTask() = %v, %v, want 2 events", loaded, err)
+	}
+	// 保存已加密的任务时保持加密
+	if err := SaveTask("登录", model.NewTaskData("1920x1080")); err != nil {
+		t.Fatal(err)
+	}
+	assertTaskFiles(t, "登录", true)
+
+	SetPassphrase("")
+	if _, err := LoadTask("登录"); !errors.Is(err, ErrPassphraseRequired) {
+		t.Errorf("LoadTask() without passphrase error = %v, want ErrPassphraseRequired", err)
+	}
+	if err := SaveTask("登录", task); !errors.Is(err, ErrPassphraseRequired) {
+		t.Errorf("SaveTask() without passphrase error = %v, want ErrPassphraseRequired", err)
+	}
+	SetPassphrase("wrong horse")
+	if err := DecryptTask("登录"); !errors.Is(err, ErrWrongPassphrase) {
+		t.Errorf("DecryptTask() with wrong passphrase error = %v, want ErrWrongPassphrase", err)
+	}
+
+	SetPassphrase("correct horse")
+	if err := DecryptTask("登录"); err != nil {
+		t.Fatalf("DecryptTask() error = %v", err)
+	}
+	assertTaskFiles(t, "登录", false)
+	if _, err := RestoreLatestTaskBackup("登录"); err != nil {
+		t.Errorf("RestoreLatestTaskBackup() error = %v", err)
+	}
+}
+
+// assertTaskFiles 检查任务文件及其所有备份是否都（未）加密
+func assertTaskFiles(t *testing.T, name string, encrypted bool) {
+	t.Helper()
+	taskPath, err := TaskPath(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	paths := []string{taskPath}
+	backups, err := ListTaskBackups(name)
+	if err != nil || len(backups) == 0 {
+		t.Fatalf("ListTaskBackups() = %v, %v, want backups", backups, err)
+	}
+	for _, backup := range backups {
+		paths = append(paths, backup.Path)
+	}
+
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if IsEncrypted(data) != encrypted {
+			t.Errorf("%s: encrypted = %v, want %v", path, !encrypted, encrypted)
+		}
+		if encrypted && bytes.Contains(data, []byte("P@ssw0rd")) {
+			t.Errorf("%s contains the password in plain text", path)
+		}
+	}
+}
@@ -0,0 +1,305 @@
+package storage
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// DataMode 数据目录模式，决定任务库、配置和备份保存的位置
+type DataMode string
+
+const (
+	// DataModeAuto 用户目录已有数据或程序目录不可写时使用用户模式，否则使用便携模式
+	DataModeAuto DataMode = ""
+	// DataModePortable 便携模式：数据保存在程序所在目录
+	DataModePortable DataMode = "portable"
+	// DataModeUser 用户模式：数据保存在 %APPDATA%\DailyFlow
+	DataModeUser DataMode = "user"
+	// DataModeCustom 自定义目录（命令行 --data-dir）
+	DataModeCustom DataMode = "custom"
+)
+
+// AppDirName 用户模式下的数据目录名
+const AppDirName = "DailyFlow"
+
+// migrateStagingName 迁移过程中的临时目录，全部复制完成后才移入数据目录
+const migrateStagingName = ".migrating"
+
+// ErrDirNotWritable 数据目录不可写
+var ErrDirNotWritable = errors.New("directory is not writable")
+
+var (
+	dataDirMutex  sync.Mutex
+	dataDir       string
+	dataMode      DataMode
+	requestedMode DataMode
+)
+
+// SetDataDir 按指定模式选择数据目录并返回实际使用的目录，应在读写任何数据之前调用
+// mode 为 DataModeCustom 时使用 dir；首次使用用户目录时会把程序目录中已有的数据复制过去
+func SetDataDir(mode DataMode, dir string) (string, error) {
+	dataDirMutex.Lock()
+	defer dataDirMutex.Unlock()
+
+	if err := selectDataDir(mode, dir); err != nil {
+		return "", err
+	}
+	requestedMode = mode
+	return dataDir, nil
+}
+
+// GetDataDir 获取数据目录，未调用 SetDataDir 时按自动模式选择
+func GetDataDir() (string, error) {
+	dataDirMutex.Lock()
+	defer dataDirMutex.Unlock()
+
+	if dataDir == "" {
+		if err := selectDataDir(DataModeAuto, ""); err != nil {
+			return "", err
+		}
+	}
+	return dataDir, nil
+}
+
+// GetDataMode 返回当前数据目录的实际模式（自动模式已解析为便携或用户模式）
+func GetDataMode() DataMode {
+	dataDirMutex.Lock()
+	defer dataDirMutex.Unlock()
+	return dataMode
+}
+
+// DataDirArgs 返回重现当前数据目录选择的命令行参数（用于开机自启快捷方式），自动模式返回空
+func DataDirArgs() []string {
+	dataDirMutex.Lock()
+	defer dataDirMutex.Unlock()
+
+	switch requestedMode {
+	case DataModePortable:
+		return []string{"--portable"}
+	case DataModeUser:
+		return []string{"--user"}
+	case DataModeCustom:
+		return []string{"--data-dir", dataDir}
+	}
+	return nil
+}
+
+// AddDataDirFlags 在 flags 上注册与 DataDirArgs 对应的 --portable、--user、--data-dir 选项
+// 解析命令行之后调用返回的函数，按选项设置数据目录（都不指定时自动选择）
+func AddDataDirFlags(flags *flag.FlagSet) func() (string, error) {
+	portable := flags.Bool("portable", false, "store data next to the executable")
+	user := flags.Bool("user", false, "store data in %APPDATA%\\DailyFlow")
+	dir := flags.String("data-dir", "", "store data in the given directory")
+
+	return func() (string, error) {
+		mode := DataModeAuto
+		selected := 0
+		if *portable {
+			mode = DataModePortable
+			selected++
+		}
+		if *user {
+			mode = DataModeUser
+			selected++
+		}
+		if *dir != "" {
+			mode = DataModeCustom
+			selected++
+		}
+		if selected > 1 {
+			return "", fmt.Errorf("--portable, --user and --data-dir cannot be combined")
+		}
+		return SetDataDir(mode, *dir)
+	}
+}
+
+// GetUserDataDir 获取用户模式的数据目录（%APPDATA%\DailyFlow）
+func GetUserDataDir() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get user config directory: %w", err)
+	}
+	return filepath.Join(configDir, AppDirName), nil
+}
+
+// selectDataDir 解析数据目录并保存到包级状态，调用方需持有 dataDirMutex
+func selectDataDir(mode DataMode, dir string) error {
+	exeDir, err := GetExecutableDir()
+	if err != nil {
+		return err
+	}
+	userDir := ""
+	if mode == DataModeAuto || mode == DataModeUser {
+		if userDir, err = GetUserDataDir(); err != nil {
+			return err
+		}
+	}
+
+	resolvedDir, resolvedMode, err := resolveDataDir(mode, dir, exeDir, userDir)
+	if err != nil {
+		return err
+	}
+	dataDir, dataMode = resolvedDir, resolvedMode
+	return nil
+}
+
+// resolveDataDir 按模式确定数据目录，用户模式首次使用时迁移程序目录中的数据
+func resolveDataDir(mode DataMode, customDir, exeDir, userDir string) (string, DataMode, error) {
+	switch mode {
+	case DataModeAuto:
+		// 已经在用用户目录时保持不变，避免程序目录变为可写后"丢失"数据
+		if !hasData(userDir) && isDirWritable(exeDir) {
+			return exeDir, DataModePortable, nil
+		}
+		return resolveDataDir(DataModeUser, "", exeDir, userDir)
+
+	case DataModePortable:
+		if !isDirWritable(exeDir) {
+			return "", "", fmt.Errorf("%w: %s", ErrDirNotWritable, exeDir)
+		}
+		return exeDir, DataModePortable, nil
+
+	case DataModeUser:
+		if err := os.MkdirAll(userDir, 0755); err != nil {
+			return "", "", fmt.Errorf("failed to create data directory: %w", err)
+		}
+		if !hasData(userDir) {
+			if err := migrateDataDir(exeDir, userDir); err != nil {
+				return "", "", err
+			}
+		}
+		return userDir, DataModeUser, nil
+
+	case DataModeCustom:
+		if customDir == "" {
+			return "", "", fmt.Errorf("data directory is empty")
+		}
+		absDir, err := filepath.Abs(customDir)
+		if err != nil {
+			return "", "", fmt.Errorf("invalid data directory %q: %w", customDir, err)
+		}
+		if err := os.MkdirAll(absDir, 0755); err != nil {
+			return "", "", fmt.Errorf("failed to create data directory: %w", err)
+		}
+		if !isDirWritable(absDir) {
+			return "", "", fmt.Errorf("%w: %s", ErrDirNotWritable, absDir)
+		}
+		return absDir, DataModeCustom, nil
+	}
+	return "", "", fmt.Errorf("unknown data directory mode %q", mode)
+}
+
+// dataEntries 数据目录中需要迁移的文件和目录
+var dataEntries = []string{TasksDirName, BackupsDirName, ConfigFileName, LegacyTaskFileName}
+
+// hasData 判断目录中是否已有 DailyFlow 数据
+func hasData(dir string) bool {
+	for _, name := range dataEntries {
+		if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// isDirWritable 通过创建临时文件判断目录是否可写
+func isDirWritable(dir string) bool {
+	f, err := os.CreateTemp(dir, ".dailyflow-write-test-*")
+	if err != nil {
+		return false
+	}
+	name := f.Name()
+	f.Close()
+	os.Remove(name)
+	return true
+}
+
+// migrateDataDir 把 srcDir 中的数据复制到 dstDir（源目录可能只读，不删除原文件）
+// 先全部复制到临时目录再逐项移入；某一项移入失败时删除已经移入的项，
+// dstDir 中没有数据，下次启动时会重新迁移，不会只留下一部分数据
+func migrateDataDir(srcDir, dstDir string) error {
+	if !hasData(srcDir) {
+		return nil
+	}
+
+	stagingDir := filepath.Join(dstDir, migrateStagingName)
+	if err := os.RemoveAll(stagingDir); err != nil {
+		return fmt.Errorf("failed to clean up migration directory: %w", err)
+	}
+	defer os.RemoveAll(stagingDir)
+
+	var moved []string
+	for _, name := range dataEntries {
+		src := filepath.Join(srcDir, name)
+		if _, err := os.Stat(src); os.IsNotExist(err) {
+			continue
+		}
+		if err := copyTree(src, filepath.Join(stagingDir, name)); err != nil {
+			return fmt.Errorf("failed to migrate %s: %w", name, err)
+		}
+		moved = append(moved, name)
+	}
+	for i, name := range moved {
+		if err := os.Rename(filepath.Join(stagingDir, name), filepath.Join(dstDir, name)); err != nil {
+			for _, done := range moved[:i] {
+				if rmErr := os.RemoveAll(filepath.Join(dstDir, done)); rmErr != nil {
+					return fmt.Errorf("failed to migrate %s: %w (and failed to roll back %s: %v)", name, err, done, rmErr)
+				}
+			}
+			return fmt.Errorf("failed to migrate %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// copyTree 递归复制文件或目录
+func copyTree(src, dst string) error {
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return copyFile(src, dst)
+	}
+
+	if err := os.MkdirAll(dst, 0755); err != nil {
+		return err
+	}
+	entries, err := os.ReadDir(src)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if err := copyTree(filepath.Join(src, entry.Name()), filepath.Join(dst, entry.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// copyFile 复制单个文件
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
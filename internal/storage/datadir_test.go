@@ -0,0 +1,179 @@
+package storage
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeFiles 在 dir 下创建文件，files 的键为相对路径
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestResolveDataDir(t *testing.T) {
+	tests := []struct {
+		name     string
+		mode     DataMode
+		exeFiles map[string]string
+		// userFiles 为 nil 表示用户目录不存在
+		userFiles map[string]string
+		wantMode  DataMode
+	}{
+		{"auto prefers portable", DataModeAuto, map[string]string{"config.json": "{}"}, nil, DataModePortable},
+		{"auto keeps user data", DataModeAuto, nil, map[string]string{"tasks/日报.json": "{}"}, DataModeUser},
+		{"auto ignores unrelated user files", DataModeAuto, nil, map[string]string{"dailyflow_error.log": ""}, DataModePortable},
+		{"explicit portable", DataModePortable, nil, map[string]string{"config.json": "{}"}, DataModePortable},
+		{"explicit user", DataModeUser, nil, nil, DataModeUser},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exeDir := t.TempDir()
+			userDir := filepath.Join(t.TempDir(), AppDirName)
+			writeFiles(t, exeDir, tt.exeFiles)
+			if tt.userFiles != nil {
+				writeFiles(t, userDir, tt.userFiles)
+			}
+
+			dir, mode, err := resolveDataDir(tt.mode, "", exeDir, userDir)
+			if err != nil {
+				t.Fatalf("resolveDataDir() error = %v", err)
+			}
+			wantDir := exeDir
+			if tt.wantMode == DataModeUser {
+				wantDir = userDir
+			}
+			if dir != wantDir || mode != tt.wantMode {
+				t.Errorf("resolveDataDir() = %s, %s, want %s, %s", dir, mode, wantDir, tt.wantMode)
+			}
+		})
+	}
+}
+
+func TestResolveCustomDataDir(t *testing.T) {
+	customDir := filepath.Join(t.TempDir(), "共享", "DailyFlow")
+	dir, mode, err := resolveDataDir(DataModeCustom, customDir, t.TempDir(), "")
+	if err != nil {
+		t.Fatalf("resolveDataDir() error = %v", err)
+	}
+	if dir != customDir || mode != DataModeCustom {
+		t.Errorf("resolveDataDir() = %s, %s, want %s, %s", dir, mode, customDir, DataModeCustom)
+	}
+	if _, err := os.Stat(customDir); err != nil {
+		t.Errorf("custom data directory was not created: %v", err)
+	}
+
+	if _, _, err := resolveDataDir(DataModeCustom, "", t.TempDir(), ""); err == nil {
+		t.Error("resolveDataDir() with empty custom directory succeeded")
+	}
+}
+
+func TestResolveReadOnlyExeDir(t *testing.T) {
+	exeDir := t.TempDir()
+	writeFiles(t, exeDir, map[string]string{"config.json": "{}"})
+	if err := os.Chmod(exeDir, 0555); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chmod(exeDir, 0755)
+	if isDirWritable(exeDir) {
+		t.Skip("read-only directories are writable for this user")
+	}
+
+	if _, _, err := resolveDataDir(DataModePortable, "", exeDir, ""); !errors.Is(err, ErrDirNotWritable) {
+		t.Errorf("portable mode error = %v, want ErrDirNotWritable", err)
+	}
+
+	userDir := filepath.Join(t.TempDir(), AppDirName)
+	dir, mode, err := resolveDataDir(DataModeAuto, "", exeDir, userDir)
+	if err != nil || dir != userDir || mode != DataModeUser {
+		t.Errorf("resolveDataDir() = %s, %s, %v, want %s, %s", dir, mode, err, userDir, DataModeUser)
+	}
+}
+
+func TestMigrateToUserDataDir(t *testing.T) {
+	exeDir := t.TempDir()
+	userDir := filepath.Join(t.TempDir(), AppDirName)
+	writeFiles(t, exeDir, map[string]string{
+		"config.json":    `{"current_task": "日报"}`,
+		"task.json":      "{}",
+		"tasks/日报.json":  "{}",
+		"tasks/巡检.dflow": "move 1,1\n",
+		"backups/tasks/日报/20241201-083000.000.json": "{}",
+		"DailyFlow.exe": "MZ",
+	})
+
+	if _, _, err := resolveDataDir(DataModeUser, "", exeDir, userDir); err != nil {
+		t.Fatalf("resolveDataDir() error = %v", err)
+	}
+
+	for _, name := range []string{"config.json", "task.json", "tasks/日报.json", "tasks/巡检.dflow", "backups/tasks/日报/20241201-083000.000.json"} {
+		want, _ := os.ReadFile(filepath.Join(exeDir, name))
+		got, err := os.ReadFile(filepath.Join(userDir, name))
+		if err != nil || string(got) != string(want) {
+			t.Errorf("%s = %q, %v, want %q", name, got, err, want)
+		}
+		// 程序目录中的原文件保留
+		if _, err := os.Stat(filepath.Join(exeDir, name)); err != nil {
+			t.Errorf("source %s removed: %v", name, err)
+		}
+	}
+	for _, name := range []string{"DailyFlow.exe", migrateStagingName} {
+		if _, err := os.Stat(filepath.Join(userDir, name)); !os.IsNotExist(err) {
+			t.Errorf("%s should not exist in the user data directory", name)
+		}
+	}
+
+	// 用户目录已有数据时不再迁移
+	writeFiles(t, exeDir, map[string]string{"tasks/新任务.json": "{}"})
+	if _, _, err := resolveDataDir(DataModeUser, "", exeDir, userDir); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(userDir, "tasks", "新任务.json")); !os.IsNotExist(err) {
+		t.Error("data was migrated again into a user directory that already has data")
+	}
+}
+
+func TestMigrateDataDirRollsBack(t *testing.T) {
+	exeDir := t.TempDir()
+	userDir := filepath.Join(t.TempDir(), AppDirName)
+	writeFiles(t, exeDir, map[string]string{
+		"config.json":   "{}",
+		"tasks/日报.json": "{}",
+		"backups/tasks/日报/20241201-083000.000.json": "{}",
+	})
+
+	// 用户目录中挡路的 backups 使第二项移入失败，已经移入的 tasks 被撤回
+	writeFiles(t, userDir, map[string]string{"backups/占用": "x"})
+	if err := migrateDataDir(exeDir, userDir); err == nil {
+		t.Fatal("migrateDataDir() error = nil, want rename failure")
+	}
+	for _, name := range []string{TasksDirName, ConfigFileName, migrateStagingName} {
+		if _, err := os.Stat(filepath.Join(userDir, name)); !os.IsNotExist(err) {
+			t.Errorf("%s left in the user data directory after a failed migration", name)
+		}
+	}
+
+	// 没有留下数据，下次启动时重新迁移全部内容
+	if err := os.RemoveAll(filepath.Join(userDir, BackupsDirName)); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := resolveDataDir(DataModeUser, "", exeDir, userDir); err != nil {
+		t.Fatalf("resolveDataDir() error = %v", err)
+	}
+	for _, name := range []string{"config.json", "tasks/日报.json", "backups/tasks/日报/20241201-083000.000.json"} {
+		if _, err := os.Stat(filepath.Join(userDir, name)); err != nil {
+			t.Errorf("%s not migrated on retry: %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package storage
+
+import (
+	"bytes"
+	"dailyflow/internal/model"
+	"encoding/binary"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// 数据文件的字节顺序标记
+var (
+	bomUTF8    = []byte{0xEF, 0xBB, 0xBF}
+	bomUTF16LE = []byte{0xFF, 0xFE}
+	bomUTF16BE = []byte{0xFE, 0xFF}
+)
+
+// ErrDataEncoding 数据文件不是 UTF-8 或带 BOM 的 UTF-16
+var ErrDataEncoding = errors.New("data file is not UTF-8 or UTF-16 encoded")
+
+// LoadDataFile 读取数据驱动回放的数据文件，相对路径相对于数据目录
+func LoadDataFile(path string) (*model.DataSet, error) {
+	if !filepath.IsAbs(path) {
+		dataDir, err := GetDataDir()
+		if err != nil {
+			return nil, err
+		}
+		path = filepath.Join(dataDir, path)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read data file: %w", err)
+	}
+	dataSet, err := ParseDataFile(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filepath.Base(path), err)
+	}
+	return dataSet, nil
+}
+
+// ParseDataFile 解析 CSV 或制表符分隔（TSV）的数据，第一行为表头
+// 支持 Excel 另存为的 "CSV UTF-8"（带 BOM）和 "Unicode 文本"（UTF-16、制表符分隔）；
+// 表头中有制表符时按制表符分隔，否则按逗号分隔
+func ParseDataFile(data []byte) (*model.DataSet, error) {
+	text, err := decodeDataText(data)
+	if err != nil {
+		return nil, err
+	}
+
+	reader := csv.NewReader(strings.NewReader(text))
+	header, _, _ := strings.Cut(text, "\n")
+	if strings.Contains(header, "\t") {
+		reader.Comma = '\t'
+	}
+	reader.FieldsPerRecord = -1 // 列数由 NewDataSet 检查，允许行尾省略空列
+
+	// 空行和跨行的引号字段使记录与行不一一对应，记下每条记录开始的行号
+	var records [][]string
+	var lines []int
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse data file: %w", err)
+		}
+		line, _ := reader.FieldPos(0)
+		records = append(records, record)
+		lines = append(lines, line)
+	}
+	return model.NewDataSetWithLines(records, lines)
+}
+
+// decodeDataText 按 BOM 把数据文件解码为字符串，没有 BOM 时要求是合法的 UTF-8
+func decodeDataText(data []byte) (string, error) {
+	switch {
+	case bytes.HasPrefix(data, bomUTF8):
+		data = data[len(bomUTF8):]
+	case bytes.HasPrefix(data, bomUTF16LE):
+		return decodeUTF16(data[len(bomUTF16LE):], binary.LittleEndian)
+	case bytes.HasPrefix(data, bomUTF16BE):
+		return decodeUTF16(data[len(bomUTF16BE):], binary.BigEndian)
+	}
+	if !utf8.Valid(data) {
+		// 多半是 Excel 默认的 "CSV（逗号分隔）"，在中文系统上是 GBK 编码
+		return "", fmt.Errorf("%w, save it as \"CSV UTF-8\" in Excel", ErrDataEncoding)
+	}
+	return string(data), nil
+}
+
+// decodeUTF16 解码不含 BOM 的 UTF-16 数据
+func decodeUTF16(data []byte, order binary.ByteOrder) (string, error) {
+	if len(data)%2 != 0 {
+		return "", fmt.Errorf("%w: odd length for UTF-16", ErrDataEncoding)
+	}
+	units := make([]uint16, len(data)/2)
+	for i := range units {
+		units[i] = order.Uint16(data[2*i:])
+	}
+	return string(utf16.Decode(units)), nil
+}
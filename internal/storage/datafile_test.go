@@ -0,0 +1,85 @@
+package storage
+
+import (
+	"dailyflow/internal/model"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseDataFile(t *testing.T) {
+	want := &model.DataSet{
+		Columns: []string{"单号", "客户", "备注"},
+		Rows:    [][]string{{"A001", "华东, 上海", "首单"}, {"A002", "华南", ""}},
+		Lines:   []int{2, 3},
+	}
+
+	tests := []struct {
+		fixture string
+		wantErr error
+	}{
+		{"data_utf8_bom.csv", nil},
+		{"data_unicode.txt", nil},
+		{"data_gbk.csv", ErrDataEncoding},
+	}
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			data, err := os.ReadFile(filepath.Join("testdata", tt.fixture))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := ParseDataFile(data)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("ParseDataFile() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseDataFile() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("ParseDataFile() = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestParseDataFileLines(t *testing.T) {
+	// 空行被 CSV 解析跳过，引号中的换行使一条记录跨两行，行号仍与文件一致
+	data, err := ParseDataFile([]byte("单号,备注\n\nA1,\"第一行\n第二行\"\n,\nA2,x\n"))
+	if err != nil {
+		t.Fatalf("ParseDataFile() error = %v", err)
+	}
+	if want := []int{3, 6}; !reflect.DeepEqual(data.Lines, want) {
+		t.Errorf("Lines = %v, want %v", data.Lines, want)
+	}
+
+	_, err = ParseDataFile([]byte("单号\n\nA1\nA2,B2\n"))
+	if !errors.Is(err, model.ErrInvalidDataSet) || !strings.Contains(err.Error(), "line 4") {
+		t.Errorf("ParseDataFile() error = %v, want invalid data at line 4", err)
+	}
+}
+
+func TestParseDataFileErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"empty", ""},
+		{"bad column name", "单号,2列\nA1,B1\n"},
+		{"duplicate column", "单号,单号\nA1,A2\n"},
+		{"too many fields", "单号\nA1,B1\n"},
+		{"bad quote", "单号\n\"A1\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, err := ParseDataFile([]byte(tt.data)); err == nil {
+				t.Errorf("ParseDataFile() = %+v, want error", got)
+			}
+		})
+	}
+}
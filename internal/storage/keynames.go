@@ -0,0 +1,124 @@
+package storage
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// keyNames 虚拟键码对应的脚本按键名称
+var keyNames = map[int]string{
+	0x08: "backspace",
+	0x09: "tab",
+	0x0D: "enter",
+	0x10: "shift",
+	0x11: "ctrl",
+	0x12: "alt",
+	0x13: "pause",
+	0x14: "capslock",
+	0x1B: "esc",
+	0x20: "space",
+	0x21: "pageup",
+	0x22: "pagedown",
+	0x23: "end",
+	0x24: "home",
+	0x25: "left",
+	0x26: "up",
+	0x27: "right",
+	0x28: "down",
+	0x2C: "printscreen",
+	0x2D: "insert",
+	0x2E: "delete",
+	0x5B: "lwin",
+	0x5C: "rwin",
+	0x5D: "apps",
+	0x6A: "multiply",
+	0x6B: "add",
+	0x6D: "subtract",
+	0x6E: "decimal",
+	0x6F: "divide",
+	0x90: "numlock",
+	0x91: "scrolllock",
+	0xA0: "lshift",
+	0xA1: "rshift",
+	0xA2: "lctrl",
+	0xA3: "rctrl",
+	0xA4: "lalt",
+	0xA5: "ralt",
+	0xBA: ";",
+	0xBB: "=",
+	0xBC: ",",
+	0xBD: "-",
+	0xBE: ".",
+	0xBF: "/",
+	0xC0: "`",
+	0xDB: "[",
+	0xDC: `\`,
+	0xDD: "]",
+	0xDE: "'",
+}
+
+// keyAliases 解析时额外接受的按键名称
+var keyAliases = map[string]int{
+	"control": 0x11,
+	"return":  0x0D,
+	"escape":  0x1B,
+	"del":     0x2E,
+	"ins":     0x2D,
+	"win":     0x5B,
+	"menu":    0x12,
+}
+
+// keyCodes 按键名称到虚拟键码的反查表
+var keyCodes = func() map[string]int {
+	codes := make(map[string]int, len(keyNames)+len(keyAliases)+26+10+10+24)
+	for code, name := range keyNames {
+		codes[name] = code
+	}
+	for name, code := range keyAliases {
+		codes[name] = code
+	}
+	for c := 'a'; c <= 'z'; c++ {
+		codes[string(c)] = int(c - 'a' + 'A')
+	}
+	for d := 0; d <= 9; d++ {
+		codes[strconv.Itoa(d)] = '0' + d
+		codes["num"+strconv.Itoa(d)] = 0x60 + d
+	}
+	for f := 1; f <= 24; f++ {
+		codes["f"+strconv.Itoa(f)] = 0x6F + f
+	}
+	return codes
+}()
+
+// KeyName 返回虚拟键码的脚本名称，没有名称的键码写成十六进制（如 "0xE2"）
+func KeyName(code int) string {
+	switch {
+	case code >= 'A' && code <= 'Z':
+		return string(rune(code - 'A' + 'a'))
+	case code >= '0' && code <= '9':
+		return string(rune(code))
+	case code >= 0x60 && code <= 0x69:
+		return "num" + strconv.Itoa(code-0x60)
+	case code >= 0x70 && code <= 0x87:
+		return "f" + strconv.Itoa(code-0x6F)
+	}
+	if name, ok := keyNames[code]; ok {
+		return name
+	}
+	return fmt.Sprintf("0x%02X", code)
+}
+
+// ParseKeyName 解析按键名称（不区分大小写）或十六进制键码
+func ParseKeyName(name string) (int, error) {
+	if code, ok := keyCodes[strings.ToLower(name)]; ok {
+		return code, nil
+	}
+	if strings.HasPrefix(name, "0x") || strings.HasPrefix(name, "0X") {
+		code, err := strconv.ParseInt(name[2:], 16, 32)
+		if err == nil && code >= 0 {
+			return int(code), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown key %q", name)
+}
@@ -0,0 +1,339 @@
+package storage
+
+import (
+	"dailyflow/internal/model"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var (
+	// ErrTaskNotFound 任务库中不存在指定名称的任务
+	ErrTaskNotFound = errors.New("task not found")
+	// ErrTaskExists 任务库中已存在同名任务
+	ErrTaskExists = errors.New("task already exists")
+	// ErrInvalidTaskName 任务名称不能用作文件名
+	ErrInvalidTaskName = errors.New("invalid task name")
+)
+
+// maxTaskNameLength 任务名称的最大字符数
+const maxTaskNameLength = 64
+
+// ValidateTaskName 检查任务名称是否可以作为文件名使用
+func ValidateTaskName(name string) error {
+	if strings.TrimSpace(name) == "" {
+		return fmt.Errorf("%w: name is empty", ErrInvalidTaskName)
+	}
+	if name != strings.TrimSpace(name) || strings.HasSuffix(name, ".") {
+		return fmt.Errorf("%w: %q has leading/trailing spaces or a trailing dot", ErrInvalidTaskName, name)
+	}
+	if utf8.RuneCountInString(name) > maxTaskNameLength {
+		return fmt.Errorf("%w: %q is longer than %d characters", ErrInvalidTaskName, name, maxTaskNameLength)
+	}
+	for _, r := range name {
+		if strings.ContainsRune(`\/:*?"<>|`, r) || unicode.IsControl(r) {
+			return fmt.Errorf("%w: %q contains %q", ErrInvalidTaskName, name, r)
+		}
+	}
+	if isReservedDeviceName(name) {
+		return fmt.Errorf("%w: %q is a reserved device name on Windows", ErrInvalidTaskName, name)
+	}
+	return nil
+}
+
+// isReservedDeviceName 判断名称是否为 Windows 保留的设备名（CON、NUL、COM1 等）
+// 带扩展名时同样指向设备，如 "NUL.json"，不区分大小写
+func isReservedDeviceName(name string) bool {
+	stem, _, _ := strings.Cut(name, ".")
+	stem = strings.ToUpper(strings.TrimRight(stem, " "))
+	switch stem {
+	case "CON", "PRN", "AUX", "NUL":
+		return true
+	}
+	if len(stem) == 4 && (strings.HasPrefix(stem, "COM") || strings.HasPrefix(stem, "LPT")) {
+		return stem[3] >= '1' && stem[3] <= '9'
+	}
+	return false
+}
+
+// GetTasksDir 获取任务库目录，不存在时创建，并迁移旧版单任务文件
+func GetTasksDir() (string, error) {
+	dataDir, err := GetDataDir()
+	if err != nil {
+		return "", err
+	}
+
+	tasksDir := filepath.Join(dataDir, TasksDirName)
+	if err := os.MkdirAll(tasksDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create tasks directory: %w", err)
+	}
+
+	if err := migrateLegacyTask(dataDir, tasksDir); err != nil {
+		return "", err
+	}
+
+	return tasksDir, nil
+}
+
+// migrateLegacyTask 把旧版 task.json 移入任务库，命名为 DefaultTaskName
+func migrateLegacyTask(dataDir, tasksDir string) error {
+	legacyPath := filepath.Join(dataDir, LegacyTaskFileName)
+	if _, err := os.Stat(legacyPath); os.IsNotExist(err) {
+		return nil
+	}
+
+	targetPath := filepath.Join(tasksDir, DefaultTaskName+TaskFileExt)
+	if _, err := os.Stat(targetPath); err == nil {
+		// 已迁移过（或用户自建了同名任务），保留旧文件不做处理
+		return nil
+	}
+
+	if err := os.Rename(legacyPath, targetPath); err != nil {
+		return fmt.Errorf("failed to migrate %s: %w", LegacyTaskFileName, err)
+	}
+	return nil
+}
+
+// taskFileBase 返回任务在任务库中不含扩展名的文件路径
+func taskFileBase(name string) (string, error) {
+	if err := ValidateTaskName(name); err != nil {
+		return "", err
+	}
+
+	tasksDir, err := GetTasksDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(tasksDir, name), nil
+}
+
+// taskFilePath 返回任务在任务库中的文件路径
+// 任务可以保存为 JSON 或文本脚本，已存在时返回现有文件，新任务使用 JSON
+func taskFilePath(name string) (string, error) {
+	base, err := taskFileBase(name)
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(base + TaskFileExt); err != nil {
+		if _, err := os.Stat(base + ScriptFileExt); err == nil {
+			return base + ScriptFileExt, nil
+		}
+	}
+	return base + TaskFileExt, nil
+}
+
+// TaskPath 返回已存在任务的文件路径（用于在外部编辑器中打开）
+func TaskPath(name string) (string, error) {
+	return existingTaskPath(name)
+}
+
+// TaskExists 检查任务库中是否存在指定任务
+func TaskExists(name string) bool {
+	taskPath, err := taskFilePath(name)
+	if err != nil {
+		return false
+	}
+	_, err = os.Stat(taskPath)
+	return err == nil
+}
+
+// isTaskFileExt 判断扩展名是否为任务库支持的文件格式
+func isTaskFileExt(ext string) bool {
+	return ext == TaskFileExt || ext == ScriptFileExt
+}
+
+// ListTasks 列出任务库中的所有任务名称（按名称排序）
+func ListTasks() ([]string, error) {
+	tasksDir, err := GetTasksDir()
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(tasksDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tasks directory: %w", err)
+	}
+
+	names := make([]string, 0, len(entries))
+	seen := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		ext := filepath.Ext(entry.Name())
+		if entry.IsDir() || !isTaskFileExt(ext) {
+			continue
+		}
+		// 同名的 JSON 和脚本文件只算一个任务（以 JSON 为准）
+		name := strings.TrimSuffix(entry.Name(), ext)
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	return names, nil
+}
+
+// CreateTask 在任务库中创建一个空任务
+func CreateTask(name string) error {
+	if TaskExists(name) {
+		return fmt.Errorf("%w: %s", ErrTaskExists, name)
+	}
+	return SaveTask(name, model.NewTaskData(""))
+}
+
+// RenameTask 重命名任务，任务的备份目录随之改名
+// 只改变大小写时（Windows 上视为同一个文件）直接改名；新名称下已有备份时备份保留在原名称下，
+// 任务文件已经改名但备份目录改名失败时返回错误
+func RenameTask(oldName, newName string) error {
+	oldPath, err := existingTaskPath(oldName)
+	if err != nil {
+		return err
+	}
+	newBase, err := taskFileBase(newName)
+	if err != nil {
+		return err
+	}
+	if TaskExists(newName) {
+		existing, err := existingTaskPath(newName)
+		if err != nil || !sameFile(oldPath, existing) {
+			return fmt.Errorf("%w: %s", ErrTaskExists, newName)
+		}
+	}
+	newPath := newBase + filepath.Ext(oldPath)
+
+	if err := os.Rename(oldPath, newPath); err != nil {
+		return fmt.Errorf("failed to rename task: %w", err)
+	}
+
+	oldBackupDir, err := taskBackupDir(oldName)
+	if err != nil {
+		return err
+	}
+	newBackupDir, err := taskBackupDir(newName)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(oldBackupDir); os.IsNotExist(err) {
+		return nil
+	}
+	if _, err := os.Stat(newBackupDir); err == nil && !sameFile(oldBackupDir, newBackupDir) {
+		return nil
+	}
+	if err := os.Rename(oldBackupDir, newBackupDir); err != nil {
+		return fmt.Errorf("task renamed but failed to rename its backups: %w", err)
+	}
+	return nil
+}
+
+// sameFile 判断两个路径是否指向同一个文件或目录（如 Windows 上只有大小写不同的路径）
+func sameFile(a, b string) bool {
+	aInfo, err := os.Stat(a)
+	if err != nil {
+		return false
+	}
+	bInfo, err := os.Stat(b)
+	if err != nil {
+		return false
+	}
+	return os.SameFile(aInfo, bInfo)
+}
+
+// DuplicateTask 复制任务为新名称
+func DuplicateTask(srcName, dstName string) error {
+	srcPath, err := existingTaskPath(srcName)
+	if err != nil {
+		return err
+	}
+	dstBase, err := taskFileBase(dstName)
+	if err != nil {
+		return err
+	}
+	if TaskExists(dstName) {
+		return fmt.Errorf("%w: %s", ErrTaskExists, dstName)
+	}
+	dstPath := dstBase + filepath.Ext(srcPath)
+
+	src, err := os.Open(srcPath)
+	if err != nil {
+		return fmt.Errorf("failed to open task file: %w", err)
+	}
+	defer src.Close()
+
+	// O_EXCL 保证不会覆盖已存在的任务
+	dst, err := os.OpenFile(dstPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if os.IsExist(err) {
+		return fmt.Errorf("%w: %s", ErrTaskExists, dstName)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create task file: %w", err)
+	}
+
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		os.Remove(dstPath)
+		return fmt.Errorf("failed to copy task: %w", err)
+	}
+	if err := dst.Close(); err != nil {
+		os.Remove(dstPath)
+		return fmt.Errorf("failed to copy task: %w", err)
+	}
+	return nil
+}
+
+// DeleteTask 从任务库中删除任务，任务的备份仍保留在备份目录中
+func DeleteTask(name string) error {
+	taskPath, err := existingTaskPath(name)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(taskPath); err != nil {
+		return fmt.Errorf("failed to delete task: %w", err)
+	}
+	return nil
+}
+
+// ConvertTask 把任务转存为指定格式（TaskFileExt 或 ScriptFileExt），并删除原格式的文件
+func ConvertTask(name, ext string) error {
+	if !isTaskFileExt(ext) {
+		return fmt.Errorf("unsupported task file format %q", ext)
+	}
+	oldPath, err := existingTaskPath(name)
+	if err != nil {
+		return err
+	}
+	if filepath.Ext(oldPath) == ext {
+		return nil
+	}
+
+	taskData, err := loadTaskFile(oldPath)
+	if err != nil {
+		return err
+	}
+	newPath := strings.TrimSuffix(oldPath, filepath.Ext(oldPath)) + ext
+	if err := writeTaskFile(newPath, taskData, isEncryptedFile(oldPath)); err != nil {
+		return err
+	}
+	if err := os.Remove(oldPath); err != nil {
+		return fmt.Errorf("failed to remove %s: %w", filepath.Base(oldPath), err)
+	}
+	return nil
+}
+
+// existingTaskPath 返回已存在任务的文件路径，不存在时返回 ErrTaskNotFound
+func existingTaskPath(name string) (string, error) {
+	taskPath, err := taskFilePath(name)
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(taskPath); os.IsNotExist(err) {
+		return "", fmt.Errorf("%w: %s", ErrTaskNotFound, name)
+	}
+	return taskPath, nil
+}
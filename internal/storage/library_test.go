@@ -0,0 +1,92 @@
+package storage
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestValidateTaskName(t *testing.T) {
+	valid := []string{"日报", "Daily Report", "v1.2", "CONSOLE", "COM10", "LPT0", "nullable", "备份 NUL"}
+	for _, name := range valid {
+		if err := ValidateTaskName(name); err != nil {
+			t.Errorf("ValidateTaskName(%q) error = %v, want nil", name, err)
+		}
+	}
+
+	invalid := []string{
+		"", "  ", " 日报", "日报 ", "日报.", "a/b", `a\b`, "a:b", "a*b", "a?b", `a"b`, "a<b", "a|b", "a\tb",
+		strings.Repeat("长", maxTaskNameLength+1),
+		// Windows 保留的设备名，带扩展名或小写同样不可用
+		"CON", "PRN", "AUX", "NUL", "COM1", "COM9", "LPT1", "LPT9",
+		"nul", "Con", "com3", "NUL.json", "aux.txt", "lpt2.tar.gz", "NUL .txt",
+	}
+	for _, name := range invalid {
+		if err := ValidateTaskName(name); !errors.Is(err, ErrInvalidTaskName) {
+			t.Errorf("ValidateTaskName(%q) error = %v, want ErrInvalidTaskName", name, err)
+		}
+	}
+}
+
+func TestListTasksIgnoresOtherFiles(t *testing.T) {
+	useTempDataDir(t)
+	tasksDir, err := GetTasksDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 用户放进任务库的说明文件不是任务
+	for _, name := range []string{"日报" + TaskFileExt, "巡检" + ScriptFileExt, "README.txt", "笔记.txt"} {
+		if err := os.WriteFile(filepath.Join(tasksDir, name), []byte("{}"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	names, err := ListTasks()
+	if want := []string{"巡检", "日报"}; err != nil || !reflect.DeepEqual(names, want) {
+		t.Errorf("ListTasks() = %v, %v, want %v", names, err, want)
+	}
+}
+
+func TestRenameTask(t *testing.T) {
+	useTempDataDir(t)
+	tasksDir, err := GetTasksDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"report", "周报"} {
+		if err := CreateTask(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	oldBackupDir, _ := taskBackupDir("report")
+	if err := os.MkdirAll(oldBackupDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := RenameTask("report", "周报"); !errors.Is(err, ErrTaskExists) {
+		t.Errorf("RenameTask() onto another task error = %v, want ErrTaskExists", err)
+	}
+
+	// 在不区分大小写的文件系统上 "Report" 与 "report" 是同一个文件，这里用符号链接模拟
+	oldPath := filepath.Join(tasksDir, "report"+TaskFileExt)
+	newPath := filepath.Join(tasksDir, "Report"+TaskFileExt)
+	if err := os.Symlink(oldPath, newPath); err != nil {
+		t.Skipf("symlinks not available: %v", err)
+	}
+	if err := RenameTask("report", "Report"); err != nil {
+		t.Fatalf("RenameTask() changing only case error = %v", err)
+	}
+	if info, err := os.Lstat(newPath); err != nil || !info.Mode().IsRegular() {
+		t.Errorf("renamed task file = %v, %v, want a regular file", info, err)
+	}
+	if _, err := os.Stat(oldPath); !os.IsNotExist(err) {
+		t.Errorf("old task file still exists: %v", err)
+	}
+	newBackupDir, _ := taskBackupDir("Report")
+	if _, err := os.Stat(newBackupDir); err != nil {
+		t.Errorf("backups were not renamed: %v", err)
+	}
+}
@@ -0,0 +1,290 @@
+package storage
+
+import (
+	"bytes"
+	"dailyflow/internal/model"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrUnsupportedVersion 任务文件版本无法识别或高于当前程序支持的版本
+var ErrUnsupportedVersion = errors.New("unsupported task file version")
+
+// legacyTaskVersion 未写入版本号的早期文件按此版本处理
+const legacyTaskVersion = "1.0"
+
+// taskMigration 描述一次版本升级：把 from 版本的原始 JSON 文档改写为 to 版本
+// migrate 为 nil 表示只新增了字段或事件类型，旧文档无需改写
+type taskMigration struct {
+	from    string
+	to      string
+	migrate func(doc map[string]interface{}) error
+}
+
+// taskMigrations 迁移链，必须按版本顺序排列，最后一项的 to 等于 model.TaskVersion
+var taskMigrations = []taskMigration{
+	{from: "1.0", to: "1.1", migrate: migrateTask_1_0_to_1_1},
+	{from: "1.1", to: "1.2"}, // 新增 mouse_wheel 事件
+	{from: "1.2", to: "1.3"}, // 新增 mouse_down / mouse_up 事件
+	{from: "1.3", to: "1.4"}, // 新增 type_text 事件
+	{from: "1.4", to: "1.5"}, // 新增 meta.monitors 显示器布局
+	{from: "1.5", to: "1.6"}, // 点击事件新增 window / rel_x / rel_y
+	{from: "1.6", to: "1.7"}, // 新增 secret 敏感输入占位事件
+	{from: "1.7", to: "1.8", migrate: migrateTask_1_7_to_1_8},
+	{from: "1.8", to: "1.9"},   // 新增 repeat 块
+	{from: "1.9", to: "1.10"},  // 新增 call 步骤
+	{from: "1.10", to: "1.11"}, // 新增 if 块和 wait_window 步骤
+}
+
+// decodeTask 解析任务文件内容，必要时沿迁移链升级到当前版本
+func decodeTask(data []byte) (*model.TaskData, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var doc map[string]interface{}
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("%w: failed to parse task file: %w", ErrCorrupt, err)
+	}
+
+	version, err := documentVersion(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	// 迁移可能改变事件个数（如 1.0 拆分 key_press），此时总数按迁移后的事件重新计算
+	keepTotal := version == model.TaskVersion && documentHasTotalEvents(doc)
+
+	if version != model.TaskVersion {
+		if err := migrateTask(doc, version); err != nil {
+			return nil, err
+		}
+
+		// 迁移后重新编码，再按当前结构解析
+		if data, err = json.Marshal(doc); err != nil {
+			return nil, fmt.Errorf("failed to encode migrated task: %w", err)
+		}
+	}
+
+	var taskData model.TaskData
+	if err := json.Unmarshal(data, &taskData); err != nil {
+		return nil, fmt.Errorf("%w: failed to parse task file: %w", ErrCorrupt, err)
+	}
+	// 保留文件中记录的总数，Validate 据此发现与事件数不一致的文件
+	if !keepTotal {
+		taskData.Meta.TotalEvents = len(taskData.Events)
+	}
+
+	return &taskData, nil
+}
+
+// documentHasTotalEvents 判断原始文档是否写有 meta.total_events
+func documentHasTotalEvents(doc map[string]interface{}) bool {
+	meta, ok := doc["meta"].(map[string]interface{})
+	if !ok {
+		return false
+	}
+	_, ok = meta["total_events"]
+	return ok
+}
+
+// documentVersion 读取 meta.version 并检查是否为当前程序可处理的版本
+func documentVersion(doc map[string]interface{}) (string, error) {
+	version := legacyTaskVersion
+	if meta, ok := doc["meta"].(map[string]interface{}); ok {
+		if v, ok := meta["version"].(string); ok && v != "" {
+			version = v
+		}
+	}
+
+	newer, err := versionNewer(version, model.TaskVersion)
+	if err != nil {
+		return "", fmt.Errorf("%w: %q", ErrUnsupportedVersion, version)
+	}
+	if newer {
+		return "", fmt.Errorf("%w: task file version %s is newer than supported version %s, please upgrade DailyFlow",
+			ErrUnsupportedVersion, version, model.TaskVersion)
+	}
+
+	return version, nil
+}
+
+// migrateTask 从 version 开始依次执行迁移，直到当前版本
+func migrateTask(doc map[string]interface{}, version string) error {
+	for _, m := range taskMigrations {
+		if m.from != version {
+			continue
+		}
+		if m.migrate != nil {
+			if err := m.migrate(doc); err != nil {
+				return fmt.Errorf("failed to migrate task from %s to %s: %w", m.from, m.to, err)
+			}
+		}
+		version = m.to
+	}
+
+	if version != model.TaskVersion {
+		return fmt.Errorf("%w: no migration path from %s", ErrUnsupportedVersion, version)
+	}
+
+	meta, ok := doc["meta"].(map[string]interface{})
+	if !ok {
+		meta = make(map[string]interface{})
+		doc["meta"] = meta
+	}
+	meta["version"] = version
+
+	return nil
+}
+
+// versionNewer 判断 a 是否比 b 新，版本格式为 "主版本.次版本"
+func versionNewer(a, b string) (bool, error) {
+	aMajor, aMinor, err := parseVersion(a)
+	if err != nil {
+		return false, err
+	}
+	bMajor, bMinor, err := parseVersion(b)
+	if err != nil {
+		return false, err
+	}
+	if aMajor != bMajor {
+		return aMajor > bMajor, nil
+	}
+	return aMinor > bMinor, nil
+}
+
+// parseVersion 解析 "主版本.次版本" 格式的版本号
+func parseVersion(version string) (int, int, error) {
+	majorStr, minorStr, found := strings.Cut(version, ".")
+	if !found {
+		return 0, 0, fmt.Errorf("invalid version %q", version)
+	}
+	major, err := strconv.Atoi(majorStr)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid version %q", version)
+	}
+	minor, err := strconv.Atoi(minorStr)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid version %q", version)
+	}
+	return major, minor, nil
+}
+
+// documentEvents 返回原始文档中的事件数组
+func documentEvents(doc map[string]interface{}) ([]interface{}, error) {
+	raw, ok := doc["events"]
+	if !ok || raw == nil {
+		return nil, nil
+	}
+	events, ok := raw.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("events is not an array")
+	}
+	return events, nil
+}
+
+// migrateTask_1_0_to_1_1 把旧版 key_press 拆分为 key_down + key_up
+// 旧版回放在按下和释放之间固定等待 10ms，迁移后保持相同节奏
+func migrateTask_1_0_to_1_1(doc map[string]interface{}) error {
+	events, err := documentEvents(doc)
+	if err != nil {
+		return err
+	}
+
+	migrated := make([]interface{}, 0, len(events))
+	for _, raw := range events {
+		event, ok := raw.(map[string]interface{})
+		if !ok || event["type"] != model.EventKeyPress {
+			migrated = append(migrated, raw)
+			continue
+		}
+
+		down := make(map[string]interface{}, len(event))
+		for k, v := range event {
+			down[k] = v
+		}
+		down["type"] = model.EventKeyDown
+
+		up := make(map[string]interface{}, len(event))
+		for k, v := range event {
+			up[k] = v
+		}
+		up["type"] = model.EventKeyUp
+		up["delay"] = 10
+
+		migrated = append(migrated, down, up)
+	}
+	doc["events"] = migrated
+
+	return nil
+}
+
+// migrateTask_1_7_to_1_8 type_text 开始支持 {{...}} 模板变量
+// 转义旧文本中已有的 "{{"，使其仍按原样输入
+func migrateTask_1_7_to_1_8(doc map[string]interface{}) error {
+	events, err := documentEvents(doc)
+	if err != nil {
+		return err
+	}
+	for _, raw := range events {
+		event, ok := raw.(map[string]interface{})
+		if !ok || event["type"] != model.EventTypeText {
+			continue
+		}
+		if text, ok := event["text"].(string); ok {
+			event["text"] = model.EscapeTemplate(text)
+		}
+	}
+	return nil
+}
+
+// legacyConfig 多任务之前的配置格式：定时、速度和运行状态只有一份
+type legacyConfig struct {
+	ScheduleTime *string `json:"schedule_time"`
+	IsEnabled    bool    `json:"is_enabled"`
+	SpeedFactor  float64 `json:"speed_factor"`
+	LastRunDate  string  `json:"last_run_date"`
+}
+
+// decodeConfig 解析配置文件内容，旧版单任务配置会迁移为对应任务的设置
+// 返回值 migrated 表示内容发生了迁移，调用方应回写文件
+func decodeConfig(data []byte) (config *model.Config, migrated bool, err error) {
+	config = model.NewConfig()
+	if err := json.Unmarshal(data, config); err != nil {
+		return nil, false, fmt.Errorf("%w: failed to parse config file: %w", ErrCorrupt, err)
+	}
+	if config.Tasks == nil {
+		config.Tasks = make(map[string]*model.TaskSchedule)
+	}
+
+	var legacy legacyConfig
+	if err := json.Unmarshal(data, &legacy); err != nil {
+		return nil, false, fmt.Errorf("%w: failed to parse config file: %w", ErrCorrupt, err)
+	}
+	if legacy.ScheduleTime == nil {
+		return config, false, nil
+	}
+
+	// 旧版配置只对应一个任务：当前任务，或由 task.json 迁移来的默认任务
+	taskName := config.CurrentTask
+	if taskName == "" {
+		taskName = DefaultTaskName
+		config.CurrentTask = taskName
+	}
+	if _, exists := config.Tasks[taskName]; !exists {
+		speedFactor := legacy.SpeedFactor
+		if speedFactor <= 0 {
+			speedFactor = 1.0
+		}
+		config.Tasks[taskName] = &model.TaskSchedule{
+			ScheduleTime: *legacy.ScheduleTime,
+			IsEnabled:    legacy.IsEnabled,
+			SpeedFactor:  speedFactor,
+			LastRunDate:  legacy.LastRunDate,
+		}
+	}
+
+	return config, true, nil
+}
@@ -0,0 +1,204 @@
+package storage
+
+import (
+	"dailyflow/internal/model"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func keyEvent(eventType string, keyCode, delay int) model.Event {
+	return model.Event{Type: eventType, Button: "none", KeyCode: keyCode, Delay: delay}
+}
+
+func TestDecodeTaskVersions(t *testing.T) {
+	tests := []struct {
+		name    string
+		fixture string
+		want    []model.Event
+		wantErr error
+	}{
+		{
+			name:    "v1.0 splits key_press into down and up",
+			fixture: "task_v1.0.json",
+			want: []model.Event{
+				{Type: model.EventMouseMove, X: 100, Y: 200, Button: "none"},
+				{Type: model.EventMouseClick, X: 100, Y: 200, Button: "left", Delay: 120},
+				keyEvent(model.EventKeyDown, 65, 300),
+				keyEvent(model.EventKeyUp, 65, 10),
+			},
+		},
+		{
+			name:    "missing version is treated as v1.0",
+			fixture: "task_unversioned.json",
+			want: []model.Event{
+				keyEvent(model.EventKeyDown, 13, 50),
+				keyEvent(model.EventKeyUp, 13, 10),
+			},
+		},
+		{
+			name:    "v1.1 loads without rewriting events",
+			fixture: "task_v1.1.json",
+			want: []model.Event{
+				keyEvent(model.EventKeyDown, 17, 0),
+				keyEvent(model.EventKeyDown, 83, 80),
+				keyEvent(model.EventKeyUp, 83, 60),
+				keyEvent(model.EventKeyUp, 17, 40),
+			},
+		},
+		{
+			name:    "v1.7 escapes text that looks like a template",
+			fixture: "task_v1.7.json",
+			want: []model.Event{
+				{Type: model.EventTypeText, Button: "none", Text: `{{"{{"}}date}} 不是变量`},
+				{Type: model.EventSecret, Button: "none", Delay: 50, Secret: "门户#1"},
+			},
+		},
+		{
+			name:    "newer version is rejected",
+			fixture: "task_v9.0.json",
+			wantErr: ErrUnsupportedVersion,
+		},
+		{
+			name:    "malformed version is rejected",
+			fixture: "task_badversion.json",
+			wantErr: ErrUnsupportedVersion,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := os.ReadFile(filepath.Join("testdata", tt.fixture))
+			if err != nil {
+				t.Fatalf("read fixture: %v", err)
+			}
+
+			task, err := decodeTask(data)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("decodeTask() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("decodeTask() unexpected error: %v", err)
+			}
+
+			if task.Meta.Version != model.TaskVersion {
+				t.Errorf("Meta.Version = %q, want %q", task.Meta.Version, model.TaskVersion)
+			}
+			if task.Meta.TotalEvents != len(tt.want) {
+				t.Errorf("Meta.TotalEvents = %d, want %d", task.Meta.TotalEvents, len(tt.want))
+			}
+			if !reflect.DeepEqual(task.Events, tt.want) {
+				t.Errorf("Events = %+v, want %+v", task.Events, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeTaskKeepsTotalEvents(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "task_wrong_total.json"))
+	if err != nil {
+		t.Fatalf("read fixture: %v", err)
+	}
+	task, err := decodeTask(data)
+	if err != nil {
+		t.Fatalf("decodeTask() unexpected error: %v", err)
+	}
+
+	if task.Meta.TotalEvents != 5 {
+		t.Errorf("Meta.TotalEvents = %d, want 5 as stored in the file", task.Meta.TotalEvents)
+	}
+	findings := task.Validate()
+	if len(findings) != 1 || findings[0].Check != model.CheckTotalEvents {
+		t.Errorf("Validate() = %+v, want a total_events finding", findings)
+	}
+}
+
+func TestMigrationChainEndsAtCurrentVersion(t *testing.T) {
+	if len(taskMigrations) == 0 {
+		t.Skip("no migrations registered")
+	}
+	for i := 1; i < len(taskMigrations); i++ {
+		if taskMigrations[i].from != taskMigrations[i-1].to {
+			t.Errorf("migration %d starts at %s, previous ends at %s", i, taskMigrations[i].from, taskMigrations[i-1].to)
+		}
+	}
+	if last := taskMigrations[len(taskMigrations)-1].to; last != model.TaskVersion {
+		t.Errorf("migration chain ends at %s, want %s", last, model.TaskVersion)
+	}
+}
+
+func TestDecodeConfig(t *testing.T) {
+	tests := []struct {
+		name         string
+		fixture      string
+		wantMigrated bool
+		wantCurrent  string
+		wantAuto     bool
+		wantTasks    map[string]model.TaskSchedule
+	}{
+		{
+			name:         "legacy config becomes default task schedule",
+			fixture:      "config_legacy.json",
+			wantMigrated: true,
+			wantCurrent:  DefaultTaskName,
+			wantAuto:     true,
+			wantTasks: map[string]model.TaskSchedule{
+				DefaultTaskName: {ScheduleTime: "09:15", IsEnabled: true, SpeedFactor: 0.8, LastRunDate: "2024-12-01"},
+			},
+		},
+		{
+			name:         "legacy config with current task",
+			fixture:      "config_legacy_current.json",
+			wantMigrated: true,
+			wantCurrent:  "早报",
+			wantTasks: map[string]model.TaskSchedule{
+				"早报": {ScheduleTime: "07:45", SpeedFactor: 1.0},
+			},
+		},
+		{
+			name:        "per-task config loads unchanged",
+			fixture:     "config_tasks.json",
+			wantCurrent: "数据导出",
+			wantTasks: map[string]model.TaskSchedule{
+				"早报":   {ScheduleTime: "08:30", IsEnabled: true, SpeedFactor: 1, LastRunDate: "2024-12-02"},
+				"数据导出": {ScheduleTime: "17:00", SpeedFactor: 0.5},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := os.ReadFile(filepath.Join("testdata", tt.fixture))
+			if err != nil {
+				t.Fatalf("read fixture: %v", err)
+			}
+
+			config, migrated, err := decodeConfig(data)
+			if err != nil {
+				t.Fatalf("decodeConfig() unexpected error: %v", err)
+			}
+			if migrated != tt.wantMigrated {
+				t.Errorf("migrated = %v, want %v", migrated, tt.wantMigrated)
+			}
+			if config.CurrentTask != tt.wantCurrent {
+				t.Errorf("CurrentTask = %q, want %q", config.CurrentTask, tt.wantCurrent)
+			}
+			if config.AutoStart != tt.wantAuto {
+				t.Errorf("AutoStart = %v, want %v", config.AutoStart, tt.wantAuto)
+			}
+
+			got := make(map[string]model.TaskSchedule, len(config.Tasks))
+			for name, schedule := range config.Tasks {
+				got[name] = *schedule
+			}
+			if !reflect.DeepEqual(got, tt.wantTasks) {
+				t.Errorf("Tasks = %+v, want %+v", got, tt.wantTasks)
+			}
+		})
+	}
+}
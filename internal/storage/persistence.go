@@ -3,14 +3,21 @@ package storage
 import (
 	"dailyflow/internal/model"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
 const (
-	TaskFileName   = "task.json"
+	TasksDirName   = "tasks"
+	TaskFileExt    = ".json"
 	ConfigFileName = "config.json"
+
+	// LegacyTaskFileName 多任务之前的单任务文件，首次使用任务库时迁移为 DefaultTaskName
+	LegacyTaskFileName = "task.json"
+	DefaultTaskName    = "默认任务"
 )
 
 // GetExecutableDir 获取可执行文件所在目录
@@ -22,63 +29,91 @@ func GetExecutableDir() (string, error) {
 	return filepath.Dir(exePath), nil
 }
 
-// LoadTask 从 task.json 加载任务数据
-func LoadTask() (*model.TaskData, error) {
-	execDir, err := GetExecutableDir()
+// LoadTask 从任务库加载指定名称的任务，旧版本文件会在加载时升级到当前版本
+func LoadTask(name string) (*model.TaskData, error) {
+	taskPath, err := existingTaskPath(name)
 	if err != nil {
 		return nil, err
 	}
+	return loadTaskFile(taskPath)
+}
 
-	taskPath := filepath.Join(execDir, TaskFileName)
-	
-	// 检查文件是否存在
-	if _, err := os.Stat(taskPath); os.IsNotExist(err) {
-		// 文件不存在，返回空任务数据
-		return model.NewTaskData(""), nil
+// SaveTask 保存任务数据到任务库，同名任务会被覆盖（保持原有的文件格式，已加密的任务仍然加密）
+func SaveTask(name string, taskData *model.TaskData) error {
+	taskPath, err := taskFilePath(name)
+	if err != nil {
+		return err
 	}
+	return writeTaskFile(taskPath, taskData, isEncryptedFile(taskPath))
+}
 
+// loadTaskFile 按扩展名解析 JSON 或文本脚本格式的任务文件，加密的文件先用当前口令解密
+func loadTaskFile(taskPath string) (*model.TaskData, error) {
 	data, err := os.ReadFile(taskPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read task file: %w", err)
 	}
 
-	var taskData model.TaskData
-	if err := json.Unmarshal(data, &taskData); err != nil {
-		return nil, fmt.Errorf("failed to parse task file: %w", err)
+	if IsEncrypted(data) {
+		data, err = decryptData(data)
+		if errors.Is(err, ErrTampered) {
+			return nil, fmt.Errorf("%w: %s: %w", ErrCorrupt, filepath.Base(taskPath), err)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", filepath.Base(taskPath), err)
+		}
+	}
+
+	if filepath.Ext(taskPath) == ScriptFileExt {
+		taskData, err := ParseScript(data)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %w", ErrCorrupt, filepath.Base(taskPath), err)
+		}
+		return taskData, nil
 	}
 
-	return &taskData, nil
+	// 按版本解析，旧版本自动迁移，新版本直接拒绝
+	return decodeTask(data)
 }
 
-// SaveTask 保存任务数据到 task.json
-func SaveTask(taskData *model.TaskData) error {
-	execDir, err := GetExecutableDir()
+// writeTaskFile 按扩展名把任务写为 JSON 或文本脚本，encrypt 时用当前口令加密，旧内容保留为备份
+func writeTaskFile(taskPath string, taskData *model.TaskData, encrypt bool) error {
+	ext := filepath.Ext(taskPath)
+	backupDir, err := taskBackupDir(strings.TrimSuffix(filepath.Base(taskPath), ext))
 	if err != nil {
 		return err
 	}
 
-	taskPath := filepath.Join(execDir, TaskFileName)
-
-	data, err := json.MarshalIndent(taskData, "", "  ")
-	if err != nil {
-		return fmt.Errorf("failed to marshal task data: %w", err)
+	var data []byte
+	if ext == ScriptFileExt {
+		data = PrintScript(taskData)
+	} else {
+		data, err = json.MarshalIndent(taskData, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal task data: %w", err)
+		}
+	}
+	if encrypt {
+		if data, err = encryptData(data); err != nil {
+			return fmt.Errorf("failed to encrypt task: %w", err)
+		}
 	}
 
-	if err := os.WriteFile(taskPath, data, 0644); err != nil {
+	if err := writeFileBackedUp(taskPath, backupDir, data); err != nil {
 		return fmt.Errorf("failed to write task file: %w", err)
 	}
 
 	return nil
 }
 
-// LoadConfig 从 config.json 加载配置
+// LoadConfig 从 config.json 加载配置，旧版单任务配置会自动迁移
 func LoadConfig() (*model.Config, error) {
-	execDir, err := GetExecutableDir()
+	dataDir, err := GetDataDir()
 	if err != nil {
 		return nil, err
 	}
 
-	configPath := filepath.Join(execDir, ConfigFileName)
+	configPath := filepath.Join(dataDir, ConfigFileName)
 
 	// 检查文件是否存在
 	if _, err := os.Stat(configPath); os.IsNotExist(err) {
@@ -91,29 +126,40 @@ func LoadConfig() (*model.Config, error) {
 		return nil, fmt.Errorf("failed to read config file: %w", err)
 	}
 
-	var config model.Config
-	if err := json.Unmarshal(data, &config); err != nil {
-		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	config, migrated, err := decodeConfig(data)
+	if err != nil {
+		return nil, err
 	}
 
-	return &config, nil
+	// 旧版配置迁移后立即回写，避免下次启动重复迁移
+	if migrated {
+		if err := SaveConfig(config); err != nil {
+			return nil, err
+		}
+	}
+
+	return config, nil
 }
 
-// SaveConfig 保存配置到 config.json
+// SaveConfig 保存配置到 config.json，旧内容保留为备份
 func SaveConfig(config *model.Config) error {
-	execDir, err := GetExecutableDir()
+	dataDir, err := GetDataDir()
 	if err != nil {
 		return err
 	}
 
-	configPath := filepath.Join(execDir, ConfigFileName)
+	configPath := filepath.Join(dataDir, ConfigFileName)
+	backupDir, err := configBackupDir()
+	if err != nil {
+		return err
+	}
 
 	data, err := json.MarshalIndent(config, "", "  ")
 	if err != nil {
 		return fmt.Errorf("failed to marshal config data: %w", err)
 	}
 
-	if err := os.WriteFile(configPath, data, 0644); err != nil {
+	if err := writeFileBackedUp(configPath, backupDir, data); err != nil {
 		return fmt.Errorf("failed to write config file: %w", err)
 	}
 
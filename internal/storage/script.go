@@ -0,0 +1,836 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"dailyflow/internal/model"
+	"dailyflow/internal/screen"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ScriptFileExt 文本脚本格式的任务文件扩展名
+// 不使用 .txt，避免用户放进任务库的说明文件等被当作任务列出和执行
+const ScriptFileExt = ".dflow"
+
+// scriptHeader 脚本文件开头的说明注释
+const scriptHeader = "# DailyFlow 任务脚本，语法见 docs/USER_GUIDE.md"
+
+// ScriptError 脚本解析错误，Line 从 1 开始
+type ScriptError struct {
+	Line int
+	Err  error
+}
+
+func (e *ScriptError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+func (e *ScriptError) Unwrap() error {
+	return e.Err
+}
+
+// ParseScript 解析文本脚本格式的任务
+//
+// 每行一条语句，# 开头的行为注释：
+//
+//	version 1.11                       数据版本，低于当前版本的脚本解析后按迁移链升级
+//	created 2024-12-01T08:30:00+08:00  录制时间
+//	resolution 1920x1080               录制时的主屏分辨率
+//	monitor 0,0,1920,1080 primary      显示器布局（左,上,宽,高），每个显示器一行
+//	wait 300ms                         下一步之前等待，多个 wait 会累加
+//	move 812,440                       鼠标移动
+//	click left 812,440                 点击（down / up 为按下 / 释放，用于拖拽）
+//	wheel -120 812,440                 纵向滚轮（hwheel 为横向），120 为一格
+//	keydown ctrl / keyup ctrl          按下 / 释放按键
+//	key ctrl+s                         组合键：依次按下，再倒序释放
+//	type "日报{{date}}"                输入文本（Go 字符串语法），可含模板变量
+//	secret "门户#1"                    输入凭据存储中的敏感内容（如密码），脚本中只有名称
+//	repeat 20 as i ... end             把其间的步骤重复 20 次，文本中可用 {{i}} 引用当前次数
+//	call "登录门户" speed=0.8          回放任务库中的另一个任务，speed 省略时使用该任务自己的速度
+//	if weekday == 1 ... else ... end   条件成立时执行 if 之后的步骤，否则执行 else 之后的步骤（else 可省略）
+//	waitwindow title="日报" timeout=10s as found
+//	                                   等待窗口出现（可加 class="..."），as 把是否出现保存到变量，省略时超时即出错
+//	raw {"type":"...", ...}            其他无法用以上语句表示的事件（JSON）
+//
+// 鼠标语句后可跟 title="..." class="..." rect=左,上,宽,高 rel=x,y，表示按窗口定位的点击目标
+func ParseScript(data []byte) (*model.TaskData, error) {
+	p := &scriptParser{
+		task: &model.TaskData{
+			Meta:   model.TaskMeta{Version: model.TaskVersion},
+			Events: make([]model.Event, 0),
+		},
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if line == 1 {
+			text = strings.TrimPrefix(text, "\ufeff") // 记事本保存的 UTF-8 BOM
+		}
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		p.line = line
+		if err := p.statement(text); err != nil {
+			return nil, &ScriptError{Line: line, Err: err}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read script: %w", err)
+	}
+
+	if p.pending != 0 {
+		return nil, &ScriptError{Line: p.waitLine, Err: errors.New("wait is not followed by any step")}
+	}
+	if n := len(p.blocks); n > 0 {
+		block := p.blocks[n-1]
+		return nil, &ScriptError{Line: block.line, Err: fmt.Errorf("%s is not closed with end", block.event.Type)}
+	}
+
+	p.task.Meta.TotalEvents = len(p.task.Events)
+	if p.task.Meta.Version != model.TaskVersion {
+		return migrateScriptTask(p.task)
+	}
+	return p.task, nil
+}
+
+// migrateScriptTask 旧版本的脚本按其声明的版本解析，再与任务文件一样沿迁移链升级
+func migrateScriptTask(task *model.TaskData) (*model.TaskData, error) {
+	data, err := json.Marshal(task)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode script task: %w", err)
+	}
+	return decodeTask(data)
+}
+
+// scriptParser 保存解析过程中的状态
+type scriptParser struct {
+	task     *model.TaskData
+	blocks   []*scriptBlock // 尚未遇到 end 的块，最后一个为当前所在的块
+	pending  int            // 尚未分配给事件的等待时间（毫秒）
+	waitLine int            // 第一个未分配的 wait 所在行
+	line     int            // 当前行号
+}
+
+// scriptBlock 解析中的块，遇到 end 时把 steps 和 elseSteps 填入 event
+type scriptBlock struct {
+	event     model.Event
+	steps     []model.Event
+	elseSteps []model.Event
+	inElse    bool // 已遇到 else，之后的步骤属于 else 分支
+	line      int  // 块开始的行号
+}
+
+// statement 解析一行语句
+func (p *scriptParser) statement(text string) error {
+	keyword, rest := text, ""
+	if i := strings.IndexAny(text, " \t"); i >= 0 {
+		keyword, rest = text[:i], strings.TrimSpace(text[i+1:])
+	}
+
+	switch keyword {
+	case "version":
+		newer, err := versionNewer(rest, model.TaskVersion)
+		if err != nil {
+			return fmt.Errorf("%w: %q", ErrUnsupportedVersion, rest)
+		}
+		if newer {
+			return fmt.Errorf("%w: script version %s is newer than supported version %s, please upgrade DailyFlow",
+				ErrUnsupportedVersion, rest, model.TaskVersion)
+		}
+		p.task.Meta.Version = rest
+		return nil
+	case "created":
+		created, err := time.Parse(time.RFC3339, rest)
+		if err != nil {
+			return fmt.Errorf("invalid created time %q, expected RFC 3339 such as 2024-12-01T08:30:00+08:00", rest)
+		}
+		p.task.Meta.CreatedAt = created.Unix()
+		return nil
+	case "resolution":
+		p.task.Meta.Resolution = rest
+		return nil
+	case "monitor":
+		return p.monitor(rest)
+	case "wait":
+		return p.wait(rest)
+	case "repeat":
+		event, err := parseRepeat(rest)
+		if err != nil {
+			return err
+		}
+		p.begin(event)
+		return nil
+	case "if":
+		event, err := parseIf(rest)
+		if err != nil {
+			return err
+		}
+		p.begin(event)
+		return nil
+	case "else":
+		return p.elseBranch(rest)
+	case "end":
+		return p.end(rest)
+	case "raw":
+		var event model.Event
+		decoder := json.NewDecoder(strings.NewReader(rest))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&event); err != nil {
+			return fmt.Errorf("invalid raw event: %w", err)
+		}
+		p.add(event)
+		return nil
+	}
+
+	events, err := parseStep(keyword, rest)
+	if err != nil {
+		return err
+	}
+	for _, event := range events {
+		p.add(event)
+	}
+	return nil
+}
+
+// add 把事件追加到当前所在的块（不在块中时追加到任务），并把累计的等待时间分配给它
+func (p *scriptParser) add(event model.Event) {
+	event.Delay += p.pending
+	p.pending = 0
+	p.append(event)
+}
+
+// append 把事件追加到当前所在的块（或其 else 分支）或任务
+func (p *scriptParser) append(event model.Event) {
+	if n := len(p.blocks); n > 0 {
+		block := p.blocks[n-1]
+		if block.inElse {
+			block.elseSteps = append(block.elseSteps, event)
+		} else {
+			block.steps = append(block.steps, event)
+		}
+		return
+	}
+	p.task.Events = append(p.task.Events, event)
+}
+
+// begin 开始一个块，块之前的等待属于块本身
+func (p *scriptParser) begin(event model.Event) {
+	event.Delay = p.pending
+	p.pending = 0
+	p.blocks = append(p.blocks, &scriptBlock{event: event, line: p.line})
+}
+
+// elseBranch 开始当前 if 块的 else 分支
+func (p *scriptParser) elseBranch(rest string) error {
+	if rest != "" {
+		return fmt.Errorf("else takes no arguments")
+	}
+	n := len(p.blocks)
+	if n == 0 || p.blocks[n-1].event.Type != model.EventIf {
+		return errors.New("else without if")
+	}
+	block := p.blocks[n-1]
+	if block.inElse {
+		return fmt.Errorf("if on line %d already has an else", block.line)
+	}
+	if p.pending != 0 {
+		return fmt.Errorf("wait on line %d is not followed by any step before else", p.waitLine)
+	}
+	block.inElse = true
+	return nil
+}
+
+// end 结束当前所在的块
+func (p *scriptParser) end(rest string) error {
+	if rest != "" {
+		return fmt.Errorf("end takes no arguments")
+	}
+	n := len(p.blocks)
+	if n == 0 {
+		return errors.New("end without repeat or if")
+	}
+	if p.pending != 0 {
+		return fmt.Errorf("wait on line %d is not followed by any step before end", p.waitLine)
+	}
+
+	block := p.blocks[n-1]
+	p.blocks = p.blocks[:n-1]
+	block.event.Steps = block.steps
+	block.event.Else = block.elseSteps
+	p.append(block.event)
+	return nil
+}
+
+// parseRepeat 解析 repeat 块的开头：repeat 次数 [as 变量名]
+func parseRepeat(rest string) (model.Event, error) {
+	args, err := splitArgs(rest)
+	if err != nil {
+		return model.Event{}, err
+	}
+	if len(args) != 1 && !(len(args) == 3 && args[1] == "as") {
+		return model.Event{}, fmt.Errorf("repeat expects a count such as repeat 20 or repeat 20 as i")
+	}
+	count, err := strconv.Atoi(args[0])
+	if err != nil {
+		return model.Event{}, fmt.Errorf("invalid repeat count %q", args[0])
+	}
+	event := model.Event{Type: model.EventRepeat, Button: "none", Count: count}
+	if len(args) == 3 {
+		event.Var = args[2]
+	}
+	return event, nil
+}
+
+// parseIf 解析 if 块的开头：if 条件表达式，条件的语法由 Validate 检查
+func parseIf(rest string) (model.Event, error) {
+	if rest == "" {
+		return model.Event{}, fmt.Errorf("if expects a condition such as if weekday == 1")
+	}
+	return model.Event{Type: model.EventIf, Button: "none", Cond: rest}, nil
+}
+
+// wait 解析等待时长，支持 ms、s、m 等 Go 时长写法，必须是整毫秒
+func (p *scriptParser) wait(arg string) error {
+	d, err := time.ParseDuration(arg)
+	if err != nil || d%time.Millisecond != 0 {
+		return fmt.Errorf("invalid wait duration %q, expected whole milliseconds such as 300ms or 1.5s", arg)
+	}
+	if p.pending == 0 {
+		p.waitLine = p.line
+	}
+	p.pending += int(d / time.Millisecond)
+	return nil
+}
+
+// monitor 解析一个显示器：左,上,宽,高 [primary]
+func (p *scriptParser) monitor(arg string) error {
+	fields := strings.Fields(arg)
+	if len(fields) == 0 || len(fields) > 2 || (len(fields) == 2 && fields[1] != "primary") {
+		return fmt.Errorf("monitor expects left,top,width,height [primary], got %q", arg)
+	}
+	rect, err := parseRect(fields[0])
+	if err != nil {
+		return err
+	}
+	p.task.Meta.Monitors = append(p.task.Meta.Monitors, screen.Monitor{Rect: rect, Primary: len(fields) == 2})
+	return nil
+}
+
+// parseStep 解析一条动作语句，返回对应的事件（key 组合键会展开为多个事件）
+func parseStep(keyword, rest string) ([]model.Event, error) {
+	args, err := splitArgs(rest)
+	if err != nil {
+		return nil, err
+	}
+
+	switch keyword {
+	case "move":
+		event := model.Event{Type: model.EventMouseMove, Button: "none"}
+		return mouseStep(keyword, event, args, 0)
+	case "click", "down", "up":
+		if len(args) == 0 {
+			return nil, fmt.Errorf("%s expects a button and a position such as %s left 812,440", keyword, keyword)
+		}
+		types := map[string]string{"click": model.EventMouseClick, "down": model.EventMouseDown, "up": model.EventMouseUp}
+		event := model.Event{Type: types[keyword], Button: args[0]}
+		return mouseStep(keyword, event, args, 1)
+	case "wheel", "hwheel":
+		if len(args) == 0 {
+			return nil, fmt.Errorf("%s expects a delta and a position such as %s -120 812,440", keyword, keyword)
+		}
+		delta, err := strconv.Atoi(args[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid wheel delta %q", args[0])
+		}
+		event := model.Event{Type: model.EventMouseWheel, Button: "none", WheelDelta: delta, Orientation: model.WheelVertical}
+		if keyword == "hwheel" {
+			event.Orientation = model.WheelHorizontal
+		}
+		return mouseStep(keyword, event, args, 1)
+	case "keydown", "keyup", "press":
+		if len(args) != 1 {
+			return nil, fmt.Errorf("%s expects one key such as %s ctrl", keyword, keyword)
+		}
+		code, err := ParseKeyName(args[0])
+		if err != nil {
+			return nil, err
+		}
+		types := map[string]string{"keydown": model.EventKeyDown, "keyup": model.EventKeyUp, "press": model.EventKeyPress}
+		return []model.Event{{Type: types[keyword], Button: "none", KeyCode: code}}, nil
+	case "type":
+		if len(args) != 1 {
+			return nil, fmt.Errorf("type expects one quoted string such as type \"日报\"")
+		}
+		text, err := strconv.Unquote(args[0])
+		if err != nil {
+			return nil, fmt.Errorf("type expects a quoted string, got %s", args[0])
+		}
+		return []model.Event{{Type: model.EventTypeText, Button: "none", Text: text}}, nil
+	case "secret":
+		if len(args) != 1 {
+			return nil, fmt.Errorf("secret expects one quoted name such as secret \"门户#1\"")
+		}
+		name, err := strconv.Unquote(args[0])
+		if err != nil {
+			return nil, fmt.Errorf("secret expects a quoted name, got %s", args[0])
+		}
+		return []model.Event{{Type: model.EventSecret, Button: "none", Secret: name}}, nil
+	case "key":
+		if len(args) != 1 {
+			return nil, fmt.Errorf("key expects keys joined by + such as key ctrl+s")
+		}
+		return parseChord(args[0])
+	case "call":
+		event, err := parseCall(args)
+		if err != nil {
+			return nil, err
+		}
+		return []model.Event{event}, nil
+	case "waitwindow":
+		event, err := parseWaitWindow(args)
+		if err != nil {
+			return nil, err
+		}
+		return []model.Event{event}, nil
+	default:
+		return nil, fmt.Errorf("unknown step %q", keyword)
+	}
+}
+
+// parseWaitWindow 解析 waitwindow title="标题" [class="类名"] timeout=时长 [as 变量名]
+func parseWaitWindow(args []string) (model.Event, error) {
+	event := model.Event{Type: model.EventWaitWindow, Button: "none", Window: &screen.Window{}}
+	for i := 0; i < len(args); i++ {
+		if args[i] == "as" {
+			if i+1 >= len(args) {
+				return model.Event{}, fmt.Errorf("as expects a variable name such as as found")
+			}
+			event.Var = args[i+1]
+			i++
+			continue
+		}
+		key, value, _ := strings.Cut(args[i], "=")
+		switch key {
+		case "title", "class":
+			if err := applyOption(&event, args[i]); err != nil {
+				return model.Event{}, err
+			}
+		case "timeout":
+			d, err := time.ParseDuration(value)
+			if err != nil || d <= 0 || d%time.Millisecond != 0 {
+				return model.Event{}, fmt.Errorf("invalid timeout %q, expected whole milliseconds such as 10s", value)
+			}
+			event.Timeout = int(d / time.Millisecond)
+		default:
+			return model.Event{}, fmt.Errorf("unknown waitwindow option %q", args[i])
+		}
+	}
+	if event.Timeout == 0 {
+		return model.Event{}, fmt.Errorf("waitwindow expects a timeout such as waitwindow title=\"日报\" timeout=10s")
+	}
+	return event, nil
+}
+
+// parseCall 解析 call "任务名" [speed=速度因子]
+func parseCall(args []string) (model.Event, error) {
+	if len(args) != 1 && len(args) != 2 {
+		return model.Event{}, fmt.Errorf("call expects a quoted task name such as call \"登录门户\" speed=0.8")
+	}
+	name, err := strconv.Unquote(args[0])
+	if err != nil {
+		return model.Event{}, fmt.Errorf("call expects a quoted task name, got %s", args[0])
+	}
+	event := model.Event{Type: model.EventCall, Button: "none", Task: name}
+	if len(args) == 2 {
+		value, ok := strings.CutPrefix(args[1], "speed=")
+		if !ok {
+			return model.Event{}, fmt.Errorf("unknown call option %q", args[1])
+		}
+		if event.Speed, err = strconv.ParseFloat(value, 64); err != nil {
+			return model.Event{}, fmt.Errorf("invalid call speed %q", value)
+		}
+	}
+	return event, nil
+}
+
+// parseChord 解析组合键：依次按下，再倒序释放
+func parseChord(chord string) ([]model.Event, error) {
+	// "+" 本身不是按键名称（加号键写作 "="），按 + 分割即可
+	names := strings.Split(chord, "+")
+	codes := make([]int, 0, len(names))
+	for _, name := range names {
+		code, err := ParseKeyName(name)
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+	}
+
+	events := make([]model.Event, 0, 2*len(codes))
+	for _, code := range codes {
+		events = append(events, model.Event{Type: model.EventKeyDown, Button: "none", KeyCode: code})
+	}
+	for i := len(codes) - 1; i >= 0; i-- {
+		events = append(events, model.Event{Type: model.EventKeyUp, Button: "none", KeyCode: codes[i]})
+	}
+	return events, nil
+}
+
+// mouseStep 解析鼠标语句的坐标（args[pos]）和其后的窗口选项
+func mouseStep(keyword string, event model.Event, args []string, pos int) ([]model.Event, error) {
+	if len(args) <= pos {
+		return nil, fmt.Errorf("%s is missing a position such as 812,440", keyword)
+	}
+	x, y, err := parsePoint(args[pos])
+	if err != nil {
+		return nil, err
+	}
+	event.X, event.Y = x, y
+
+	for _, option := range args[pos+1:] {
+		if err := applyOption(&event, option); err != nil {
+			return nil, err
+		}
+	}
+	return []model.Event{event}, nil
+}
+
+// applyOption 解析 title= / class= / rect= / rel= 选项
+func applyOption(event *model.Event, option string) error {
+	key, value, found := strings.Cut(option, "=")
+	if !found {
+		return fmt.Errorf("unexpected argument %q", option)
+	}
+
+	window := func() *screen.Window {
+		if event.Window == nil {
+			event.Window = &screen.Window{}
+		}
+		return event.Window
+	}
+
+	switch key {
+	case "title", "class":
+		text, err := strconv.Unquote(value)
+		if err != nil {
+			return fmt.Errorf("%s expects a quoted string, got %s", key, value)
+		}
+		if key == "title" {
+			window().Title = text
+		} else {
+			window().Class = text
+		}
+	case "rect":
+		rect, err := parseRect(value)
+		if err != nil {
+			return err
+		}
+		window().Rect = rect
+	case "rel":
+		x, y, err := parsePoint(value)
+		if err != nil {
+			return err
+		}
+		event.RelX, event.RelY = x, y
+	default:
+		return fmt.Errorf("unknown option %q", key)
+	}
+	return nil
+}
+
+// parsePoint 解析 "x,y"
+func parsePoint(s string) (int, int, error) {
+	values, err := parseInts(s, 2)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid position %q, expected x,y", s)
+	}
+	return values[0], values[1], nil
+}
+
+// parseRect 解析 "左,上,宽,高"
+func parseRect(s string) (screen.Rect, error) {
+	values, err := parseInts(s, 4)
+	if err != nil {
+		return screen.Rect{}, fmt.Errorf("invalid rectangle %q, expected left,top,width,height", s)
+	}
+	return screen.Rect{Left: values[0], Top: values[1], Width: values[2], Height: values[3]}, nil
+}
+
+// parseInts 解析 n 个以逗号分隔的整数
+func parseInts(s string, n int) ([]int, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != n {
+		return nil, fmt.Errorf("expected %d numbers", n)
+	}
+	values := make([]int, n)
+	for i, part := range parts {
+		v, err := strconv.Atoi(part)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = v
+	}
+	return values, nil
+}
+
+// splitArgs 按空白分割参数，双引号内（含 \" 转义）的空白不分割
+func splitArgs(s string) ([]string, error) {
+	var args []string
+	var current strings.Builder
+	inQuote, escaped, hasToken := false, false, false
+
+	for _, r := range s {
+		switch {
+		case inQuote:
+			current.WriteRune(r)
+			if escaped {
+				escaped = false
+			} else if r == '\\' {
+				escaped = true
+			} else if r == '"' {
+				inQuote = false
+			}
+		case r == '"':
+			current.WriteRune(r)
+			inQuote, hasToken = true, true
+		case r == ' ' || r == '\t':
+			if hasToken {
+				args = append(args, current.String())
+				current.Reset()
+				hasToken = false
+			}
+		default:
+			current.WriteRune(r)
+			hasToken = true
+		}
+	}
+	if inQuote {
+		return nil, errors.New("unterminated quoted string")
+	}
+	if hasToken {
+		args = append(args, current.String())
+	}
+	return args, nil
+}
+
+// PrintScript 把任务输出为文本脚本格式，ParseScript 解析结果与原任务一致（TotalEvents 按事件数重新计算）
+func PrintScript(task *model.TaskData) []byte {
+	var b bytes.Buffer
+	b.WriteString(scriptHeader + "\n")
+
+	meta := task.Meta
+	fmt.Fprintf(&b, "version %s\n", meta.Version)
+	if meta.CreatedAt != 0 {
+		fmt.Fprintf(&b, "created %s\n", time.Unix(meta.CreatedAt, 0).Format(time.RFC3339))
+	}
+	if meta.Resolution != "" {
+		fmt.Fprintf(&b, "resolution %s\n", meta.Resolution)
+	}
+	for _, m := range meta.Monitors {
+		fmt.Fprintf(&b, "monitor %s", formatRect(m.Rect))
+		if m.Primary {
+			b.WriteString(" primary")
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString("\n")
+
+	printSteps(&b, task.Events, "")
+	return b.Bytes()
+}
+
+// printSteps 输出同一层级的步骤，块中的步骤多缩进两个空格
+func printSteps(b *bytes.Buffer, events []model.Event, indent string) {
+	for i := 0; i < len(events); {
+		if events[i].Delay != 0 {
+			fmt.Fprintf(b, "%swait %s\n", indent, time.Duration(events[i].Delay)*time.Millisecond)
+		}
+
+		if n := chordLength(events[i:]); n > 0 {
+			names := make([]string, 0, n/2)
+			for _, event := range events[i : i+n/2] {
+				names = append(names, KeyName(event.KeyCode))
+			}
+			fmt.Fprintf(b, "%skey %s\n", indent, strings.Join(names, "+"))
+			i += n
+			continue
+		}
+
+		if header, ok := formatBlockHeader(events[i]); ok {
+			b.WriteString(indent + header + "\n")
+			printSteps(b, events[i].Steps, indent+"  ")
+			if len(events[i].Else) > 0 {
+				b.WriteString(indent + "else\n")
+				printSteps(b, events[i].Else, indent+"  ")
+			}
+			b.WriteString(indent + "end\n")
+			i++
+			continue
+		}
+
+		b.WriteString(indent + formatEvent(events[i]))
+		b.WriteString("\n")
+		i++
+	}
+}
+
+// formatBlockHeader 输出块开头的语句；不是块或无法无损表示时返回 false，由 formatEvent 整体输出为 raw
+func formatBlockHeader(event model.Event) (string, bool) {
+	var header string
+	var parsed model.Event
+	var err error
+	switch event.Type {
+	case model.EventRepeat:
+		header = fmt.Sprintf("repeat %d", event.Count)
+		if event.Var != "" {
+			header += " as " + event.Var
+		}
+		parsed, err = parseRepeat(strings.TrimPrefix(header, "repeat "))
+	case model.EventIf:
+		// 条件跨行时无法写在一行中；首尾的空白解析时会被去掉，由下面的比较发现
+		if strings.ContainsAny(event.Cond, "\r\n") {
+			return "", false
+		}
+		header = "if " + event.Cond
+		parsed, err = parseIf(strings.TrimSpace(event.Cond))
+	default:
+		return "", false
+	}
+
+	event.Delay, event.Steps, event.Else = 0, nil, nil
+	return header, err == nil && reflect.DeepEqual(parsed, event)
+}
+
+// chordLength 判断 events 开头是否为可写作 "key a+b" 的组合键（中间无等待），返回其事件数，否则返回 0
+func chordLength(events []model.Event) int {
+	downs := 0
+	for downs < len(events) && events[downs].Type == model.EventKeyDown && (downs == 0 || events[downs].Delay == 0) {
+		downs++
+	}
+	if downs == 0 || 2*downs > len(events) {
+		return 0
+	}
+
+	names := make([]string, 0, downs)
+	for _, event := range events[:downs] {
+		names = append(names, KeyName(event.KeyCode))
+	}
+	parsed, err := parseChord(strings.Join(names, "+"))
+	if err != nil {
+		return 0
+	}
+
+	actual := make([]model.Event, 2*downs)
+	copy(actual, events[:2*downs])
+	actual[0].Delay = 0
+	if !reflect.DeepEqual(parsed, actual) {
+		return 0
+	}
+	return 2 * downs
+}
+
+// formatEvent 输出单个事件的语句（不含等待）；无法用普通语句无损表示时输出 raw
+func formatEvent(event model.Event) string {
+	event.Delay = 0
+
+	if line, ok := formatStep(event); ok {
+		keyword, rest, _ := strings.Cut(line, " ")
+		parsed, err := parseStep(keyword, rest)
+		if err == nil && len(parsed) == 1 && reflect.DeepEqual(parsed[0], event) {
+			return line
+		}
+	}
+
+	raw, _ := json.Marshal(event)
+	return "raw " + string(raw)
+}
+
+// formatStep 按事件类型输出普通语句，不保证无损，由 formatEvent 校验
+func formatStep(event model.Event) (string, bool) {
+	var line string
+	switch event.Type {
+	case model.EventMouseMove:
+		line = "move " + formatPoint(event.X, event.Y)
+	case model.EventMouseClick, model.EventMouseDown, model.EventMouseUp:
+		if !isPlainToken(event.Button) {
+			return "", false
+		}
+		keyword := map[string]string{model.EventMouseClick: "click", model.EventMouseDown: "down", model.EventMouseUp: "up"}[event.Type]
+		line = fmt.Sprintf("%s %s %s", keyword, event.Button, formatPoint(event.X, event.Y))
+	case model.EventMouseWheel:
+		keyword := "wheel"
+		if event.Orientation == model.WheelHorizontal {
+			keyword = "hwheel"
+		}
+		line = fmt.Sprintf("%s %d %s", keyword, event.WheelDelta, formatPoint(event.X, event.Y))
+	case model.EventKeyDown:
+		return "keydown " + KeyName(event.KeyCode), true
+	case model.EventKeyUp:
+		return "keyup " + KeyName(event.KeyCode), true
+	case model.EventKeyPress:
+		return "press " + KeyName(event.KeyCode), true
+	case model.EventTypeText:
+		return "type " + strconv.Quote(event.Text), true
+	case model.EventSecret:
+		return "secret " + strconv.Quote(event.Secret), true
+	case model.EventCall:
+		line = "call " + strconv.Quote(event.Task)
+		if event.Speed != 0 {
+			line += " speed=" + strconv.FormatFloat(event.Speed, 'g', -1, 64)
+		}
+		return line, true
+	case model.EventWaitWindow:
+		if event.Window == nil {
+			return "", false
+		}
+		line = "waitwindow title=" + strconv.Quote(event.Window.Title)
+		if event.Window.Class != "" {
+			line += " class=" + strconv.Quote(event.Window.Class)
+		}
+		line += " timeout=" + (time.Duration(event.Timeout) * time.Millisecond).String()
+		if event.Var != "" {
+			line += " as " + event.Var
+		}
+		return line, true
+	default:
+		return "", false
+	}
+
+	// 鼠标语句的窗口选项
+	if w := event.Window; w != nil {
+		line += " title=" + strconv.Quote(w.Title)
+		if w.Class != "" {
+			line += " class=" + strconv.Quote(w.Class)
+		}
+		if w.Rect != (screen.Rect{}) {
+			line += " rect=" + formatRect(w.Rect)
+		}
+	}
+	if event.RelX != 0 || event.RelY != 0 {
+		line += " rel=" + formatPoint(event.RelX, event.RelY)
+	}
+	return line, true
+}
+
+// isPlainToken 判断字符串能否不加引号直接写在脚本中
+func isPlainToken(s string) bool {
+	return s != "" && !strings.ContainsAny(s, " \t\"=")
+}
+
+func formatPoint(x, y int) string {
+	return fmt.Sprintf("%d,%d", x, y)
+}
+
+func formatRect(r screen.Rect) string {
+	return fmt.Sprintf("%d,%d,%d,%d", r.Left, r.Top, r.Width, r.Height)
+}
@@ -0,0 +1,316 @@
+package storage
+
+import (
+	"dailyflow/internal/model"
+	"dailyflow/internal/screen"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseScript(t *testing.T) {
+	script := `# 每日日报
+version 1.6
+resolution 1920x1080
+
+wait 300ms
+click left 812,440
+wait 1s
+wait 200ms
+type "日报\t2024"
+key ctrl+s
+type "{{x}}"
+`
+	task, err := ParseScript([]byte(script))
+	if err != nil {
+		t.Fatalf("ParseScript() error = %v", err)
+	}
+
+	want := &model.TaskData{
+		// 旧版本的脚本升级到当前版本，文本中的 "{{" 不会被当作模板变量
+		Meta: model.TaskMeta{Version: model.TaskVersion, Resolution: "1920x1080", TotalEvents: 7},
+		Events: []model.Event{
+			{Type: model.EventMouseClick, X: 812, Y: 440, Button: "left", Delay: 300},
+			{Type: model.EventTypeText, Button: "none", Text: "日报\t2024", Delay: 1200},
+			{Type: model.EventKeyDown, Button: "none", KeyCode: 0x11},
+			{Type: model.EventKeyDown, Button: "none", KeyCode: 'S'},
+			{Type: model.EventKeyUp, Button: "none", KeyCode: 'S'},
+			{Type: model.EventKeyUp, Button: "none", KeyCode: 0x11},
+			{Type: model.EventTypeText, Button: "none", Text: `{{"{{"}}x}}`},
+		},
+	}
+	if !reflect.DeepEqual(task, want) {
+		t.Errorf("ParseScript() = %+v, want %+v", task, want)
+	}
+}
+
+func TestParseScriptErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		line   int
+		want   string
+	}{
+		{"unknown step", "version 1.6\n\nclik left 1,2\n", 3, `unknown step "clik"`},
+		{"bad position", "click left 812\n", 1, `invalid position "812"`},
+		{"unknown key", "# c\nkey ctrl+foo\n", 2, `unknown key "foo"`},
+		{"unquoted text", "type 日报\n", 1, "quoted string"},
+		{"unterminated quote", `type "日报` + "\n", 1, "unterminated"},
+		{"bad wait", "wait soon\nmove 1,1\n", 1, "invalid wait duration"},
+		{"trailing wait", "move 1,1\n\nwait 1s\n# end\nwait 2s\n", 3, "not followed by any step"},
+		{"unknown option", "click left 1,1 color=red\n", 1, `unknown option "color"`},
+		{"bad raw", "raw {\"type\":\"mouse_move\",\"volume\":3}\n", 1, "invalid raw event"},
+		{"newer version", "version 9.0\n", 1, "newer than supported"},
+		{"unclosed repeat", "repeat 3\nmove 1,1\nrepeat 2\nend\n", 1, "repeat is not closed with end"},
+		{"end without repeat", "move 1,1\nend\n", 2, "end without repeat or if"},
+		{"wait before end", "repeat 3\nmove 1,1\nwait 1s\nend\n", 4, "wait on line 3"},
+		{"bad repeat count", "repeat many\nend\n", 1, `invalid repeat count "many"`},
+		{"unquoted call", "call 登录\n", 1, "quoted task name"},
+		{"bad call speed", "call \"登录\" speed=fast\n", 1, `invalid call speed "fast"`},
+		{"unknown call option", "call \"登录\" slow\n", 1, `unknown call option "slow"`},
+		{"if without condition", "if\nend\n", 1, "if expects a condition"},
+		{"else without if", "repeat 2\nmove 1,1\nelse\nend\n", 3, "else without if"},
+		{"second else", "if found\nelse\nelse\nend\n", 3, "if on line 1 already has an else"},
+		{"wait before else", "if found\nmove 1,1\nwait 1s\nelse\nend\n", 4, "wait on line 3"},
+		{"unclosed if", "if found\nmove 1,1\n", 1, "if is not closed with end"},
+		{"waitwindow without timeout", "waitwindow title=\"日报\"\n", 1, "waitwindow expects a timeout"},
+		{"bad waitwindow timeout", "waitwindow title=\"日报\" timeout=soon\n", 1, `invalid timeout "soon"`},
+		{"waitwindow missing var", "waitwindow title=\"日报\" timeout=1s as\n", 1, "as expects a variable name"},
+		{"unknown waitwindow option", "waitwindow title=\"日报\" rel=1,1 timeout=1s\n", 1, `unknown waitwindow option "rel=1,1"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseScript([]byte(tt.script))
+			var scriptErr *ScriptError
+			if !errors.As(err, &scriptErr) {
+				t.Fatalf("ParseScript() error = %v, want *ScriptError", err)
+			}
+			if scriptErr.Line != tt.line || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("ParseScript() error = %q, want line %d containing %q", err, tt.line, tt.want)
+			}
+		})
+	}
+
+	_, err := ParseScript([]byte("version 9.0\n"))
+	if !errors.Is(err, ErrUnsupportedVersion) {
+		t.Errorf("newer version error %v is not ErrUnsupportedVersion", err)
+	}
+}
+
+func TestScriptBlocks(t *testing.T) {
+	script := scriptHeader + `
+version ` + model.TaskVersion + `
+
+wait 1s
+repeat 20 as 行
+  click left 812,440
+  wait 300ms
+  type "第{{行}}行"
+  repeat 2
+    key tab
+  end
+end
+key enter
+call "登录门户"
+call "导出" speed=0.5
+waitwindow title="日报" class="Notepad" timeout=10s as found
+if found && (weekday == 1 || is_month_end)
+  type "周报"
+else
+  if [客户] == "华东"
+    press enter
+  end
+end
+if !found
+else
+  waitwindow title="保存" timeout=1.5s
+end
+`
+	task, err := ParseScript([]byte(script))
+	if err != nil {
+		t.Fatalf("ParseScript() error = %v", err)
+	}
+
+	want := []model.Event{
+		{Type: model.EventRepeat, Button: "none", Count: 20, Var: "行", Delay: 1000, Steps: []model.Event{
+			{Type: model.EventMouseClick, X: 812, Y: 440, Button: "left"},
+			{Type: model.EventTypeText, Button: "none", Text: "第{{行}}行", Delay: 300},
+			{Type: model.EventRepeat, Button: "none", Count: 2, Steps: []model.Event{
+				{Type: model.EventKeyDown, Button: "none", KeyCode: 0x09},
+				{Type: model.EventKeyUp, Button: "none", KeyCode: 0x09},
+			}},
+		}},
+		{Type: model.EventKeyDown, Button: "none", KeyCode: 0x0D},
+		{Type: model.EventKeyUp, Button: "none", KeyCode: 0x0D},
+		{Type: model.EventCall, Button: "none", Task: "登录门户"},
+		{Type: model.EventCall, Button: "none", Task: "导出", Speed: 0.5},
+		{Type: model.EventWaitWindow, Button: "none", Window: &screen.Window{Title: "日报", Class: "Notepad"}, Timeout: 10000, Var: "found"},
+		{Type: model.EventIf, Button: "none", Cond: "found && (weekday == 1 || is_month_end)", Steps: []model.Event{
+			{Type: model.EventTypeText, Button: "none", Text: "周报"},
+		}, Else: []model.Event{
+			{Type: model.EventIf, Button: "none", Cond: `[客户] == "华东"`, Steps: []model.Event{
+				{Type: model.EventKeyPress, Button: "none", KeyCode: 0x0D},
+			}},
+		}},
+		{Type: model.EventIf, Button: "none", Cond: "!found", Else: []model.Event{
+			{Type: model.EventWaitWindow, Button: "none", Window: &screen.Window{Title: "保存"}, Timeout: 1500},
+		}},
+	}
+	if !reflect.DeepEqual(task.Events, want) {
+		t.Errorf("ParseScript() events = %+v, want %+v", task.Events, want)
+	}
+	if got := string(PrintScript(task)); got != script {
+		t.Errorf("PrintScript() =\n%s\nwant\n%s", got, script)
+	}
+}
+
+func TestPrintScript(t *testing.T) {
+	task := &model.TaskData{
+		Meta: model.TaskMeta{
+			Version:    "1.6",
+			Resolution: "1920x1080",
+			Monitors: []screen.Monitor{
+				{Rect: screen.Rect{Left: 0, Top: 0, Width: 1920, Height: 1080}, Primary: true},
+				{Rect: screen.Rect{Left: -1280, Top: 0, Width: 1280, Height: 1024}},
+			},
+		},
+		Events: []model.Event{
+			{Type: model.EventMouseMove, X: -600, Y: 300, Button: "none", Delay: 50},
+			{Type: model.EventMouseClick, X: 812, Y: 440, Button: "left", Delay: 1500,
+				Window: &screen.Window{Title: "无标题 - 记事本", Class: "Notepad", Rect: screen.Rect{Left: 100, Top: 50, Width: 800, Height: 600}},
+				RelX:   712, RelY: 390},
+			{Type: model.EventKeyDown, Button: "none", KeyCode: 0xA2, Delay: 90000},
+			{Type: model.EventKeyDown, Button: "none", KeyCode: 'S', Delay: 80},
+			{Type: model.EventKeyUp, Button: "none", KeyCode: 'S', Delay: 60},
+			{Type: model.EventKeyUp, Button: "none", KeyCode: 0xA2, Delay: 40},
+			{Type: model.EventKeyDown, Button: "none", KeyCode: 0x11},
+			{Type: model.EventKeyDown, Button: "none", KeyCode: 0x56},
+			{Type: model.EventKeyUp, Button: "none", KeyCode: 0x56},
+			{Type: model.EventKeyUp, Button: "none", KeyCode: 0x11},
+			{Type: model.EventMouseWheel, X: 812, Y: 440, Button: "none", WheelDelta: -240, Orientation: model.WheelVertical, Delay: 10},
+			{Type: model.EventTypeText, Button: "none", Text: "日报 \"完成\"\n"},
+			{Type: "mouse_teleport", X: 1, Y: 2, Delay: 5},
+		},
+	}
+
+	want := scriptHeader + `
+version 1.6
+resolution 1920x1080
+monitor 0,0,1920,1080 primary
+monitor -1280,0,1280,1024
+
+wait 50ms
+move -600,300
+wait 1.5s
+click left 812,440 title="无标题 - 记事本" class="Notepad" rect=100,50,800,600 rel=712,390
+wait 1m30s
+keydown lctrl
+wait 80ms
+keydown s
+wait 60ms
+keyup s
+wait 40ms
+keyup lctrl
+key ctrl+v
+wait 10ms
+wheel -240 812,440
+type "日报 \"完成\"\n"
+wait 5ms
+raw {"type":"mouse_teleport","x":1,"y":2,"button":"","key_code":0,"delay":0}
+`
+	if got := string(PrintScript(task)); got != want {
+		t.Errorf("PrintScript() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestScriptRoundTrip(t *testing.T) {
+	tasks := map[string]*model.TaskData{
+		"unusual values": {
+			Meta: model.TaskMeta{Version: model.TaskVersion, CreatedAt: 1701390600},
+			Events: []model.Event{
+				{Type: model.EventMouseClick, X: 5, Y: 5, Button: "", Delay: -20},
+				{Type: model.EventMouseMove, X: 5, Y: 5, Button: "left"},
+				{Type: model.EventMouseWheel, X: 1, Y: 1, Button: "none", WheelDelta: 120},
+				{Type: model.EventKeyDown, Button: "none", KeyCode: 300},
+				{Type: model.EventKeyUp, Button: "none", KeyCode: -1},
+				{Type: model.EventKeyPress, Button: "none", KeyCode: 0x0D},
+				{Type: model.EventMouseDown, X: 1, Y: 1, Button: "right", Window: &screen.Window{}},
+				{Type: model.EventMouseUp, X: 9, Y: 9, Button: "right", RelX: -3},
+				{Type: model.EventMouseClick, X: 1, Y: 1, Button: "x 1"},
+				{Type: model.EventKeyDown, Button: "none", KeyCode: 0x11, Delay: 30},
+				{Type: model.EventKeyUp, Button: "none", KeyCode: 0x11, Delay: 1},
+				{Type: model.EventTypeText, Button: "none", Text: ""},
+				{Type: model.EventTypeText, Button: "none", Text: "{{date:YYYYMMDD}} {{\"{{\"}}"},
+				{Type: model.EventSecret, Button: "none", Secret: "门户 \"#1\""},
+				{Type: model.EventSecret, Button: "left", Secret: "门户#2"},
+				{Type: model.EventRepeat, Button: "none", Count: 3, Var: "i", Delay: 40, Steps: []model.Event{
+					{Type: model.EventTypeText, Button: "none", Text: "{{i}}", Delay: 10},
+					{Type: model.EventRepeat, Button: "none", Count: 0, Steps: []model.Event{
+						{Type: model.EventKeyDown, Button: "none", KeyCode: 0x11},
+						{Type: model.EventKeyUp, Button: "none", KeyCode: 0x11},
+					}},
+					{Type: model.EventRepeat, Button: "none", Count: 2},
+				}},
+				{Type: model.EventRepeat, Button: "none", Count: 2, Var: "行 号"},
+				{Type: model.EventRepeat, Count: 2, Steps: []model.Event{{Type: model.EventTypeText, Button: "none", Text: "a"}}},
+				{Type: model.EventCall, Button: "none", Task: "登录 \"门户\"", Speed: 0.75},
+				{Type: model.EventCall, Button: "none", Task: "", Speed: -1},
+				{Type: model.EventCall, Button: "left", Task: "导出"},
+				{Type: model.EventIf, Button: "none", Cond: " found", Steps: []model.Event{{Type: model.EventTypeText, Button: "none", Text: "a"}}},
+				{Type: model.EventIf, Button: "none", Cond: "a ==\n1", Else: []model.Event{{Type: model.EventTypeText, Button: "none", Text: "b"}}},
+				{Type: model.EventIf, Button: "none", Cond: "", Delay: 5},
+				{Type: model.EventWaitWindow, Button: "none", Window: &screen.Window{Class: "Notepad", Rect: screen.Rect{Width: 10}}, Timeout: 100},
+				{Type: model.EventWaitWindow, Button: "none", Timeout: 100, Var: "x"},
+				{Type: model.EventWaitWindow, Button: "none", Window: &screen.Window{Title: "a"}, Timeout: 0},
+				{Type: model.EventWaitWindow, Button: "none", Window: &screen.Window{Title: "a"}, Timeout: 1},
+			},
+		},
+	}
+
+	// 测试数据中的 JSON 任务
+	fixtures, err := filepath.Glob(filepath.Join("testdata", "task_v1.*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, fixture := range fixtures {
+		data, err := os.ReadFile(fixture)
+		if err != nil {
+			t.Fatal(err)
+		}
+		task, err := decodeTask(data)
+		if err != nil {
+			t.Fatalf("%s: %v", fixture, err)
+		}
+		tasks[fixture] = task
+	}
+
+	for name, task := range tasks {
+		t.Run(name, func(t *testing.T) {
+			task.Meta.TotalEvents = len(task.Events)
+			script := PrintScript(task)
+			got, err := ParseScript(script)
+			if err != nil {
+				t.Fatalf("ParseScript(PrintScript()) error = %v\n%s", err, script)
+			}
+			if !reflect.DeepEqual(got, task) {
+				t.Errorf("round trip mismatch:\n got %+v\nwant %+v\nscript:\n%s", got, task, script)
+			}
+		})
+	}
+}
+
+func TestKeyNames(t *testing.T) {
+	for code := 0; code <= 0xFF; code++ {
+		got, err := ParseKeyName(KeyName(code))
+		if err != nil || got != code {
+			t.Errorf("ParseKeyName(KeyName(0x%02X)) = 0x%02X, %v", code, got, err)
+		}
+	}
+	if code, err := ParseKeyName("Ctrl"); err != nil || code != 0x11 {
+		t.Errorf("ParseKeyName(Ctrl) = 0x%02X, %v, want 0x11", code, err)
+	}
+}
@@ -0,0 +1,143 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+)
+
+// ErrSecretNotFound 凭据存储中没有指定名称的敏感输入
+var ErrSecretNotFound = errors.New("secret not found")
+
+// SecretStore 敏感输入（密码等）的存储
+// 录制时任务中只保存 secret 事件的名称，回放时按名称从这里取出内容输入
+type SecretStore interface {
+	// Secret 返回名称对应的内容，不存在时返回 ErrSecretNotFound
+	Secret(name string) (string, error)
+	// SetSecret 保存名称对应的内容，已存在时覆盖
+	SetSecret(name, value string) error
+	// DeleteSecret 删除名称对应的内容，不存在时不报错
+	DeleteSecret(name string) error
+}
+
+// MissingSecrets 返回 names 中在 secrets 里找不到的名称
+func MissingSecrets(secrets SecretStore, names []string) ([]string, error) {
+	var missing []string
+	for _, name := range names {
+		if _, err := secrets.Secret(name); errors.Is(err, ErrSecretNotFound) {
+			missing = append(missing, name)
+		} else if err != nil {
+			return nil, err
+		}
+	}
+	return missing, nil
+}
+
+// FileSecretStore 把敏感输入以明文 JSON 保存在单个文件中的 SecretStore
+// 用于测试和没有系统凭据管理器的环境；Windows 下应使用 NewCredentialStore
+type FileSecretStore struct {
+	mutex sync.Mutex
+	path  string
+}
+
+// NewFileSecretStore 创建保存在 path 的凭据存储，文件在第一次写入时创建
+func NewFileSecretStore(path string) *FileSecretStore {
+	return &FileSecretStore{path: path}
+}
+
+// Secret 实现 SecretStore
+func (s *FileSecretStore) Secret(name string) (string, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	secrets, err := s.load()
+	if err != nil {
+		return "", err
+	}
+	value, ok := secrets[name]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrSecretNotFound, name)
+	}
+	return value, nil
+}
+
+// SetSecret 实现 SecretStore
+func (s *FileSecretStore) SetSecret(name, value string) error {
+	if name == "" {
+		return fmt.Errorf("secret name cannot be empty")
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	secrets, err := s.load()
+	if err != nil {
+		return err
+	}
+	secrets[name] = value
+	return s.save(secrets)
+}
+
+// DeleteSecret 实现 SecretStore
+func (s *FileSecretStore) DeleteSecret(name string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	secrets, err := s.load()
+	if err != nil {
+		return err
+	}
+	if _, ok := secrets[name]; !ok {
+		return nil
+	}
+	delete(secrets, name)
+	return s.save(secrets)
+}
+
+// Names 返回已保存的全部名称（按名称排序）
+func (s *FileSecretStore) Names() ([]string, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	secrets, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(secrets))
+	for name := range secrets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// load 读取文件，文件不存在时返回空集合，调用方持有 mutex
+func (s *FileSecretStore) load() (map[string]string, error) {
+	secrets := make(map[string]string)
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return secrets, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read secrets file: %w", err)
+	}
+	if err := json.Unmarshal(data, &secrets); err != nil {
+		return nil, fmt.Errorf("%w: failed to parse secrets file: %w", ErrCorrupt, err)
+	}
+	return secrets, nil
+}
+
+// save 原子地写入文件（仅当前用户可读写），调用方持有 mutex
+func (s *FileSecretStore) save(secrets map[string]string) error {
+	data, err := json.MarshalIndent(secrets, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal secrets: %w", err)
+	}
+	if err := writeFileAtomic(s.path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write secrets file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package storage
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestFileSecretStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secrets.json")
+	secrets := NewFileSecretStore(path)
+
+	if _, err := secrets.Secret("门户#1"); !errors.Is(err, ErrSecretNotFound) {
+		t.Errorf("Secret() on empty store error = %v, want ErrSecretNotFound", err)
+	}
+	if err := secrets.SetSecret("门户#1", "P@ss w0rd"); err != nil {
+		t.Fatal(err)
+	}
+	if err := secrets.SetSecret("门户#2", ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := secrets.SetSecret("", "x"); err == nil {
+		t.Error("SetSecret() accepted an empty name")
+	}
+
+	// 重新打开文件，内容仍在
+	reopened := NewFileSecretStore(path)
+	if value, err := reopened.Secret("门户#1"); err != nil || value != "P@ss w0rd" {
+		t.Errorf("Secret() = %q, %v, want the saved value", value, err)
+	}
+	missing, err := MissingSecrets(reopened, []string{"门户#1", "门户#2", "门户#3"})
+	if err != nil || !reflect.DeepEqual(missing, []string{"门户#3"}) {
+		t.Errorf("MissingSecrets() = %v, %v, want [门户#3]", missing, err)
+	}
+
+	if err := reopened.DeleteSecret("门户#1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := reopened.DeleteSecret("门户#1"); err != nil {
+		t.Errorf("deleting a missing secret error = %v", err)
+	}
+	if names, err := secrets.Names(); err != nil || !reflect.DeepEqual(names, []string{"门户#2"}) {
+		t.Errorf("Names() = %v, %v, want [门户#2]", names, err)
+	}
+
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("secrets file mode = %v, %v, want 0600", info.Mode(), err)
+	}
+}
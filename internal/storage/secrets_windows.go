@@ -0,0 +1,121 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+const (
+	// credentialPrefix 凭据管理器中 DailyFlow 凭据的目标名前缀
+	credentialPrefix = "DailyFlow/"
+
+	credTypeGeneric         = 1 // CRED_TYPE_GENERIC
+	credPersistLocalMachine = 2 // CRED_PERSIST_LOCAL_MACHINE：仅本机、当前用户，登录会话结束后仍保留
+)
+
+var (
+	advapi32        = windows.NewLazySystemDLL("advapi32.dll")
+	procCredReadW   = advapi32.NewProc("CredReadW")
+	procCredWriteW  = advapi32.NewProc("CredWriteW")
+	procCredDeleteW = advapi32.NewProc("CredDeleteW")
+	procCredFree    = advapi32.NewProc("CredFree")
+)
+
+// credential Windows CREDENTIALW 结构
+type credential struct {
+	Flags              uint32
+	Type               uint32
+	TargetName         *uint16
+	Comment            *uint16
+	LastWritten        windows.Filetime
+	CredentialBlobSize uint32
+	CredentialBlob     *byte
+	Persist            uint32
+	AttributeCount     uint32
+	Attributes         uintptr
+	TargetAlias        *uint16
+	UserName           *uint16
+}
+
+// CredentialStore 保存在 Windows 凭据管理器中的 SecretStore
+// 内容由系统按当前用户加密，可在"控制面板 > 凭据管理器 > Windows 凭据"中查看和删除
+type CredentialStore struct{}
+
+// NewCredentialStore 创建使用 Windows 凭据管理器的凭据存储
+func NewCredentialStore() *CredentialStore {
+	return &CredentialStore{}
+}
+
+// Secret 实现 SecretStore
+func (CredentialStore) Secret(name string) (string, error) {
+	target, err := windows.UTF16PtrFromString(credentialPrefix + name)
+	if err != nil {
+		return "", err
+	}
+
+	var cred *credential
+	ret, _, callErr := procCredReadW.Call(
+		uintptr(unsafe.Pointer(target)),
+		credTypeGeneric,
+		0,
+		uintptr(unsafe.Pointer(&cred)),
+	)
+	if ret == 0 {
+		if errors.Is(callErr, windows.ERROR_NOT_FOUND) {
+			return "", fmt.Errorf("%w: %s", ErrSecretNotFound, name)
+		}
+		return "", fmt.Errorf("CredRead failed: %v", callErr)
+	}
+	defer procCredFree.Call(uintptr(unsafe.Pointer(cred)))
+
+	if cred.CredentialBlobSize == 0 {
+		return "", nil
+	}
+	return string(unsafe.Slice(cred.CredentialBlob, cred.CredentialBlobSize)), nil
+}
+
+// SetSecret 实现 SecretStore
+func (CredentialStore) SetSecret(name, value string) error {
+	if name == "" {
+		return fmt.Errorf("secret name cannot be empty")
+	}
+	target, err := windows.UTF16PtrFromString(credentialPrefix + name)
+	if err != nil {
+		return err
+	}
+	userName, _ := windows.UTF16PtrFromString("DailyFlow")
+
+	blob := []byte(value)
+	cred := credential{
+		Type:               credTypeGeneric,
+		TargetName:         target,
+		CredentialBlobSize: uint32(len(blob)),
+		Persist:            credPersistLocalMachine,
+		UserName:           userName,
+	}
+	if len(blob) > 0 {
+		cred.CredentialBlob = &blob[0]
+	}
+
+	ret, _, callErr := procCredWriteW.Call(uintptr(unsafe.Pointer(&cred)), 0)
+	if ret == 0 {
+		return fmt.Errorf("CredWrite failed: %v", callErr)
+	}
+	return nil
+}
+
+// DeleteSecret 实现 SecretStore
+func (CredentialStore) DeleteSecret(name string) error {
+	target, err := windows.UTF16PtrFromString(credentialPrefix + name)
+	if err != nil {
+		return err
+	}
+	ret, _, callErr := procCredDeleteW.Call(uintptr(unsafe.Pointer(target)), credTypeGeneric, 0)
+	if ret == 0 && !errors.Is(callErr, windows.ERROR_NOT_FOUND) {
+		return fmt.Errorf("CredDelete failed: %v", callErr)
+	}
+	return nil
+}
@@ -0,0 +1,163 @@
+package storage
+
+import (
+	"dailyflow/internal/model"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Store 任务库和配置的存储后端
+// 录制器、回放器、调度器和界面通过它读写数据，测试中可以换成 MemoryStore
+type Store interface {
+	LoadTask(name string) (*model.TaskData, error)
+	SaveTask(name string, taskData *model.TaskData) error
+	ListTasks() ([]string, error)
+	LoadConfig() (*model.Config, error)
+	SaveConfig(config *model.Config) error
+}
+
+// TaskLoader 返回从 store 加载任务的 model.TaskLoader，用于检查 call 步骤引用的任务
+// 任务不存在或名称无效时返回 nil, nil，由调用方报告为缺失的任务
+func TaskLoader(store Store) model.TaskLoader {
+	return func(name string) (*model.TaskData, error) {
+		taskData, err := store.LoadTask(name)
+		if errors.Is(err, ErrTaskNotFound) || errors.Is(err, ErrInvalidTaskName) {
+			return nil, nil
+		}
+		return taskData, err
+	}
+}
+
+// FileStore 保存在数据目录中的存储后端（任务库 tasks/ 和 config.json）
+type FileStore struct{}
+
+// NewFileStore 创建基于数据目录的存储后端
+func NewFileStore() *FileStore {
+	return &FileStore{}
+}
+
+// LoadTask 实现 Store
+func (FileStore) LoadTask(name string) (*model.TaskData, error) {
+	return LoadTask(name)
+}
+
+// SaveTask 实现 Store
+func (FileStore) SaveTask(name string, taskData *model.TaskData) error {
+	return SaveTask(name, taskData)
+}
+
+// ListTasks 实现 Store
+func (FileStore) ListTasks() ([]string, error) {
+	return ListTasks()
+}
+
+// LoadConfig 实现 Store
+func (FileStore) LoadConfig() (*model.Config, error) {
+	return LoadConfig()
+}
+
+// SaveConfig 实现 Store
+func (FileStore) SaveConfig(config *model.Config) error {
+	return SaveConfig(config)
+}
+
+// MemoryStore 保存在内存中的存储后端，用于测试
+// 读写时都复制数据，调用方修改返回的对象不会影响已保存的内容（与文件存储一致）
+type MemoryStore struct {
+	mutex  sync.Mutex
+	tasks  map[string]*model.TaskData
+	config *model.Config
+}
+
+// NewMemoryStore 创建空的内存存储后端
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{tasks: make(map[string]*model.TaskData)}
+}
+
+// LoadTask 实现 Store
+func (s *MemoryStore) LoadTask(name string) (*model.TaskData, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	taskData, ok := s.tasks[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrTaskNotFound, name)
+	}
+	clone := new(model.TaskData)
+	if err := deepCopy(clone, taskData); err != nil {
+		return nil, err
+	}
+	return clone, nil
+}
+
+// SaveTask 实现 Store
+func (s *MemoryStore) SaveTask(name string, taskData *model.TaskData) error {
+	if err := ValidateTaskName(name); err != nil {
+		return err
+	}
+	clone := new(model.TaskData)
+	if err := deepCopy(clone, taskData); err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.tasks[name] = clone
+	return nil
+}
+
+// ListTasks 实现 Store
+func (s *MemoryStore) ListTasks() ([]string, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	names := make([]string, 0, len(s.tasks))
+	for name := range s.tasks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// LoadConfig 实现 Store，尚未保存过配置时返回默认配置
+func (s *MemoryStore) LoadConfig() (*model.Config, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.config == nil {
+		return model.NewConfig(), nil
+	}
+	clone := model.NewConfig()
+	if err := deepCopy(clone, s.config); err != nil {
+		return nil, err
+	}
+	return clone, nil
+}
+
+// SaveConfig 实现 Store
+func (s *MemoryStore) SaveConfig(config *model.Config) error {
+	clone := model.NewConfig()
+	if err := deepCopy(clone, config); err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.config = clone
+	return nil
+}
+
+// deepCopy 通过 JSON 编解码复制 src 到 dst，与写入文件再读回的结果一致
+func deepCopy(dst, src interface{}) error {
+	data, err := json.Marshal(src)
+	if err != nil {
+		return fmt.Errorf("failed to copy data: %w", err)
+	}
+	if err := json.Unmarshal(data, dst); err != nil {
+		return fmt.Errorf("failed to copy data: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package storage
+
+import (
+	"dailyflow/internal/model"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestMemoryStore(t *testing.T) {
+	store := NewMemoryStore()
+
+	task := model.NewTaskData("1920x1080")
+	task.AddEvent(model.Event{Type: model.EventMouseClick, X: 1, Y: 2, Button: "left"})
+	if err := store.SaveTask("日报", task); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SaveTask("a/b", task); !errors.Is(err, ErrInvalidTaskName) {
+		t.Errorf("SaveTask(a/b) error = %v, want ErrInvalidTaskName", err)
+	}
+
+	// 保存后修改原对象不影响已保存的内容
+	task.Events[0].X = 100
+	loaded, err := store.LoadTask("日报")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Events[0].X != 1 {
+		t.Errorf("loaded X = %d, want 1", loaded.Events[0].X)
+	}
+	if _, err := store.LoadTask("周报"); !errors.Is(err, ErrTaskNotFound) {
+		t.Errorf("LoadTask(周报) error = %v, want ErrTaskNotFound", err)
+	}
+
+	config, err := store.LoadConfig()
+	if err != nil || !reflect.DeepEqual(config, model.NewConfig()) {
+		t.Fatalf("LoadConfig() = %+v, %v, want default config", config, err)
+	}
+	config.Schedule("日报").IsEnabled = true
+	if err := store.SaveConfig(config); err != nil {
+		t.Fatal(err)
+	}
+	config.Tasks["日报"].IsEnabled = false
+	if saved, _ := store.LoadConfig(); !saved.Tasks["日报"].IsEnabled {
+		t.Error("saved config changed together with the caller's copy")
+	}
+
+	names, err := store.ListTasks()
+	if err != nil || !reflect.DeepEqual(names, []string{"日报"}) {
+		t.Errorf("ListTasks() = %v, %v", names, err)
+	}
+}
@@ -0,0 +1,64 @@
+package ui
+
+import (
+	"strings"
+
+	"github.com/lxn/walk"
+	"github.com/lxn/walk/declarative"
+)
+
+// promptText 弹出单行文本输入对话框，返回输入内容和是否确认
+func promptText(owner walk.Form, title, label, value string) (string, bool) {
+	text, ok := runPrompt(owner, title, label, value, false)
+	return strings.TrimSpace(text), ok
+}
+
+// promptPassword 弹出不回显的口令输入对话框，返回输入内容（不去除空格）和是否确认
+func promptPassword(owner walk.Form, title, label string) (string, bool) {
+	return runPrompt(owner, title, label, "", true)
+}
+
+// runPrompt 弹出单行输入对话框，password 时输入内容显示为掩码
+func runPrompt(owner walk.Form, title, label, value string, password bool) (string, bool) {
+	var dlg *walk.Dialog
+	var edit *walk.LineEdit
+	var acceptBtn, cancelBtn *walk.PushButton
+	var text string
+
+	result, err := declarative.Dialog{
+		AssignTo:      &dlg,
+		Title:         title,
+		DefaultButton: &acceptBtn,
+		CancelButton:  &cancelBtn,
+		MinSize:       declarative.Size{Width: 280, Height: 120},
+		Layout:        declarative.VBox{},
+		Children: []declarative.Widget{
+			declarative.Label{Text: label},
+			declarative.LineEdit{AssignTo: &edit, Text: value, PasswordMode: password},
+			declarative.Composite{
+				Layout: declarative.HBox{MarginsZero: true},
+				Children: []declarative.Widget{
+					declarative.HSpacer{},
+					declarative.PushButton{
+						AssignTo: &acceptBtn,
+						Text:     "确定",
+						OnClicked: func() {
+							text = edit.Text()
+							dlg.Accept()
+						},
+					},
+					declarative.PushButton{
+						AssignTo:  &cancelBtn,
+						Text:      "取消",
+						OnClicked: func() { dlg.Cancel() },
+					},
+				},
+			},
+		},
+	}.Run(owner)
+
+	if err != nil || result != walk.DlgCmdOK {
+		return "", false
+	}
+	return text, true
+}
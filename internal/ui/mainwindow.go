@@ -4,7 +4,12 @@ import (
 	"dailyflow/internal/core"
 	"dailyflow/internal/model"
 	"dailyflow/internal/storage"
+	"errors"
 	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/lxn/walk"
@@ -14,37 +19,55 @@ import (
 // AppMainWindow 主窗口
 type AppMainWindow struct {
 	*walk.MainWindow
+	store     storage.Store
+	secrets   storage.SecretStore
 	recorder  *core.Recorder
 	player    *core.Player
 	scheduler *core.Scheduler
 	trayIcon  *walk.NotifyIcon
 	config    *model.Config
+	prompting bool // 正在请用户输入口令或敏感输入，定时任务再次失败时不重复弹出
 
 	// UI 控件
 	statusLabel       *walk.Label
+	taskCombo         *walk.ComboBox
 	recordBtn         *walk.PushButton
 	playBtn           *walk.PushButton
 	scheduleTimeEdit  *walk.LineEdit
 	enableCheckBox    *walk.CheckBox
 	speedSlider       *walk.Slider
 	speedLabel        *walk.Label
+	dataFileLabel     *walk.Label
 	autoStartCheckBox *walk.CheckBox
+	scaleCheckBox     *walk.CheckBox
 }
 
 // NewMainWindow 创建新的主窗口
 func NewMainWindow() (*AppMainWindow, error) {
+	store := storage.NewFileStore()
+	secrets := storage.NewCredentialStore()
+	desktop := core.NewDesktop()
 	mw := &AppMainWindow{
-		recorder: core.NewRecorder(),
-		player:   core.NewPlayer(),
+		store:    store,
+		secrets:  secrets,
+		recorder: core.NewRecorder(store, desktop, core.NewInputSource()),
+		player:   core.NewPlayer(store, secrets, desktop),
 	}
-	mw.scheduler = core.NewScheduler(mw.player)
+	mw.scheduler = core.NewScheduler(mw.player, store)
 
-	// 加载配置
-	config, err := storage.LoadConfig()
+	// 加载配置，配置文件损坏时提示从备份恢复
+	config, err := store.LoadConfig()
+	if errors.Is(err, storage.ErrCorrupt) && restoreConfigBackup(err) {
+		config, err = store.LoadConfig()
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to load config: %w", err)
 	}
 	mw.config = config
+	mw.player.SetResolutionPolicy(config.ResolutionPolicy)
+	mw.player.SetVariables(config.Variables)
+	mw.recorder.SetOptimizeOptions(config.OptimizeOptions())
+	mw.recorder.SetSensitiveWindows(config.SensitiveWindows)
 
 	return mw, nil
 }
@@ -52,17 +75,20 @@ func NewMainWindow() (*AppMainWindow, error) {
 // Create 创建并显示窗口
 func (mw *AppMainWindow) Create() error {
 	var statusLabel *walk.Label
+	var taskCombo *walk.ComboBox
 	var recordBtn, playBtn *walk.PushButton
 	var scheduleTimeEdit *walk.LineEdit
-	var enableCheckBox, autoStartCheckBox *walk.CheckBox
+	var enableCheckBox, autoStartCheckBox, scaleCheckBox *walk.CheckBox
 	var speedSlider *walk.Slider
-	var speedLabel *walk.Label
+	var speedLabel, dataFileLabel *walk.Label
+
+	schedule := mw.scheduleOrDefault()
 
 	// 使用声明式方式创建 UI
 	err := (declarative.MainWindow{
 		AssignTo: &mw.MainWindow,
 		Title:    "DailyFlow",
-		Size:     declarative.Size{Width: 320, Height: 480},
+		Size:     declarative.Size{Width: 320, Height: 620},
 		Layout:   declarative.VBox{},
 		Children: []declarative.Widget{
 			// 警告横幅
@@ -90,6 +116,36 @@ func (mw *AppMainWindow) Create() error {
 				},
 			},
 
+			// 任务库
+			declarative.GroupBox{
+				Title:  "任务",
+				Layout: declarative.VBox{Margins: declarative.Margins{Left: 10, Top: 10, Right: 10, Bottom: 10}},
+				Children: []declarative.Widget{
+					declarative.ComboBox{
+						AssignTo:              &taskCombo,
+						OnCurrentIndexChanged: func() { mw.onTaskSelected() },
+					},
+					declarative.Composite{
+						Layout: declarative.HBox{MarginsZero: true},
+						Children: []declarative.Widget{
+							declarative.PushButton{Text: "新建", OnClicked: func() { mw.onNewTaskClick() }},
+							declarative.PushButton{Text: "重命名", OnClicked: func() { mw.onRenameTaskClick() }},
+							declarative.PushButton{Text: "复制", OnClicked: func() { mw.onDuplicateTaskClick() }},
+							declarative.PushButton{Text: "删除", OnClicked: func() { mw.onDeleteTaskClick() }},
+						},
+					},
+					declarative.Composite{
+						Layout: declarative.HBox{MarginsZero: true},
+						Children: []declarative.Widget{
+							declarative.PushButton{Text: "优化", OnClicked: func() { mw.onOptimizeTaskClick() }},
+							declarative.PushButton{Text: "编辑脚本", OnClicked: func() { mw.onEditTaskClick() }},
+							declarative.PushButton{Text: "导入 AHK", OnClicked: func() { mw.onImportAHKClick() }},
+							declarative.PushButton{Text: "导出 AHK", OnClicked: func() { mw.onExportAHKClick() }},
+						},
+					},
+				},
+			},
+
 			// 操作按钮
 			declarative.Composite{
 				Layout: declarative.HBox{Margins: declarative.Margins{Left: 10, Top: 10, Right: 10, Bottom: 10}},
@@ -121,7 +177,7 @@ func (mw *AppMainWindow) Create() error {
 							declarative.Label{Text: "执行时间:", MinSize: declarative.Size{Width: 70}},
 							declarative.LineEdit{
 								AssignTo: &scheduleTimeEdit,
-								Text:     mw.config.ScheduleTime,
+								Text:     schedule.ScheduleTime,
 								OnEditingFinished: func() {
 									mw.onScheduleTimeChanged()
 								},
@@ -129,7 +185,7 @@ func (mw *AppMainWindow) Create() error {
 							declarative.CheckBox{
 								AssignTo: &enableCheckBox,
 								Text:     "每日启用",
-								Checked:  mw.config.IsEnabled,
+								Checked:  schedule.IsEnabled,
 								OnClicked: func() {
 									mw.onEnableChanged()
 								},
@@ -146,18 +202,42 @@ func (mw *AppMainWindow) Create() error {
 								AssignTo:       &speedSlider,
 								MinValue:       50,
 								MaxValue:       100,
-								Value:          int(mw.config.SpeedFactor * 100),
+								Value:          int(schedule.SpeedFactor * 100),
 								ToolTipText:    "调整回放速度",
 								OnValueChanged: func() { mw.onSpeedChanged() },
 							},
 							declarative.Label{
 								AssignTo: &speedLabel,
-								Text:     fmt.Sprintf("%.1fx", mw.config.SpeedFactor),
+								Text:     fmt.Sprintf("%.1fx", schedule.SpeedFactor),
 								MinSize:  declarative.Size{Width: 40},
 							},
 						},
 					},
 
+					// 数据驱动回放
+					declarative.Composite{
+						Layout: declarative.HBox{Spacing: 5},
+						Children: []declarative.Widget{
+							declarative.Label{Text: "数据:", MinSize: declarative.Size{Width: 50}},
+							declarative.Label{
+								AssignTo:    &dataFileLabel,
+								Text:        dataFileText(schedule),
+								ToolTipText: "设置数据文件后，每次回放按文件中的每一行各执行一遍任务",
+							},
+							declarative.PushButton{Text: "选择", OnClicked: func() { mw.onDataFileClick() }},
+							declarative.PushButton{Text: "清除", OnClicked: func() { mw.onClearDataFileClick() }},
+						},
+					},
+
+					// 分辨率策略
+					declarative.CheckBox{
+						AssignTo:    &scaleCheckBox,
+						Text:        "分辨率不同时按比例缩放",
+						Checked:     mw.config.ResolutionPolicy != model.ResolutionStrict,
+						ToolTipText: "取消勾选后，分辨率与录制时不同将拒绝回放",
+						OnClicked:   func() { mw.onScaleChanged() },
+					},
+
 					// 自启动
 					declarative.CheckBox{
 						AssignTo:  &autoStartCheckBox,
@@ -182,15 +262,19 @@ func (mw *AppMainWindow) Create() error {
 
 	// 保存控件引用
 	mw.statusLabel = statusLabel
+	mw.taskCombo = taskCombo
 	mw.recordBtn = recordBtn
 	mw.playBtn = playBtn
 	mw.scheduleTimeEdit = scheduleTimeEdit
 	mw.enableCheckBox = enableCheckBox
 	mw.speedSlider = speedSlider
 	mw.speedLabel = speedLabel
+	mw.dataFileLabel = dataFileLabel
 	mw.autoStartCheckBox = autoStartCheckBox
+	mw.scaleCheckBox = scaleCheckBox
 
-	// 更新状态显示
+	// 加载任务列表并更新状态显示
+	mw.refreshTaskList()
 	mw.updateStatus()
 
 	// 启动调度器
@@ -200,15 +284,24 @@ func (mw *AppMainWindow) Create() error {
 
 	// 设置调度器回调
 	mw.scheduler.SetCallbacks(
-		func() {
+		func(taskName string) {
 			mw.Synchronize(func() {
-				walk.MsgBox(mw, "任务执行", "定时任务已执行", walk.MsgBoxIconInformation)
+				walk.MsgBox(mw, "任务执行", fmt.Sprintf("定时任务「%s」已执行", taskName), walk.MsgBoxIconInformation)
 				mw.updateStatus()
 			})
 		},
-		func(err error) {
+		func(taskName string, err error) {
 			mw.Synchronize(func() {
-				walk.MsgBox(mw, "任务失败", fmt.Sprintf("任务执行失败: %v", err), walk.MsgBoxIconError)
+				if errors.Is(err, storage.ErrPassphraseRequired) || errors.Is(err, storage.ErrWrongPassphrase) {
+					// 到期的加密任务需要口令，输入后在调度器下一次检查时执行
+					mw.unlockTask(taskName)
+					return
+				}
+				if errors.Is(err, storage.ErrSecretNotFound) {
+					mw.fillSecrets(taskName)
+					return
+				}
+				walk.MsgBox(mw, "任务失败", fmt.Sprintf("任务「%s」执行失败: %v", taskName, err), walk.MsgBoxIconError)
 			})
 		},
 	)
@@ -225,11 +318,33 @@ func (mw *AppMainWindow) onRecordClick() {
 			return
 		}
 		mw.recordBtn.SetText("🔴 录制 (F8)")
-		walk.MsgBox(mw, "成功", "录制已保存", walk.MsgBoxIconInformation)
+		if stats := mw.recorder.Stats(); stats.DroppedActions > 0 {
+			// 丢失的点击或按键会使回放不完整，只丢了鼠标移动时不影响结果
+			walk.MsgBox(mw, "录制不完整",
+				fmt.Sprintf("录制已保存，但系统繁忙，有 %d 个点击或按键未能录下，回放可能不完整，建议重新录制", stats.DroppedActions),
+				walk.MsgBoxIconWarning)
+		} else {
+			walk.MsgBox(mw, "成功", "录制已保存", walk.MsgBoxIconInformation)
+		}
+		// 录制中的敏感输入只保存了名称，立即请用户填写内容
+		mw.fillSecrets(mw.config.CurrentTask)
+		mw.refreshTaskList()
 		mw.updateStatus()
 	} else {
-		// 开始录制
-		if err := mw.recorder.StartRecording(); err != nil {
+		// 开始录制（未选择任务时录制到默认任务）
+		if mw.config.CurrentTask == "" {
+			mw.config.CurrentTask = storage.DefaultTaskName
+			mw.saveConfig()
+		}
+		if !mw.unlockTask(mw.config.CurrentTask) {
+			return
+		}
+		if storage.TaskExists(mw.config.CurrentTask) &&
+			walk.MsgBox(mw, "覆盖确认", fmt.Sprintf("重新录制将覆盖任务「%s」，是否继续？", mw.config.CurrentTask),
+				walk.MsgBoxYesNo|walk.MsgBoxIconQuestion) != walk.DlgCmdYes {
+			return
+		}
+		if err := mw.recorder.StartRecording(mw.config.CurrentTask); err != nil {
 			walk.MsgBox(mw, "错误", fmt.Sprintf("开始录制失败: %v", err), walk.MsgBoxIconError)
 			return
 		}
@@ -248,8 +363,23 @@ func (mw *AppMainWindow) onPlayClick() {
 		mw.playBtn.SetText("🟢 回放 (F12)")
 	} else {
 		// 开始回放
+		if mw.config.CurrentTask == "" {
+			walk.MsgBox(mw, "提示", "请先选择或录制一个任务", walk.MsgBoxIconInformation)
+			return
+		}
+		if !mw.unlockTask(mw.config.CurrentTask) || !mw.fillSecrets(mw.config.CurrentTask) {
+			return
+		}
 		speedFactor := float64(mw.speedSlider.Value()) / 100.0
-		if err := mw.player.StartPlayback(speedFactor); err != nil {
+		if schedule := mw.currentSchedule(); schedule != nil && schedule.DataFile != "" {
+			mw.startBatch(mw.config.CurrentTask, schedule.DataFile, speedFactor)
+			return
+		}
+		if err := mw.player.StartPlayback(mw.config.CurrentTask, speedFactor); err != nil {
+			if errors.Is(err, storage.ErrCorrupt) {
+				mw.restoreTaskBackup(mw.config.CurrentTask, err)
+				return
+			}
 			walk.MsgBox(mw, "错误", fmt.Sprintf("开始回放失败: %v", err), walk.MsgBoxIconError)
 			return
 		}
@@ -257,24 +387,406 @@ func (mw *AppMainWindow) onPlayClick() {
 	}
 }
 
+// startBatch 按数据文件逐行回放任务，回放中在状态栏显示进度，结束后汇总失败的行
+func (mw *AppMainWindow) startBatch(name, dataFile string, speedFactor float64) {
+	data, err := storage.LoadDataFile(dataFile)
+	if err != nil {
+		walk.MsgBox(mw, "错误", fmt.Sprintf("读取数据文件失败: %v", err), walk.MsgBoxIconError)
+		return
+	}
+	total := len(data.Rows)
+	opts := core.BatchOptions{
+		Data: data,
+		OnRow: func(result core.RowResult) {
+			mw.Synchronize(func() {
+				state := "完成"
+				if result.Err != nil {
+					state = "失败"
+				}
+				mw.statusLabel.SetText(fmt.Sprintf("数据第 %d/%d 行%s", result.Row, total, state))
+			})
+		},
+	}
+	if err := mw.player.StartBatch(name, speedFactor, opts); err != nil {
+		if errors.Is(err, storage.ErrCorrupt) {
+			mw.restoreTaskBackup(name, err)
+			return
+		}
+		walk.MsgBox(mw, "错误", fmt.Sprintf("开始回放失败: %v", err), walk.MsgBoxIconError)
+		return
+	}
+	mw.playBtn.SetText("⏹️ 停止回放 (F12)")
+
+	go func() {
+		mw.player.Wait()
+		results := mw.player.Results()
+		mw.Synchronize(func() {
+			mw.playBtn.SetText("🟢 回放 (F12)")
+			mw.updateStatus()
+			mw.showBatchSummary(name, total, results)
+		})
+	}()
+}
+
+// showBatchSummary 显示数据驱动回放的结果，列出失败的行
+func (mw *AppMainWindow) showBatchSummary(name string, total int, results []core.RowResult) {
+	var failed []string
+	for _, result := range results {
+		if result.Err != nil && !errors.Is(result.Err, core.ErrPlaybackStopped) {
+			failed = append(failed, fmt.Sprintf("文件第 %d 行: %v", result.Line, result.Err))
+		}
+	}
+	message := fmt.Sprintf("任务「%s」已执行 %d/%d 行", name, len(results), total)
+	if len(failed) == 0 {
+		walk.MsgBox(mw, "回放结束", message, walk.MsgBoxIconInformation)
+		return
+	}
+	if len(failed) > maxShownIssues {
+		failed = append(failed[:maxShownIssues], fmt.Sprintf("……共 %d 行", len(failed)))
+	}
+	message += fmt.Sprintf("，以下行失败：\n\n%s", strings.Join(failed, "\n"))
+	walk.MsgBox(mw, "回放结束", message, walk.MsgBoxIconWarning)
+}
+
+// refreshTaskList 重新加载任务列表，并选中当前任务
+func (mw *AppMainWindow) refreshTaskList() {
+	names, err := mw.store.ListTasks()
+	if err != nil {
+		walk.MsgBox(mw, "错误", fmt.Sprintf("读取任务列表失败: %v", err), walk.MsgBoxIconError)
+		return
+	}
+
+	// 当前任务已不存在时改选第一个任务
+	current := -1
+	for i, name := range names {
+		if name == mw.config.CurrentTask {
+			current = i
+		}
+	}
+	if current < 0 && len(names) > 0 {
+		current = 0
+	}
+
+	mw.taskCombo.SetModel(names)
+	mw.taskCombo.SetCurrentIndex(current)
+	mw.onTaskSelected()
+	mw.loadScheduleControls()
+}
+
+// selectedTask 返回下拉框中选中的任务名称
+func (mw *AppMainWindow) selectedTask() string {
+	if mw.taskCombo.CurrentIndex() < 0 {
+		return ""
+	}
+	return mw.taskCombo.Text()
+}
+
+// onTaskSelected 切换任务事件
+func (mw *AppMainWindow) onTaskSelected() {
+	name := mw.selectedTask()
+	if name == "" || name == mw.config.CurrentTask {
+		return
+	}
+	mw.config.CurrentTask = name
+	mw.saveConfig()
+	mw.loadScheduleControls()
+	mw.updateStatus()
+}
+
+// currentSchedule 返回当前任务的设置，未选择任务时返回 nil
+func (mw *AppMainWindow) currentSchedule() *model.TaskSchedule {
+	if mw.config.CurrentTask == "" {
+		return nil
+	}
+	return mw.config.Schedule(mw.config.CurrentTask)
+}
+
+// scheduleOrDefault 返回当前任务的设置，未选择任务时返回默认设置（不保存）
+func (mw *AppMainWindow) scheduleOrDefault() *model.TaskSchedule {
+	if schedule := mw.currentSchedule(); schedule != nil {
+		return schedule
+	}
+	return model.NewTaskSchedule()
+}
+
+// loadScheduleControls 用当前任务的设置刷新配置区域控件
+func (mw *AppMainWindow) loadScheduleControls() {
+	schedule := mw.scheduleOrDefault()
+	mw.scheduleTimeEdit.SetText(schedule.ScheduleTime)
+	mw.enableCheckBox.SetChecked(schedule.IsEnabled)
+	mw.speedSlider.SetValue(int(schedule.SpeedFactor * 100))
+	mw.speedLabel.SetText(fmt.Sprintf("%.1fx", schedule.SpeedFactor))
+	mw.dataFileLabel.SetText(dataFileText(schedule))
+}
+
+// dataFileText 返回配置区域中显示的数据文件名
+func dataFileText(schedule *model.TaskSchedule) string {
+	if schedule.DataFile == "" {
+		return "无（回放一次）"
+	}
+	return filepath.Base(schedule.DataFile)
+}
+
+// onNewTaskClick 新建任务
+func (mw *AppMainWindow) onNewTaskClick() {
+	name, ok := promptText(mw, "新建任务", "任务名称:", "")
+	if !ok {
+		return
+	}
+	if err := storage.CreateTask(name); err != nil {
+		walk.MsgBox(mw, "错误", fmt.Sprintf("新建任务失败: %v", err), walk.MsgBoxIconError)
+		return
+	}
+	mw.config.CurrentTask = name
+	mw.saveConfig()
+	mw.refreshTaskList()
+	mw.updateStatus()
+}
+
+// onRenameTaskClick 重命名当前任务
+func (mw *AppMainWindow) onRenameTaskClick() {
+	oldName := mw.selectedTask()
+	if oldName == "" {
+		return
+	}
+	newName, ok := promptText(mw, "重命名任务", "新名称:", oldName)
+	if !ok || newName == oldName {
+		return
+	}
+	if err := storage.RenameTask(oldName, newName); err != nil {
+		walk.MsgBox(mw, "错误", fmt.Sprintf("重命名任务失败: %v", err), walk.MsgBoxIconError)
+		return
+	}
+	mw.config.RenameTask(oldName, newName)
+	mw.saveConfig()
+	mw.refreshTaskList()
+}
+
+// onDuplicateTaskClick 复制当前任务
+func (mw *AppMainWindow) onDuplicateTaskClick() {
+	srcName := mw.selectedTask()
+	if srcName == "" {
+		return
+	}
+	dstName, ok := promptText(mw, "复制任务", "新任务名称:", srcName+" 副本")
+	if !ok {
+		return
+	}
+	if err := storage.DuplicateTask(srcName, dstName); err != nil {
+		walk.MsgBox(mw, "错误", fmt.Sprintf("复制任务失败: %v", err), walk.MsgBoxIconError)
+		return
+	}
+	// 副本沿用原任务的回放速度，但默认不启用定时
+	srcSchedule := *mw.config.Schedule(srcName)
+	srcSchedule.IsEnabled = false
+	srcSchedule.LastRunDate = ""
+	mw.config.Tasks[dstName] = &srcSchedule
+	mw.config.CurrentTask = dstName
+	mw.saveConfig()
+	mw.refreshTaskList()
+}
+
+// onDeleteTaskClick 删除当前任务
+func (mw *AppMainWindow) onDeleteTaskClick() {
+	name := mw.selectedTask()
+	if name == "" {
+		return
+	}
+	if walk.MsgBox(mw, "确认删除", fmt.Sprintf("确定要删除任务「%s」吗？", name),
+		walk.MsgBoxYesNo|walk.MsgBoxIconQuestion) != walk.DlgCmdYes {
+		return
+	}
+	if err := storage.DeleteTask(name); err != nil {
+		walk.MsgBox(mw, "错误", fmt.Sprintf("删除任务失败: %v", err), walk.MsgBoxIconError)
+		return
+	}
+	mw.config.RemoveTask(name)
+	mw.saveConfig()
+	mw.refreshTaskList()
+	mw.updateStatus()
+}
+
+// onOptimizeTaskClick 压缩当前任务的录制数据（录制结束时已自动执行，用于处理旧任务）
+func (mw *AppMainWindow) onOptimizeTaskClick() {
+	name := mw.selectedTask()
+	if name == "" {
+		return
+	}
+	if !mw.unlockTask(name) {
+		return
+	}
+	taskData, err := mw.store.LoadTask(name)
+	if errors.Is(err, storage.ErrCorrupt) {
+		mw.restoreTaskBackup(name, err)
+		return
+	}
+	if err != nil {
+		walk.MsgBox(mw, "错误", fmt.Sprintf("加载任务失败: %v", err), walk.MsgBoxIconError)
+		return
+	}
+	before := model.CountEvents(taskData.Events)
+	removed := taskData.Optimize(mw.config.OptimizeOptions())
+	if removed == 0 {
+		walk.MsgBox(mw, "优化任务", "任务已是最简，无需优化", walk.MsgBoxIconInformation)
+		return
+	}
+	if err := mw.store.SaveTask(name, taskData); err != nil {
+		walk.MsgBox(mw, "错误", fmt.Sprintf("保存任务失败: %v", err), walk.MsgBoxIconError)
+		return
+	}
+	walk.MsgBox(mw, "优化任务", fmt.Sprintf("事件数 %d → %d", before, before-removed), walk.MsgBoxIconInformation)
+}
+
+// onEditTaskClick 把当前任务转为文本脚本并用记事本打开
+func (mw *AppMainWindow) onEditTaskClick() {
+	name := mw.selectedTask()
+	if name == "" {
+		return
+	}
+	if encrypted, _ := storage.IsTaskEncrypted(name); encrypted {
+		walk.MsgBox(mw, "编辑任务", "加密的任务无法在记事本中编辑，请先用 dailyflow-crypt decrypt 解密", walk.MsgBoxIconInformation)
+		return
+	}
+	if err := storage.ConvertTask(name, storage.ScriptFileExt); err != nil {
+		walk.MsgBox(mw, "错误", fmt.Sprintf("转换任务失败: %v", err), walk.MsgBoxIconError)
+		return
+	}
+	taskPath, err := storage.TaskPath(name)
+	if err != nil {
+		walk.MsgBox(mw, "错误", fmt.Sprintf("打开任务失败: %v", err), walk.MsgBoxIconError)
+		return
+	}
+	if err := exec.Command("notepad.exe", taskPath).Start(); err != nil {
+		walk.MsgBox(mw, "错误", fmt.Sprintf("打开记事本失败: %v", err), walk.MsgBoxIconError)
+	}
+}
+
+// maxShownIssues 导入时在提示框中列出的无法转换语句的最大条数
+const maxShownIssues = 10
+
+// onImportAHKClick 从 AutoHotkey 脚本导入为新任务
+func (mw *AppMainWindow) onImportAHKClick() {
+	dlg := walk.FileDialog{Title: "导入 AutoHotkey 脚本", Filter: "AutoHotkey 脚本 (*.ahk)|*.ahk"}
+	if ok, err := dlg.ShowOpen(mw); err != nil || !ok {
+		return
+	}
+
+	data, err := os.ReadFile(dlg.FilePath)
+	if err != nil {
+		walk.MsgBox(mw, "错误", fmt.Sprintf("读取脚本失败: %v", err), walk.MsgBoxIconError)
+		return
+	}
+	taskData, issues := storage.ImportAHK(data)
+	if len(taskData.Events) == 0 {
+		walk.MsgBox(mw, "错误", "脚本中没有可导入的操作", walk.MsgBoxIconError)
+		return
+	}
+
+	if len(issues) > 0 {
+		lines := make([]string, 0, maxShownIssues+1)
+		for i, issue := range issues {
+			if i == maxShownIssues {
+				lines = append(lines, fmt.Sprintf("……共 %d 处", len(issues)))
+				break
+			}
+			lines = append(lines, issue.String())
+		}
+		message := fmt.Sprintf("以下语句无法转换，将被跳过：\n\n%s\n\n是否导入其余 %d 个步骤？",
+			strings.Join(lines, "\n"), len(taskData.Events))
+		if walk.MsgBox(mw, "导入 AutoHotkey 脚本", message, walk.MsgBoxYesNo|walk.MsgBoxIconWarning) != walk.DlgCmdYes {
+			return
+		}
+	}
+
+	defaultName := strings.TrimSuffix(filepath.Base(dlg.FilePath), filepath.Ext(dlg.FilePath))
+	name, ok := promptText(mw, "导入 AutoHotkey 脚本", "任务名称:", defaultName)
+	if !ok {
+		return
+	}
+	if err := storage.ValidateTaskName(name); err != nil {
+		walk.MsgBox(mw, "错误", fmt.Sprintf("导入失败: %v", err), walk.MsgBoxIconError)
+		return
+	}
+	if storage.TaskExists(name) {
+		walk.MsgBox(mw, "错误", fmt.Sprintf("导入失败: %v: %s", storage.ErrTaskExists, name), walk.MsgBoxIconError)
+		return
+	}
+	taskData.Meta.CreatedAt = time.Now().Unix()
+	if err := mw.store.SaveTask(name, taskData); err != nil {
+		walk.MsgBox(mw, "错误", fmt.Sprintf("导入失败: %v", err), walk.MsgBoxIconError)
+		return
+	}
+
+	mw.config.CurrentTask = name
+	mw.saveConfig()
+	mw.refreshTaskList()
+}
+
+// onExportAHKClick 把当前任务导出为 AutoHotkey 脚本
+func (mw *AppMainWindow) onExportAHKClick() {
+	name := mw.selectedTask()
+	if name == "" {
+		return
+	}
+	if !mw.unlockTask(name) {
+		return
+	}
+	taskData, err := mw.store.LoadTask(name)
+	if err != nil {
+		walk.MsgBox(mw, "错误", fmt.Sprintf("加载任务失败: %v", err), walk.MsgBoxIconError)
+		return
+	}
+
+	dlg := walk.FileDialog{
+		Title:    "导出 AutoHotkey 脚本",
+		Filter:   "AutoHotkey 脚本 (*.ahk)|*.ahk",
+		FilePath: name + storage.AHKFileExt,
+	}
+	if ok, err := dlg.ShowSave(mw); err != nil || !ok {
+		return
+	}
+	path := dlg.FilePath
+	if filepath.Ext(path) == "" {
+		path += storage.AHKFileExt
+	}
+	if err := os.WriteFile(path, storage.ExportAHK(taskData), 0644); err != nil {
+		walk.MsgBox(mw, "错误", fmt.Sprintf("导出失败: %v", err), walk.MsgBoxIconError)
+		return
+	}
+	walk.MsgBox(mw, "导出 AutoHotkey 脚本", fmt.Sprintf("已导出到 %s", path), walk.MsgBoxIconInformation)
+}
+
 // onScheduleTimeChanged 时间配置改变事件
 func (mw *AppMainWindow) onScheduleTimeChanged() {
+	schedule := mw.currentSchedule()
+	if schedule == nil {
+		mw.loadScheduleControls()
+		return
+	}
+
 	newTime := mw.scheduleTimeEdit.Text()
 	// 验证时间格式
 	if _, err := time.Parse("15:04", newTime); err != nil {
 		walk.MsgBox(mw, "错误", "时间格式错误，请使用 HH:MM 格式", walk.MsgBoxIconError)
-		mw.scheduleTimeEdit.SetText(mw.config.ScheduleTime)
+		mw.scheduleTimeEdit.SetText(schedule.ScheduleTime)
 		return
 	}
 
-	mw.config.ScheduleTime = newTime
+	schedule.ScheduleTime = newTime
 	mw.saveConfig()
 	mw.updateStatus()
 }
 
 // onEnableChanged 启用状态改变事件
 func (mw *AppMainWindow) onEnableChanged() {
-	mw.config.IsEnabled = mw.enableCheckBox.Checked()
+	schedule := mw.currentSchedule()
+	if schedule == nil {
+		walk.MsgBox(mw, "提示", "请先选择或录制一个任务", walk.MsgBoxIconInformation)
+		mw.enableCheckBox.SetChecked(false)
+		return
+	}
+
+	schedule.IsEnabled = mw.enableCheckBox.Checked()
 	mw.saveConfig()
 	mw.updateStatus()
 }
@@ -283,8 +795,63 @@ func (mw *AppMainWindow) onEnableChanged() {
 func (mw *AppMainWindow) onSpeedChanged() {
 	value := mw.speedSlider.Value()
 	speedFactor := float64(value) / 100.0
-	mw.config.SpeedFactor = speedFactor
 	mw.speedLabel.SetText(fmt.Sprintf("%.1fx", speedFactor))
+
+	schedule := mw.currentSchedule()
+	if schedule == nil || schedule.SpeedFactor == speedFactor {
+		return
+	}
+	schedule.SpeedFactor = speedFactor
+	mw.saveConfig()
+}
+
+// onDataFileClick 为当前任务选择数据文件，选择后立即读取一次以检查格式
+func (mw *AppMainWindow) onDataFileClick() {
+	schedule := mw.currentSchedule()
+	if schedule == nil {
+		walk.MsgBox(mw, "提示", "请先选择或录制一个任务", walk.MsgBoxIconInformation)
+		return
+	}
+
+	dlg := walk.FileDialog{
+		Title:  "选择数据文件",
+		Filter: "数据文件 (*.csv;*.tsv;*.txt)|*.csv;*.tsv;*.txt",
+	}
+	if ok, err := dlg.ShowOpen(mw); err != nil || !ok {
+		return
+	}
+	data, err := storage.LoadDataFile(dlg.FilePath)
+	if err != nil {
+		walk.MsgBox(mw, "错误", fmt.Sprintf("读取数据文件失败: %v", err), walk.MsgBoxIconError)
+		return
+	}
+
+	schedule.DataFile = dlg.FilePath
+	mw.saveConfig()
+	mw.dataFileLabel.SetText(dataFileText(schedule))
+	walk.MsgBox(mw, "数据文件", fmt.Sprintf("共 %d 行数据，输入文本中可以引用的变量：\n\n{{%s}}",
+		len(data.Rows), strings.Join(data.Columns, "}}  {{")), walk.MsgBoxIconInformation)
+}
+
+// onClearDataFileClick 取消当前任务的数据文件，之后每次只回放一次
+func (mw *AppMainWindow) onClearDataFileClick() {
+	schedule := mw.currentSchedule()
+	if schedule == nil || schedule.DataFile == "" {
+		return
+	}
+	schedule.DataFile = ""
+	mw.saveConfig()
+	mw.dataFileLabel.SetText(dataFileText(schedule))
+}
+
+// onScaleChanged 分辨率策略改变事件
+func (mw *AppMainWindow) onScaleChanged() {
+	if mw.scaleCheckBox.Checked() {
+		mw.config.ResolutionPolicy = model.ResolutionScale
+	} else {
+		mw.config.ResolutionPolicy = model.ResolutionStrict
+	}
+	mw.player.SetResolutionPolicy(mw.config.ResolutionPolicy)
 	mw.saveConfig()
 }
 
@@ -308,9 +875,117 @@ func (mw *AppMainWindow) onAutoStartChanged() {
 	mw.saveConfig()
 }
 
+// unlockTask 任务已加密且尚未设置口令（或口令错误）时请用户输入口令，直到能解密或用户取消
+// 未加密、不存在或无法解析的任务直接返回 true，由调用方按原有流程处理
+func (mw *AppMainWindow) unlockTask(name string) bool {
+	if mw.prompting {
+		return false
+	}
+	mw.prompting = true
+	defer func() { mw.prompting = false }()
+
+	_, err := mw.store.LoadTask(name)
+	for {
+		var label string
+		switch {
+		case errors.Is(err, storage.ErrPassphraseRequired):
+			label = fmt.Sprintf("任务「%s」已加密，请输入口令:", name)
+		case errors.Is(err, storage.ErrWrongPassphrase):
+			label = fmt.Sprintf("口令错误，无法解密任务「%s」，请重新输入:", name)
+		default:
+			return true
+		}
+		passphrase, ok := promptPassword(mw, "输入口令", label)
+		if !ok || passphrase == "" {
+			return false
+		}
+		storage.SetPassphrase(passphrase)
+		_, err = mw.store.LoadTask(name)
+	}
+}
+
+// fillSecrets 任务（含它调用的任务）引用的敏感输入在凭据存储中缺失时请用户逐个输入，全部就绪时返回 true
+// 任务无法加载时返回 true，由调用方按原有流程报告错误
+func (mw *AppMainWindow) fillSecrets(name string) bool {
+	if mw.prompting {
+		return false
+	}
+	mw.prompting = true
+	defer func() { mw.prompting = false }()
+
+	taskData, err := mw.store.LoadTask(name)
+	if err != nil {
+		return true
+	}
+	// 被调用的任务中的敏感输入也要在回放前就绪；调用关系的错误留给回放时报告
+	names := taskData.Secrets()
+	if called, _, err := model.ResolveCalls(name, taskData, storage.TaskLoader(mw.store)); err == nil {
+		seen := make(map[string]bool)
+		for _, secret := range names {
+			seen[secret] = true
+		}
+		for _, calledTask := range called {
+			for _, secret := range calledTask.Secrets() {
+				if !seen[secret] {
+					seen[secret] = true
+					names = append(names, secret)
+				}
+			}
+		}
+	}
+	missing, err := storage.MissingSecrets(mw.secrets, names)
+	if err != nil {
+		walk.MsgBox(mw, "错误", fmt.Sprintf("读取凭据失败: %v", err), walk.MsgBoxIconError)
+		return false
+	}
+	for _, secret := range missing {
+		label := fmt.Sprintf("任务「%s」中的敏感输入「%s」需要填写内容（如密码）:", name, secret)
+		value, ok := promptPassword(mw, "敏感输入", label)
+		if !ok {
+			return false
+		}
+		if err := mw.secrets.SetSecret(secret, value); err != nil {
+			walk.MsgBox(mw, "错误", fmt.Sprintf("保存凭据失败: %v", err), walk.MsgBoxIconError)
+			return false
+		}
+	}
+	return true
+}
+
+// restoreTaskBackup 任务文件损坏时询问是否从最近的可用备份恢复
+func (mw *AppMainWindow) restoreTaskBackup(name string, loadErr error) {
+	message := fmt.Sprintf("任务「%s」的文件已损坏:\n%v\n\n是否从最近的备份恢复？", name, loadErr)
+	if walk.MsgBox(mw, "任务文件损坏", message, walk.MsgBoxYesNo|walk.MsgBoxIconWarning) != walk.DlgCmdYes {
+		return
+	}
+	backup, err := storage.RestoreLatestTaskBackup(name)
+	if err != nil {
+		walk.MsgBox(mw, "错误", fmt.Sprintf("恢复任务失败: %v", err), walk.MsgBoxIconError)
+		return
+	}
+	walk.MsgBox(mw, "恢复任务", fmt.Sprintf("已恢复到 %s 的备份", backup.Time.Format("2006-01-02 15:04:05")), walk.MsgBoxIconInformation)
+	mw.updateStatus()
+}
+
+// restoreConfigBackup 配置文件损坏时询问是否从最近的可用备份恢复，恢复成功返回 true
+// 此时主窗口尚未创建，对话框没有父窗口
+func restoreConfigBackup(loadErr error) bool {
+	message := fmt.Sprintf("配置文件已损坏:\n%v\n\n是否从最近的备份恢复？", loadErr)
+	if walk.MsgBox(nil, "配置文件损坏", message, walk.MsgBoxYesNo|walk.MsgBoxIconWarning) != walk.DlgCmdYes {
+		return false
+	}
+	backup, err := storage.RestoreLatestConfigBackup()
+	if err != nil {
+		walk.MsgBox(nil, "错误", fmt.Sprintf("恢复配置失败: %v", err), walk.MsgBoxIconError)
+		return false
+	}
+	walk.MsgBox(nil, "恢复配置", fmt.Sprintf("已恢复到 %s 的备份", backup.Time.Format("2006-01-02 15:04:05")), walk.MsgBoxIconInformation)
+	return true
+}
+
 // saveConfig 保存配置
 func (mw *AppMainWindow) saveConfig() {
-	if err := storage.SaveConfig(mw.config); err != nil {
+	if err := mw.store.SaveConfig(mw.config); err != nil {
 		walk.MsgBox(mw, "错误", fmt.Sprintf("保存配置失败: %v", err), walk.MsgBoxIconError)
 	}
 	if err := mw.scheduler.UpdateConfig(mw.config); err != nil {
@@ -321,39 +996,38 @@ func (mw *AppMainWindow) saveConfig() {
 // updateStatus 更新状态显示
 func (mw *AppMainWindow) updateStatus() {
 	// 检查是否有任务数据
-	taskData, err := storage.LoadTask()
+	if mw.config.CurrentTask == "" {
+		mw.statusLabel.SetText("任务未配置")
+		return
+	}
+	taskData, err := mw.store.LoadTask(mw.config.CurrentTask)
+	if errors.Is(err, storage.ErrPassphraseRequired) || errors.Is(err, storage.ErrWrongPassphrase) {
+		mw.statusLabel.SetText("任务已加密，回放时输入口令")
+		return
+	}
 	if err != nil || taskData == nil || len(taskData.Events) == 0 {
 		mw.statusLabel.SetText("任务未配置")
 		return
 	}
 
-	if mw.config.IsEnabled {
+	schedule := mw.currentSchedule()
+	if schedule.IsEnabled {
 		now := time.Now()
 		today := now.Format("2006-01-02")
-		
-		if mw.config.HasTaskToday(today) {
+
+		if schedule.HasRunToday(today) {
 			mw.statusLabel.SetText("今日任务已完成")
-		} else {
+		} else if targetTime, err := schedule.NextRun(now); err == nil {
 			// 计算下次运行时间
-			scheduleTime, err := time.Parse("15:04", mw.config.ScheduleTime)
-			if err == nil {
-				targetTime := time.Date(
-					now.Year(), now.Month(), now.Day(),
-					scheduleTime.Hour(), scheduleTime.Minute(), 0, 0,
-					now.Location(),
-				)
-				
-				if now.After(targetTime) {
-					// 今天的时间已过，显示明天
-					targetTime = targetTime.Add(24 * time.Hour)
-					mw.statusLabel.SetText(fmt.Sprintf("下次运行: 明天 %s", mw.config.ScheduleTime))
-				} else {
-					mw.statusLabel.SetText(fmt.Sprintf("下次运行: 今天 %s", mw.config.ScheduleTime))
-				}
+			if now.After(targetTime) {
+				// 今天的时间已过，显示明天
+				mw.statusLabel.SetText(fmt.Sprintf("下次运行: 明天 %s", schedule.ScheduleTime))
+			} else {
+				mw.statusLabel.SetText(fmt.Sprintf("下次运行: 今天 %s", schedule.ScheduleTime))
 			}
 		}
 	} else {
-		mw.statusLabel.SetText(fmt.Sprintf("任务已配置 (共 %d 个事件)", len(taskData.Events)))
+		mw.statusLabel.SetText(fmt.Sprintf("任务已配置 (共 %d 个事件)", model.CountEvents(taskData.Events)))
 	}
 }
 
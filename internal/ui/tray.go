@@ -1,6 +1,10 @@
 package ui
 
 import (
+	"dailyflow/internal/storage"
+	"fmt"
+	"os/exec"
+
 	"github.com/lxn/walk"
 )
 
@@ -56,6 +60,13 @@ func (mw *AppMainWindow) createTrayMenu() error {
 		mw.showMainWindow()
 	})
 
+	// 打开数据目录（任务库、配置和备份所在位置）
+	dataDirAction := walk.NewAction()
+	dataDirAction.SetText("打开数据目录")
+	dataDirAction.Triggered().Attach(func() {
+		mw.openDataDir()
+	})
+
 	// 关于
 	aboutAction := walk.NewAction()
 	aboutAction.SetText("关于")
@@ -82,6 +93,9 @@ func (mw *AppMainWindow) createTrayMenu() error {
 	if err := mw.trayIcon.ContextMenu().Actions().Add(showAction); err != nil {
 		return err
 	}
+	if err := mw.trayIcon.ContextMenu().Actions().Add(dataDirAction); err != nil {
+		return err
+	}
 	if err := mw.trayIcon.ContextMenu().Actions().Add(aboutAction); err != nil {
 		return err
 	}
@@ -95,6 +109,17 @@ func (mw *AppMainWindow) createTrayMenu() error {
 	return nil
 }
 
+// openDataDir 在资源管理器中打开数据目录
+func (mw *AppMainWindow) openDataDir() {
+	dataDir, err := storage.GetDataDir()
+	if err == nil {
+		err = exec.Command("explorer.exe", dataDir).Start()
+	}
+	if err != nil {
+		walk.MsgBox(mw, "错误", fmt.Sprintf("打开数据目录失败: %v", err), walk.MsgBoxIconError)
+	}
+}
+
 // showMainWindow 显示主窗口
 func (mw *AppMainWindow) showMainWindow() {
 	mw.Show()